@@ -1,75 +1,843 @@
 package types
 
 import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
+var (
+	// ErrBelowMin is returned by CheckBounds when a balance is below the minimum.
+	ErrBelowMin = errors.New("balance below minimum")
+	// ErrAboveMax is returned by CheckBounds when a balance is above the maximum.
+	ErrAboveMax = errors.New("balance above maximum")
+	// ErrNegativeBalance is returned by MustSub when the result would be negative.
+	ErrNegativeBalance = errors.New("negative balance")
+)
+
+// Balance is an amount in Wei. A balance may be negative, e.g. as the result of Sub.
+// The zero value, with a nil Int, is a valid zero balance: all methods treat a nil Int as zero.
+//
+// Balance wraps a pointer: copies of a Balance share the same big.Int.
+// Methods never modify the receiver and always return fresh values,
+// but modifying the embedded Int in place affects all copies. Use Clone to get an independent copy.
 type Balance struct {
 	*big.Int
 }
 
-// NewBalance creates a new Balance from a big.Int
+// NewBalance creates a new Balance from a big.Int. A nil big.Int creates a zero balance.
 func NewBalance(i *big.Int) Balance {
-	return Balance{Int: new(big.Int).Set(i)}
+	return Balance{Int: new(big.Int).Set(orZero(i))}
+}
+
+// Clone returns a deep copy of the balance, that does not share its big.Int with the original.
+// A nil balance is cloned as nil.
+func (b Balance) Clone() Balance {
+	if b.Int == nil {
+		return Balance{}
+	}
+	return Balance{Int: new(big.Int).Set(b.Int)}
+}
+
+// NewBalanceFromEther creates a new Balance from an amount in ETH, truncated to an integer amount of Wei.
+// The shortest decimal form of the float is converted, so 0.1 ETH is exactly 100000000000000000 Wei,
+// but floats cannot represent all amounts: extreme or very precise inputs lose precision.
+// Use ParseBalance to get exact values. NewBalanceFromEther panics if f is NaN or infinite.
+func NewBalanceFromEther(f float64) Balance {
+	return newBalanceFromUnit(f, 18)
+}
+
+// NewBalanceFromGwei creates a new Balance from an amount in Gwei, truncated to an integer amount of Wei.
+// See NewBalanceFromEther for the precision. NewBalanceFromGwei panics if f is NaN or infinite.
+func NewBalanceFromGwei(f float64) Balance {
+	return newBalanceFromUnit(f, 9)
+}
+
+func newBalanceFromUnit(f float64, decimals int64) Balance {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("cannot create balance from %v", f))
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		panic(fmt.Sprintf("cannot create balance from %v", f))
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil)))
+	return Balance{Int: new(big.Int).Quo(r.Num(), r.Denom())}
+}
+
+// balanceUnits maps the lowercase unit names that ParseBalance accepts to their number of decimals.
+var balanceUnits = map[string]int{
+	"eth":  18,
+	"gwei": 9,
+	"wei":  0,
+}
+
+// maxBalanceExponent bounds the exponent that ParseBalance accepts,
+// far beyond any realistic amount, so parsing cannot be made to build huge numbers.
+const maxBalanceExponent = 1000
+
+// ParseBalance parses a decimal amount with an optional unit suffix, such as "1.5 ETH", "200 Gwei" or "1000000 Wei".
+// The unit is case-insensitive, and defaults to Wei. ETH and Gwei amounts may have a fraction,
+// down to a single Wei, but Wei amounts may not.
+// The amount may have an exponent, such as "1e18" or "1.5e-3 ETH", as long as the result is an integer amount of Wei,
+// and digits may be separated by underscores like in Go literals, such as "1_000_000 Gwei".
+// An underscore must be between two digits.
+func ParseBalance(s string) (Balance, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Balance{}, errors.New("empty balance")
+	}
+	// The unit is the trailing run of letters, so the 'e' of an exponent is part of the amount.
+	num, unit := s, "wei"
+	if i := strings.LastIndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }); i < len(s)-1 {
+		num, unit = strings.TrimSpace(s[:i+1]), strings.ToLower(s[i+1:])
+	}
+	decimals, ok := balanceUnits[unit]
+	if !ok {
+		return Balance{}, fmt.Errorf("unknown unit in balance %q", s)
+	}
+	num, exp, hasExp := strings.Cut(strings.ToLower(num), "e")
+	if strings.Count(num, ".") > 1 {
+		return Balance{}, fmt.Errorf("more than one decimal point in balance %q", s)
+	}
+	whole, frac, _ := strings.Cut(num, ".")
+	sign := ""
+	if strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		sign, whole = whole[:1], whole[1:]
+	}
+	whole, okWhole := stripDigitSeparators(whole)
+	frac, okFrac := stripDigitSeparators(frac)
+	digits := whole + frac
+	if !okWhole || !okFrac || digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Balance{}, fmt.Errorf("invalid amount in balance %q", s)
+	}
+	// scale is the power of ten to multiply the digits with, to get the amount in Wei.
+	scale := decimals - len(frac)
+	if hasExp {
+		exp, ok := stripDigitSeparators(exp)
+		e, err := strconv.Atoi(exp)
+		if !ok || err != nil {
+			return Balance{}, fmt.Errorf("invalid exponent in balance %q", s)
+		}
+		if e > maxBalanceExponent || e < -maxBalanceExponent {
+			return Balance{}, fmt.Errorf("exponent out of range in balance %q", s)
+		}
+		scale += e
+	}
+	if scale < 0 {
+		// Only trailing zeros can be dropped, anything else is a fraction of a Wei.
+		drop := min(-scale, len(digits))
+		if strings.TrimLeft(digits[len(digits)-drop:], "0") != "" {
+			if decimals == 0 {
+				return Balance{}, fmt.Errorf("fractional Wei in balance %q", s)
+			}
+			return Balance{}, fmt.Errorf("balance %q is more precise than 1 Wei", s)
+		}
+		digits, scale = digits[:len(digits)-drop], 0
+		if digits == "" {
+			digits = "0"
+		}
+	}
+	v, ok := new(big.Int).SetString(sign+digits+strings.Repeat("0", scale), 10)
+	if !ok {
+		return Balance{}, fmt.Errorf("invalid amount in balance %q", s)
+	}
+	return Balance{Int: v}, nil
+}
+
+// stripDigitSeparators removes the underscores between digits from s,
+// and returns false if an underscore is not between two digits.
+func stripDigitSeparators(s string) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1])) {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(s, "_", ""), true
 }
 
 // Add returns a new Balance with other added to it
 func (b Balance) Add(other Balance) Balance {
-	return Balance{Int: new(big.Int).Add(b.Int, other.Int)}
+	return Balance{Int: new(big.Int).Add(orZero(b.Int), orZero(other.Int))}
 }
 
 // Sub returns a new Balance with other subtracted from it
 func (b Balance) Sub(other Balance) Balance {
-	return Balance{Int: new(big.Int).Sub(b.Int, other.Int)}
+	return Balance{Int: new(big.Int).Sub(orZero(b.Int), orZero(other.Int))}
+}
+
+// SubClamp returns a new Balance with other subtracted from it, saturating at zero:
+// unlike Sub, the result is zero, rather than negative, if other exceeds this balance.
+func (b Balance) SubClamp(other Balance) Balance {
+	out := b.Sub(other)
+	if out.Sign() < 0 {
+		return Balance{Int: new(big.Int)}
+	}
+	return out
+}
+
+// MustSub returns a new Balance with other subtracted from it, like Sub,
+// but returns an error wrapping ErrNegativeBalance instead, if the result would be negative.
+func (b Balance) MustSub(other Balance) (Balance, error) {
+	out := b.Sub(other)
+	if out.Sign() < 0 {
+		return Balance{}, fmt.Errorf("%w: %s - %s", ErrNegativeBalance, orZero(b.Int), orZero(other.Int))
+	}
+	return out, nil
 }
 
-// Mul returns a new Balance multiplied by a float64
+// Mul returns a new Balance multiplied by a float64, truncated toward zero.
+// See MulWithRounding for other rounding modes.
 func (b Balance) Mul(f float64) Balance {
-	floatResult := new(big.Float).Mul(new(big.Float).SetInt(b.Int), new(big.Float).SetFloat64(f))
+	floatResult := new(big.Float).Mul(new(big.Float).SetInt(orZero(b.Int)), new(big.Float).SetFloat64(f))
 	result := new(big.Int)
 	floatResult.Int(result)
 	return Balance{Int: result}
 }
 
+// MulWithRounding returns a new Balance multiplied by a float64,
+// with the fraction of a Wei rounded to an integer amount with the given rounding mode.
+// The multiplication itself is exact. MulWithRounding panics if f is NaN or infinite.
+func (b Balance) MulWithRounding(f float64, mode big.RoundingMode) Balance {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("cannot multiply balance by %v", f))
+	}
+	v := orZero(b.Int)
+	// a float64 has a 53-bit mantissa, so this precision makes the product exact
+	product := new(big.Float).SetPrec(uint(v.BitLen()) + 64).SetInt(v)
+	product.Mul(product, big.NewFloat(f))
+	result, acc := product.Int(nil) // truncated toward zero
+	if acc == big.Exact {
+		return Balance{Int: result}
+	}
+	// the truncated part, with the sign of the product
+	frac := new(big.Float).Sub(product, new(big.Float).SetInt(result))
+	away := false
+	switch mode {
+	case big.ToZero:
+	case big.AwayFromZero:
+		away = true
+	case big.ToNegativeInf:
+		away = product.Sign() < 0
+	case big.ToPositiveInf:
+		away = product.Sign() > 0
+	case big.ToNearestEven, big.ToNearestAway:
+		switch frac.Abs(frac).Cmp(big.NewFloat(0.5)) {
+		case 1:
+			away = true
+		case 0:
+			away = mode == big.ToNearestAway || result.Bit(0) == 1
+		}
+	}
+	if away {
+		result.Add(result, big.NewInt(int64(product.Sign())))
+	}
+	return Balance{Int: result}
+}
+
+// Percent returns p percent of the balance, truncated toward zero like Mul.
+// The computation is exact, before truncation. Percent panics if p is NaN or infinite.
+func (b Balance) Percent(p float64) Balance {
+	r := new(big.Rat).SetFloat64(p)
+	if r == nil {
+		panic(fmt.Sprintf("cannot take %v percent of balance", p))
+	}
+	r.Mul(r, new(big.Rat).SetInt(orZero(b.Int)))
+	r.Quo(r, big.NewRat(100, 1))
+	return Balance{Int: new(big.Int).Quo(r.Num(), r.Denom())}
+}
+
+// PercentOf returns the balance as a percentage of total.
+// If total is zero, PercentOf returns 0.
+func (b Balance) PercentOf(total Balance) float64 {
+	if total.IsZero() {
+		return 0
+	}
+	v := new(big.Float).SetPrec(balanceFloatPrec).SetInt(orZero(b.Int))
+	v.Quo(v, new(big.Float).SetPrec(balanceFloatPrec).SetInt(total.Int))
+	v.Mul(v, big.NewFloat(100))
+	out, _ := v.Float64()
+	return out
+}
+
+// Ratio returns the exact ratio of this balance to other, in lowest terms.
+// An error is returned if other is zero.
+func (b Balance) Ratio(other Balance) (*big.Rat, error) {
+	if other.IsZero() {
+		return nil, errors.New("ratio to zero balance")
+	}
+	return new(big.Rat).SetFrac(orZero(b.Int), other.Int), nil
+}
+
+// Div returns a new Balance divided by divisor, truncated toward zero, like big.Int.Quo.
+// Div panics if divisor is zero.
+func (b Balance) Div(divisor int64) Balance {
+	return b.DivBig(big.NewInt(divisor))
+}
+
+// DivBig returns a new Balance divided by divisor, truncated toward zero, like big.Int.Quo.
+// DivBig panics if divisor is zero.
+func (b Balance) DivBig(divisor *big.Int) Balance {
+	if divisor.Sign() == 0 {
+		panic("balance division by zero")
+	}
+	return Balance{Int: new(big.Int).Quo(orZero(b.Int), divisor)}
+}
+
+// Split divides the balance into n shares that sum up to exactly the balance.
+// The remainder is distributed one Wei at a time over the first shares,
+// so the shares of a negative balance mirror those of its absolute amount.
+// An error is returned if n is not positive.
+func (b Balance) Split(n int) ([]Balance, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split balance into %d shares", n)
+	}
+	q, r := new(big.Int).QuoRem(orZero(b.Int), big.NewInt(int64(n)), new(big.Int))
+	// |r| < n, so it fits in an int64
+	extra := r.Int64()
+	step := big.NewInt(1)
+	if extra < 0 {
+		extra, step = -extra, big.NewInt(-1)
+	}
+	shares := make([]Balance, n)
+	for i := range shares {
+		share := new(big.Int).Set(q)
+		if int64(i) < extra {
+			share.Add(share, step)
+		}
+		shares[i] = Balance{Int: share}
+	}
+	return shares, nil
+}
+
+// DistributeByWeights divides the balance into shares proportional to the weights,
+// that sum up to exactly the balance. Each share is first rounded down,
+// and the remaining Wei are then distributed one at a time over the shares with the largest remainders,
+// with ties going to the earlier shares. The shares of a negative balance mirror those of its absolute amount.
+// A zero weight always gets a zero share. An error is returned if there are no weights, or if they sum up to zero.
+func (b Balance) DistributeByWeights(weights []uint64) ([]Balance, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("cannot distribute balance without weights")
+	}
+	total := new(big.Int)
+	for _, w := range weights {
+		total.Add(total, new(big.Int).SetUint64(w))
+	}
+	if total.Sign() == 0 {
+		return nil, fmt.Errorf("cannot distribute balance by %d weights that sum up to zero", len(weights))
+	}
+	amount := orZero(b.Int)
+	abs := new(big.Int).Abs(amount)
+	shares := make([]*big.Int, len(weights))
+	remainders := make([]*big.Int, len(weights))
+	leftover := new(big.Int).Set(abs)
+	for i, w := range weights {
+		share := new(big.Int).Mul(abs, new(big.Int).SetUint64(w))
+		shares[i], remainders[i] = share.QuoRem(share, total, new(big.Int))
+		leftover.Sub(leftover, shares[i])
+	}
+	// Each share is rounded down by less than 1 Wei, so the leftover is less than the number of shares.
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		return remainders[y].Cmp(remainders[x])
+	})
+	for _, i := range order[:leftover.Int64()] {
+		shares[i].Add(shares[i], big.NewInt(1))
+	}
+	out := make([]Balance, len(weights))
+	for i, share := range shares {
+		if amount.Sign() < 0 {
+			share.Neg(share)
+		}
+		out[i] = Balance{Int: share}
+	}
+	return out, nil
+}
+
+// Abs returns a new Balance with the absolute amount of this balance.
+func (b Balance) Abs() Balance {
+	return Balance{Int: new(big.Int).Abs(orZero(b.Int))}
+}
+
+// Neg returns a new Balance with the amount of this balance negated.
+func (b Balance) Neg() Balance {
+	return Balance{Int: new(big.Int).Neg(orZero(b.Int))}
+}
+
+// Cmp compares this balance to other, and returns -1, 0 or +1,
+// if this balance is less than, equal to, or greater than other, like big.Int.Cmp.
+func (b Balance) Cmp(other Balance) int {
+	return orZero(b.Int).Cmp(orZero(other.Int))
+}
+
 // GreaterThan returns true if this balance is greater than other
 func (b Balance) GreaterThan(other Balance) bool {
-	return b.Int.Cmp(other.Int) > 0
+	return b.Cmp(other) > 0
+}
+
+// GreaterThanOrEqual returns true if this balance is greater than or equal to other
+func (b Balance) GreaterThanOrEqual(other Balance) bool {
+	return b.Cmp(other) >= 0
 }
 
 // LessThan returns true if this balance is less than other
 func (b Balance) LessThan(other Balance) bool {
-	return b.Int.Cmp(other.Int) < 0
+	return b.Cmp(other) < 0
+}
+
+// LessThanOrEqual returns true if this balance is less than or equal to other
+func (b Balance) LessThanOrEqual(other Balance) bool {
+	return b.Cmp(other) <= 0
 }
 
 // Equal returns true if this balance equals other
 func (b Balance) Equal(other Balance) bool {
-	return b.Int.Cmp(other.Int) == 0
+	return b.Cmp(other) == 0
 }
 
-// LogValue implements slog.LogValuer to format Balance in the most readable unit
+// Sign returns -1, 0 or +1, if this balance is negative, zero or positive.
+// A nil balance is treated as zero.
+func (b Balance) Sign() int {
+	if b.Int == nil {
+		return 0
+	}
+	return b.Int.Sign()
+}
+
+// IsZero returns true if this balance is zero.
+// A nil balance is treated as zero.
+func (b Balance) IsZero() bool {
+	return b.Sign() == 0
+}
+
+// IsPositive returns true if this balance is strictly greater than zero.
+// A nil balance is treated as zero.
+func (b Balance) IsPositive() bool {
+	return b.Sign() > 0
+}
+
+// IsNegative returns true if this balance is strictly less than zero.
+// A nil balance is treated as zero.
+func (b Balance) IsNegative() bool {
+	return b.Sign() < 0
+}
+
+// Clamp returns the balance, bounded to the inclusive range between lower and upper.
+// If lower is greater than upper, the bounds are inverted, and lower takes precedence.
+// Nil balances are treated as zero.
+func (b Balance) Clamp(lower, upper Balance) Balance {
+	v, lo, hi := orZero(b.Int), orZero(lower.Int), orZero(upper.Int)
+	if v.Cmp(lo) < 0 || lo.Cmp(hi) > 0 {
+		return NewBalance(lo)
+	}
+	if v.Cmp(hi) > 0 {
+		return NewBalance(hi)
+	}
+	return NewBalance(v)
+}
+
+// CheckBounds returns an error wrapping ErrBelowMin or ErrAboveMax if the balance is outside
+// the inclusive range between min and max, or nil if it is within the range.
+// Unlike Clamp, a nil bound is unbounded. A nil balance is treated as zero.
+func (b Balance) CheckBounds(min, max Balance) error {
+	v := orZero(b.Int)
+	if min.Int != nil && v.Cmp(min.Int) < 0 {
+		return fmt.Errorf("%w: %s < %s", ErrBelowMin, v, min.Int)
+	}
+	if max.Int != nil && v.Cmp(max.Int) > 0 {
+		return fmt.Errorf("%w: %s > %s", ErrAboveMax, v, max.Int)
+	}
+	return nil
+}
+
+// WithinTolerance returns true if this balance differs from other by at most tol.
+// tol is an absolute amount of Wei, not a percentage: see WithinPercent for a relative tolerance.
+// A negative tol is never met. Nil balances are treated as zero.
+func (b Balance) WithinTolerance(other Balance, tol Balance) bool {
+	diff := new(big.Int).Sub(orZero(b.Int), orZero(other.Int))
+	return diff.Abs(diff).Cmp(orZero(tol.Int)) <= 0
+}
+
+// WithinPercent returns true if this balance differs from other by at most pct percent of other.
+// The shortest decimal form of pct is compared exactly, so a zero other is only matched by a zero balance.
+// A negative, NaN or infinite pct is never met. Nil balances are treated as zero.
+func (b Balance) WithinPercent(other Balance, pct float64) bool {
+	if math.IsNaN(pct) || math.IsInf(pct, 0) || pct < 0 {
+		return false
+	}
+	diff := new(big.Int).Sub(orZero(b.Int), orZero(other.Int))
+	// |b - other| * 100 <= pct * |other|
+	lhs := new(big.Rat).SetInt(diff.Abs(diff))
+	lhs.Mul(lhs, big.NewRat(100, 1))
+	// the shortest decimal form of pct, so a percentage like 0.1 is met exactly
+	rhs, ok := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	if !ok {
+		return false
+	}
+	rhs.Mul(rhs, new(big.Rat).SetInt(new(big.Int).Abs(orZero(other.Int))))
+	return lhs.Cmp(rhs) <= 0
+}
+
+// SummarizeBalances returns the total of the balances, the number of balances, and the largest balance.
+// Nil balances are treated as zero. For an empty slice, the total and max are zero.
+func SummarizeBalances(bs []Balance) (total Balance, count int, max Balance) {
+	sum := new(big.Int)
+	var largest *big.Int
+	for _, b := range bs {
+		v := orZero(b.Int)
+		sum.Add(sum, v)
+		if largest == nil || v.Cmp(largest) > 0 {
+			largest = v
+		}
+	}
+	return Balance{Int: sum}, len(bs), NewBalance(orZero(largest))
+}
+
+// Sum returns the total of the balances, or zero if there are none.
+// Nil balances are treated as zero.
+func Sum(bs ...Balance) Balance {
+	sum := new(big.Int)
+	for _, b := range bs {
+		sum.Add(sum, orZero(b.Int))
+	}
+	return Balance{Int: sum}
+}
+
+// Min returns the smallest of the balances, or zero if there are none.
+// Nil balances are treated as zero.
+func Min(bs ...Balance) Balance {
+	return extreme(bs, -1)
+}
+
+// Max returns the largest of the balances, or zero if there are none.
+// Nil balances are treated as zero.
+func Max(bs ...Balance) Balance {
+	return extreme(bs, 1)
+}
+
+// extreme returns a copy of the balance that compares as cmp against all others
+func extreme(bs []Balance, cmp int) Balance {
+	var out *big.Int
+	for _, b := range bs {
+		v := orZero(b.Int)
+		if out == nil || v.Cmp(out) == cmp {
+			out = v
+		}
+	}
+	return NewBalance(out)
+}
+
+// orZero returns i, or zero if i is nil
+func orZero(i *big.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+	return i
+}
+
+// balanceFloatPrec is the precision of the big.Float conversions of a Balance,
+// enough to represent a 1 Wei difference at the scale of any realistic ETH amount.
+const balanceFloatPrec = 256
+
+// ToEther returns the balance in ETH.
+// The result is a fresh value, but a float, and thus possibly lossy: it is meant for display and assertions.
+func (b Balance) ToEther() *big.Float {
+	return b.toUnit(18)
+}
+
+// ToGwei returns the balance in Gwei.
+// The result is a fresh value, but a float, and thus possibly lossy: it is meant for display and assertions.
+func (b Balance) ToGwei() *big.Float {
+	return b.toUnit(9)
+}
+
+// ToWei returns a copy of the balance in Wei. A nil balance is treated as zero.
+func (b Balance) ToWei() *big.Int {
+	return new(big.Int).Set(orZero(b.Int))
+}
+
+// toUnit returns the balance divided by 10^decimals
+func (b Balance) toUnit(decimals int64) *big.Float {
+	v := new(big.Float).SetPrec(balanceFloatPrec).SetInt(orZero(b.Int))
+	unit := new(big.Float).SetPrec(balanceFloatPrec).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil))
+	return v.Quo(v, unit)
+}
+
+// String implements fmt.Stringer, formatting the balance in the most readable unit, like LogValue.
+// See WeiString for the exact amount.
+func (b Balance) String() string {
+	return b.LogValue().String()
+}
+
+// Format implements fmt.Formatter: the %v and %s verbs format like String,
+// and all other verbs format the Wei amount like big.Int does.
+func (b Balance) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		_, _ = io.WriteString(s, b.String())
+	default:
+		orZero(b.Int).Format(s, verb)
+	}
+}
+
+// WeiString returns the exact balance, as decimal Wei amount, without unit.
+// A nil balance is treated as zero.
+func (b Balance) WeiString() string {
+	return orZero(b.Int).Text(10)
+}
+
+// BalanceFormat configures how a balance is formatted, see FormatWith.
+// A balance is formatted in the largest unit that its absolute amount reaches the threshold of,
+// and otherwise in Wei.
+type BalanceFormat struct {
+	// MinEther is the smallest absolute amount, in ETH, that is formatted in ETH.
+	MinEther float64
+	// MinGwei is the smallest absolute amount, in Gwei, that is formatted in Gwei.
+	MinGwei float64
+	// Digits is the number of significant digits, if Decimals is not set.
+	// This defaults to the digits of DefaultBalanceFormat if not set.
+	Digits int
+	// Decimals, if set, is the fixed number of decimals, instead of a number of significant digits.
+	Decimals int
+}
+
+// DefaultBalanceFormat is the format of LogValue.
+var DefaultBalanceFormat = BalanceFormat{
+	MinEther: 0.001,
+	MinGwei:  0.001,
+	Digits:   3,
+}
+
+// LogValue implements slog.LogValuer to format Balance in the most readable unit, see DefaultBalanceFormat.
 func (b Balance) LogValue() slog.Value {
 	if b.Int == nil {
 		return slog.StringValue("0 ETH")
 	}
+	return b.LogValueWith(DefaultBalanceFormat)
+}
+
+// LogValueWith formats the balance for logging with the given format, see FormatWith.
+func (b Balance) LogValueWith(f BalanceFormat) slog.Value {
+	return slog.StringValue(b.FormatWith(f))
+}
+
+// FormatWith formats the balance with a unit, in the given format.
+// Amounts are never formatted in scientific notation. A nil balance is formatted as zero.
+func (b Balance) FormatWith(f BalanceFormat) string {
+	units := []struct {
+		name     string
+		decimals int64
+		min      float64
+	}{
+		{"ETH", 18, f.MinEther},
+		{"Gwei", 9, f.MinGwei},
+	}
+	for _, u := range units {
+		amount := b.toUnit(u.decimals)
+		abs := new(big.Float).Abs(amount)
+		// Parse the shortest decimal form of the threshold, so a threshold like 0.001 is met exactly.
+		threshold, _, err := big.ParseFloat(strconv.FormatFloat(u.min, 'g', -1, 64), 10, balanceFloatPrec, big.ToNearestEven)
+		if err != nil {
+			threshold = big.NewFloat(u.min)
+		}
+		if abs.Sign() > 0 && abs.Cmp(threshold) >= 0 {
+			return fmt.Sprintf("%s %s", formatAmount(amount, f), u.name)
+		}
+	}
+	return fmt.Sprintf("%s Wei", b.WeiString())
+}
+
+// formatAmount formats x with the decimals or significant digits of f, in plain decimal notation
+func formatAmount(x *big.Float, f BalanceFormat) string {
+	if f.Decimals > 0 {
+		return x.Text('f', f.Decimals)
+	}
+	digits := f.Digits
+	if digits <= 0 {
+		digits = DefaultBalanceFormat.Digits
+	}
+	s := x.Text('g', digits)
+	_, exp, ok := strings.Cut(s, "e")
+	if !ok {
+		return s
+	}
+	// Expand the rounded value from scientific notation, with just enough decimals to keep the digits.
+	e, err := strconv.Atoi(exp)
+	if err != nil {
+		return s
+	}
+	rounded, _, err := big.ParseFloat(s, 10, balanceFloatPrec, big.ToNearestEven)
+	if err != nil {
+		return s
+	}
+	out := rounded.Text('f', max(0, digits-1-e))
+	if strings.Contains(out, ".") {
+		out = strings.TrimRight(strings.TrimRight(out, "0"), ".")
+	}
+	return out
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the balance as a decimal Wei amount, without unit.
+// This canonical form is exact, and decodes with UnmarshalText and ParseBalance.
+func (b Balance) MarshalText() ([]byte, error) {
+	return []byte(b.WeiString()), nil
+}
 
-	val := new(big.Float).SetInt(b.Int)
-	eth := new(big.Float).Quo(val, new(big.Float).SetInt64(1e18))
+// UnmarshalText implements encoding.TextUnmarshaler, decoding an amount with an optional unit, see ParseBalance.
+// This allows a Balance to be used as flag, with flag.TextVar, and in TOML and env config.
+// Surrounding whitespace is ignored, and empty text decodes as a zero balance.
+func (b *Balance) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		b.Int = new(big.Int)
+		return nil
+	}
+	v, err := ParseBalance(s)
+	if err != nil {
+		return fmt.Errorf("invalid balance: %w", err)
+	}
+	b.Int = v.Int
+	return nil
+}
 
-	// 1 ETH = 1e18 Wei
-	if eth.Cmp(new(big.Float).SetFloat64(0.001)) >= 0 {
-		str := eth.Text('g', 3)
-		return slog.StringValue(fmt.Sprintf("%s ETH", str))
+// MarshalJSON implements json.Marshaler, encoding the balance as a decimal Wei string,
+// so the value is not truncated by JSON consumers that decode numbers as float64.
+func (b Balance) MarshalJSON() ([]byte, error) {
+	text, err := b.MarshalText()
+	if err != nil {
+		return nil, err
 	}
+	return json.Marshal(string(text))
+}
 
-	// 1 Gwei = 1e9 Wei
-	gwei := new(big.Float).Quo(val, new(big.Float).SetInt64(1e9))
-	if gwei.Cmp(new(big.Float).SetFloat64(0.001)) >= 0 {
-		str := gwei.Text('g', 3)
-		return slog.StringValue(fmt.Sprintf("%s Gwei", str))
+// UnmarshalJSON implements json.Unmarshaler, decoding either a JSON string, see UnmarshalText,
+// or, for backwards compatibility, a JSON number of Wei, which may have an exponent like 1e18.
+// Null and the empty string decode as a zero balance.
+func (b *Balance) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		b.Int = new(big.Int)
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid balance: %w", err)
+		}
+		return b.UnmarshalText([]byte(s))
 	}
+	return b.UnmarshalText(data)
+}
+
+// Value implements driver.Valuer, storing the balance as a decimal Wei string,
+// compatible with a NUMERIC column.
+func (b Balance) Value() (driver.Value, error) {
+	return b.WeiString(), nil
+}
+
+// Scan implements sql.Scanner, loading a decimal Wei amount from a string, []byte, or int64.
+// A NULL value loads as a zero balance. Floats are rejected, as they may have lost precision.
+func (b *Balance) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		b.Int = new(big.Int)
+	case int64:
+		b.Int = big.NewInt(v)
+	case string:
+		return b.scanDecimal(v)
+	case []byte:
+		return b.scanDecimal(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into balance", src)
+	}
+	return nil
+}
+
+func (b *Balance) scanDecimal(s string) error {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return fmt.Errorf("invalid balance: %q", s)
+	}
+	b.Int = v
+	return nil
+}
+
+// balanceBinaryVersion is the version byte of the binary encoding of a Balance, see MarshalBinary.
+const balanceBinaryVersion = 1
+
+func init() {
+	// Register the full name, so a Balance can be sent as interface value,
+	// without colliding with other packages named types.
+	gob.RegisterName("github.com/ethereum-optimism/optimism/devnet-sdk/types.Balance", Balance{})
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is a version byte,
+// the uvarint length of the amount, and the amount in the gob encoding of a big.Int,
+// which starts with its own version and sign byte.
+// A nil balance is encoded as zero.
+func (b Balance) MarshalBinary() ([]byte, error) {
+	amount, err := orZero(b.Int).GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, 1+binary.MaxVarintLen64+len(amount))
+	out = append(out, balanceBinaryVersion)
+	out = binary.AppendUvarint(out, uint64(len(amount)))
+	return append(out, amount...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the encoding of MarshalBinary.
+// An empty payload, or an empty amount, decodes as a zero balance. Unknown versions are rejected.
+func (b *Balance) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		b.Int = new(big.Int)
+		return nil
+	}
+	if data[0] != balanceBinaryVersion {
+		return fmt.Errorf("unsupported balance encoding version %d", data[0])
+	}
+	size, n := binary.Uvarint(data[1:])
+	if n <= 0 {
+		return errors.New("invalid balance encoding: invalid length")
+	}
+	amount := data[1+n:]
+	if uint64(len(amount)) != size {
+		return fmt.Errorf("invalid balance encoding: expected %d bytes, got %d", size, len(amount))
+	}
+	v := new(big.Int)
+	if err := v.GobDecode(amount); err != nil {
+		return fmt.Errorf("invalid balance encoding: %w", err)
+	}
+	b.Int = v
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder with the encoding of MarshalBinary.
+// This replaces the gob encoding of the embedded big.Int, which cannot decode into a nil balance.
+func (b Balance) GobEncode() ([]byte, error) {
+	return b.MarshalBinary()
+}
 
-	// Wei
-	return slog.StringValue(fmt.Sprintf("%s Wei", b.Text(10)))
+// GobDecode implements gob.GobDecoder, see UnmarshalBinary.
+func (b *Balance) GobDecode(data []byte) error {
+	return b.UnmarshalBinary(data)
 }
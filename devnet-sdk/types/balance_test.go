@@ -1,8 +1,23 @@
 package types
 
 import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
 	"math/big"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestNewBalance(t *testing.T) {
@@ -19,6 +34,157 @@ func TestNewBalance(t *testing.T) {
 	}
 }
 
+func TestBalance_Clone(t *testing.T) {
+	src := NewBalance(big.NewInt(100))
+	alias := src
+	clone := src.Clone()
+	if !clone.Equal(src) {
+		t.Fatalf("Clone() = %v, want %v", clone, src)
+	}
+
+	src.Int.SetInt64(200)
+	if !alias.Equal(NewBalance(big.NewInt(200))) {
+		t.Error("a plain copy is expected to share the big.Int")
+	}
+	if !clone.Equal(NewBalance(big.NewInt(100))) {
+		t.Errorf("clone changed to %d after mutating the source", clone)
+	}
+	clone.Int.SetInt64(300)
+	if !src.Equal(NewBalance(big.NewInt(200))) {
+		t.Errorf("source changed to %d after mutating the clone", src)
+	}
+
+	if got := (Balance{}).Clone(); got.Int != nil {
+		t.Errorf("Clone() of nil balance = %d, want nil", got)
+	}
+}
+
+func TestNewBalanceFromUnits(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Balance
+		want string
+	}{
+		{"0.1 ETH", NewBalanceFromEther(0.1), "100000000000000000"},
+		{"5 ETH", NewBalanceFromEther(5), "5000000000000000000"},
+		{"1.5 ETH", NewBalanceFromEther(1.5), "1500000000000000000"},
+		{"-0.3 ETH", NewBalanceFromEther(-0.3), "-300000000000000000"},
+		{"1e-18 ETH", NewBalanceFromEther(1e-18), "1"},
+		{"1e-19 ETH truncates", NewBalanceFromEther(1e-19), "0"},
+		{"0 ETH", NewBalanceFromEther(0), "0"},
+		{"1e6 ETH", NewBalanceFromEther(1e6), "1000000000000000000000000"},
+		{"0.1 Gwei", NewBalanceFromGwei(0.1), "100000000"},
+		{"21000 Gwei", NewBalanceFromGwei(21000), "21000000000000"},
+		{"1.5e-9 Gwei truncates", NewBalanceFromGwei(1.5e-9), "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.WeiString(); got != tt.want {
+				t.Errorf("got %s Wei, want %s Wei", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("NaN", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("NewBalanceFromEther(NaN) did not panic")
+			}
+		}()
+		NewBalanceFromEther(math.NaN())
+	})
+}
+
+func TestParseBalance(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.5 ETH", "1500000000000000000"},
+		{"1.5eth", "1500000000000000000"},
+		{"0.000000000000000001 ETH", "1"},
+		{"2 ETH", "2000000000000000000"},
+		{"200 Gwei", "200000000000"},
+		{"0.5 GWEI", "500000000"},
+		{"1000000 Wei", "1000000"},
+		{"1000000", "1000000"},
+		{" 42 wei ", "42"},
+		{".5 ETH", "500000000000000000"},
+		{"-1.5 ETH", "-1500000000000000000"},
+		{"0", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBalance(tt.input)
+			if err != nil {
+				t.Fatalf("ParseBalance(%q) failed: %v", tt.input, err)
+			}
+			want, _ := new(big.Int).SetString(tt.want, 10)
+			if got.Int.Cmp(want) != 0 {
+				t.Errorf("ParseBalance(%q) = %v, want %v", tt.input, got.Int, want)
+			}
+		})
+	}
+
+	for _, input := range []string{
+		"", "  ", "ETH", "1.5 BTC", "1.2.3 ETH", "1.5 Wei", "1.5", "0.0000000001 Gwei",
+		"0.0000000000000000001 ETH", "abc", "1e-1", "0x10", "1,5 ETH", "- 1 ETH",
+	} {
+		if _, err := ParseBalance(input); err == nil {
+			t.Errorf("ParseBalance(%q) expected error", input)
+		}
+	}
+}
+
+func TestParseBalance_SeparatorsAndExponents(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1_000_000", "1000000"},
+		{"1_000_000 Gwei", "1000000000000000"},
+		{"1_000.000_1 Gwei", "1000000100000"},
+		{"1e18", "1000000000000000000"},
+		{"1E18 wei", "1000000000000000000"},
+		{"1e+3", "1000"},
+		{"1.5e3", "1500"},
+		{"2.5e-9 ETH", "2500000000"},
+		{"1e-18 ETH", "1"},
+		{"1000e-3", "1"},
+		{"1_500e-2", "15"},
+		{"0e-5", "0"},
+		{"-1e3 Gwei", "-1000000000000"},
+		{"1e1_0", "10000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBalance(tt.input)
+			if err != nil {
+				t.Fatalf("ParseBalance(%q) failed: %v", tt.input, err)
+			}
+			want, _ := new(big.Int).SetString(tt.want, 10)
+			if got.Int.Cmp(want) != 0 {
+				t.Errorf("ParseBalance(%q) = %v, want %v", tt.input, got.Int, want)
+			}
+		})
+	}
+
+	for _, input := range []string{
+		// misplaced separators
+		"_1000", "1000_", "1__000", "1_.5 ETH", "1._5 ETH", "-_1", "1_e3", "1e_3", "1e3_", "1 _000",
+		// malformed exponents
+		"1e", "e18", "1e+", "1e1.5", "1e3e3", "1e18e",
+		// fractional Wei
+		"15e-1", "1e-1", "1500e-3", "1.5e-18 ETH", "1e-19 ETH",
+		// huge exponents
+		"1e100000", "1e-100000",
+	} {
+		if _, err := ParseBalance(input); err == nil {
+			t.Errorf("ParseBalance(%q) expected error", input)
+		}
+	}
+}
+
 func TestBalance_Add(t *testing.T) {
 	tests := []struct {
 		a, b, want int64
@@ -65,6 +231,53 @@ func TestBalance_Sub(t *testing.T) {
 	}
 }
 
+func TestBalance_SubClamp(t *testing.T) {
+	tests := []struct {
+		a, b      int64
+		wantClamp int64
+		wantErr   bool
+	}{
+		{300, 200, 100, false},
+		{100, 100, 0, false},
+		{0, 100, 0, true},
+		{100, 300, 0, true},
+		{-100, 50, 0, true},
+		{100, -50, 150, false},
+	}
+
+	for _, tt := range tests {
+		a := NewBalance(big.NewInt(tt.a))
+		b := NewBalance(big.NewInt(tt.b))
+		want := NewBalance(big.NewInt(tt.wantClamp))
+		if got := a.SubClamp(b); !got.Equal(want) {
+			t.Errorf("SubClamp(%v, %v) = %v, want %v", tt.a, tt.b, got, want)
+		}
+		got, err := a.MustSub(b)
+		if tt.wantErr {
+			if !errors.Is(err, ErrNegativeBalance) {
+				t.Errorf("MustSub(%v, %v) error = %v, want %v", tt.a, tt.b, err, ErrNegativeBalance)
+			}
+		} else if err != nil || !got.Equal(want) {
+			t.Errorf("MustSub(%v, %v) = %v (%v), want %v", tt.a, tt.b, got, err, want)
+		}
+	}
+
+	t.Run("fresh results", func(t *testing.T) {
+		a := NewBalance(big.NewInt(100))
+		zero := Balance{}
+		got := a.SubClamp(zero)
+		got.Int.SetInt64(1)
+		res, err := a.MustSub(zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Int.SetInt64(1)
+		if !a.Equal(NewBalance(big.NewInt(100))) {
+			t.Error("results alias the original balance")
+		}
+	})
+}
+
 func TestBalance_Mul(t *testing.T) {
 	tests := []struct {
 		a    int64
@@ -87,15 +300,348 @@ func TestBalance_Mul(t *testing.T) {
 	}
 }
 
+func TestBalance_MulWithRounding(t *testing.T) {
+	tests := []struct {
+		a    int64
+		mul  float64
+		mode big.RoundingMode
+		want int64
+	}{
+		{1, 1.5, big.ToZero, 1},
+		{1, 1.5, big.AwayFromZero, 2},
+		{1, 1.5, big.ToPositiveInf, 2},
+		{1, 1.5, big.ToNegativeInf, 1},
+		{1, 1.5, big.ToNearestEven, 2},
+		{1, 2.5, big.ToNearestEven, 2},
+		{1, 2.5, big.ToNearestAway, 3},
+		{1, 1.25, big.ToNearestEven, 1},
+		{1, 1.75, big.ToNearestEven, 2},
+		{-1, 1.5, big.ToZero, -1},
+		{-1, 1.5, big.AwayFromZero, -2},
+		{-1, 1.5, big.ToPositiveInf, -1},
+		{-1, 1.5, big.ToNegativeInf, -2},
+		{-1, 2.5, big.ToNearestEven, -2},
+		{-1, 2.5, big.ToNearestAway, -3},
+		{100, 2.0, big.AwayFromZero, 200},
+		{1000, 1.5, big.AwayFromZero, 1500},
+	}
+
+	for _, tt := range tests {
+		a := NewBalance(big.NewInt(tt.a))
+		got := a.MulWithRounding(tt.mul, tt.mode)
+		if !got.Equal(NewBalance(big.NewInt(tt.want))) {
+			t.Errorf("MulWithRounding(%v, %v, %v) = %d, want %v", tt.a, tt.mul, tt.mode, got, tt.want)
+		}
+	}
+
+	// Mul truncates, rounding up differs
+	oneWei := NewBalance(big.NewInt(1))
+	if oneWei.Mul(1.5).Equal(oneWei.MulWithRounding(1.5, big.AwayFromZero)) {
+		t.Error("Mul does not differ from rounding away from zero")
+	}
+	if !oneWei.Mul(1.5).Equal(oneWei.MulWithRounding(1.5, big.ToZero)) {
+		t.Error("Mul does not truncate")
+	}
+
+	// the product is exact for large balances
+	large, _ := new(big.Int).SetString("123456789012345678901234567891", 10)
+	want, _ := new(big.Int).SetString("61728394506172839450617283946", 10)
+	if got := NewBalance(large).MulWithRounding(0.5, big.ToNearestAway); got.Int.Cmp(want) != 0 {
+		t.Errorf("MulWithRounding of large balance = %d, want %d", got, want)
+	}
+}
+
+func TestBalance_Percent(t *testing.T) {
+	tests := []struct {
+		a    int64
+		p    float64
+		want int64
+	}{
+		{1000, 2.5, 25},
+		{1000, 100, 1000},
+		{1000, 0, 0},
+		{1000, 150, 1500},
+		{999, 10, 99}, // 99.9 is truncated
+		{-999, 10, -99},
+		{1, 50, 0},
+		{3, 33.3, 0},
+		{1000, 0.1, 1},
+		{1000, -5, -50},
+	}
+
+	for _, tt := range tests {
+		a := NewBalance(big.NewInt(tt.a))
+		if got := a.Percent(tt.p); !got.Equal(NewBalance(big.NewInt(tt.want))) {
+			t.Errorf("Percent(%v, %v) = %d, want %v", tt.a, tt.p, got, tt.want)
+		}
+	}
+
+	t.Run("PercentOf", func(t *testing.T) {
+		eth := func(f float64) Balance {
+			return NewBalanceFromEther(f)
+		}
+		tests := []struct {
+			b, total Balance
+			want     float64
+		}{
+			{eth(0.025), eth(1), 2.5},
+			{eth(1), eth(1), 100},
+			{eth(2), eth(1), 200},
+			{eth(-1), eth(4), -25},
+			{eth(1), eth(3), 100.0 / 3},
+			{eth(1), Balance{}, 0},
+			{eth(1), eth(0), 0},
+			{Balance{}, eth(1), 0},
+		}
+		for _, tt := range tests {
+			if got := tt.b.PercentOf(tt.total); got != tt.want {
+				t.Errorf("PercentOf(%v, %v) = %v, want %v", tt.b, tt.total, got, tt.want)
+			}
+		}
+
+		// large values do not overflow
+		large, _ := new(big.Int).SetString("1000000000000000000000000000000000000000", 10)
+		if got := NewBalance(large).PercentOf(NewBalance(new(big.Int).Mul(large, big.NewInt(4)))); got != 25 {
+			t.Errorf("PercentOf of large values = %v, want 25", got)
+		}
+	})
+}
+
+func TestBalance_Ratio(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want *big.Rat
+	}{
+		{1, 1000, big.NewRat(1, 1000)},
+		{21000, 21000000, big.NewRat(1, 1000)},
+		{6, 4, big.NewRat(3, 2)},
+		{-6, 4, big.NewRat(-3, 2)},
+		{6, -4, big.NewRat(-3, 2)},
+		{-6, -4, big.NewRat(3, 2)},
+		{0, 7, new(big.Rat)},
+	}
+
+	for _, tt := range tests {
+		a := NewBalance(big.NewInt(tt.a))
+		b := NewBalance(big.NewInt(tt.b))
+		got, err := a.Ratio(b)
+		if err != nil {
+			t.Fatalf("Ratio(%v, %v) failed: %v", tt.a, tt.b, err)
+		}
+		if got.Cmp(tt.want) != 0 {
+			t.Errorf("Ratio(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if !a.Equal(NewBalance(big.NewInt(tt.a))) || !b.Equal(NewBalance(big.NewInt(tt.b))) {
+			t.Errorf("Ratio(%v, %v) modified its operands", tt.a, tt.b)
+		}
+	}
+
+	for _, zero := range []Balance{{}, NewBalance(big.NewInt(0))} {
+		if _, err := NewBalance(big.NewInt(1)).Ratio(zero); err == nil {
+			t.Error("Ratio to zero expected error")
+		}
+	}
+}
+
+func TestBalance_Div(t *testing.T) {
+	tests := []struct {
+		a, div int64
+		want   int64
+	}{
+		{100, 2, 50},
+		{100, 3, 33},
+		{101, 2, 50},
+		{-101, 2, -50},
+		{101, -2, -50},
+		{-100, -3, 33},
+		{0, 7, 0},
+	}
+
+	for _, tt := range tests {
+		a := NewBalance(big.NewInt(tt.a))
+		want := NewBalance(big.NewInt(tt.want))
+		if got := a.Div(tt.div); !got.Equal(want) {
+			t.Errorf("Div(%v, %v) = %v, want %v", tt.a, tt.div, got, want)
+		}
+		if got := a.DivBig(big.NewInt(tt.div)); !got.Equal(want) {
+			t.Errorf("DivBig(%v, %v) = %v, want %v", tt.a, tt.div, got, want)
+		}
+		// Verify the original balance is not modified
+		if !a.Equal(NewBalance(big.NewInt(tt.a))) {
+			t.Errorf("Div modified the original balance %v", tt.a)
+		}
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if got := (Balance{}).Div(3); !got.Equal(NewBalance(big.NewInt(0))) {
+			t.Errorf("Div of nil balance = %v, want 0", got)
+		}
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Div by zero did not panic")
+			}
+		}()
+		NewBalance(big.NewInt(100)).Div(0)
+	})
+}
+
+func TestBalance_Split(t *testing.T) {
+	large, _ := new(big.Int).SetString("1000000000000000000000000000001", 10)
+	tests := []struct {
+		name string
+		b    Balance
+		n    int
+		want []int64
+	}{
+		{"even", NewBalance(big.NewInt(9)), 3, []int64{3, 3, 3}},
+		{"10 Wei over 3", NewBalance(big.NewInt(10)), 3, []int64{4, 3, 3}},
+		{"11 Wei over 3", NewBalance(big.NewInt(11)), 3, []int64{4, 4, 3}},
+		{"less than n", NewBalance(big.NewInt(2)), 5, []int64{1, 1, 0, 0, 0}},
+		{"negative", NewBalance(big.NewInt(-10)), 3, []int64{-4, -3, -3}},
+		{"single", NewBalance(big.NewInt(7)), 1, []int64{7}},
+		{"nil", Balance{}, 2, []int64{0, 0}},
+		{"large", NewBalance(large), 7, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := tt.b.Split(tt.n)
+			if err != nil {
+				t.Fatalf("Split(%v, %d) failed: %v", tt.b, tt.n, err)
+			}
+			if len(shares) != tt.n {
+				t.Fatalf("Split(%v, %d) returned %d shares", tt.b, tt.n, len(shares))
+			}
+			if tt.want != nil {
+				for i, want := range tt.want {
+					if !shares[i].Equal(NewBalance(big.NewInt(want))) {
+						t.Errorf("share %d = %v, want %v", i, shares[i], want)
+					}
+				}
+			}
+			// the shares sum up to exactly the balance, and differ by at most 1 Wei
+			if total := Sum(shares...); !total.Equal(tt.b) {
+				t.Errorf("shares sum to %v, want %v", total, tt.b)
+			}
+			if spread := Max(shares...).Sub(Min(shares...)); spread.GreaterThan(NewBalance(big.NewInt(1))) {
+				t.Errorf("shares differ by %v", spread)
+			}
+			// each share is a fresh value
+			shares[0].Int.SetInt64(12345)
+			if tt.n > 1 && shares[1].Int.Cmp(big.NewInt(12345)) == 0 {
+				t.Error("shares alias each other")
+			}
+		})
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := NewBalance(big.NewInt(10)).Split(n); err == nil {
+			t.Errorf("Split(10, %d) expected error", n)
+		}
+	}
+}
+
+func TestBalance_DistributeByWeights(t *testing.T) {
+	large, _ := new(big.Int).SetString("1000000000000000000000000000001", 10)
+	tests := []struct {
+		name    string
+		b       Balance
+		weights []uint64
+		want    []int64
+	}{
+		{"exact", NewBalance(big.NewInt(100)), []uint64{1, 3, 6}, []int64{10, 30, 60}},
+		{"equal remainders", NewBalance(big.NewInt(10)), []uint64{1, 1, 4}, []int64{2, 2, 6}},
+		{"largest remainders", NewBalance(big.NewInt(10)), []uint64{1, 2, 4}, []int64{1, 3, 6}},
+		{"largest remainder wins over earlier", NewBalance(big.NewInt(100)), []uint64{1, 2, 4}, []int64{14, 29, 57}},
+		{"ties go to earlier shares", NewBalance(big.NewInt(10)), []uint64{1, 1, 1}, []int64{4, 3, 3}},
+		{"ties after larger remainder", NewBalance(big.NewInt(5)), []uint64{2, 1, 1, 2}, []int64{2, 1, 1, 1}},
+		{"zero weight", NewBalance(big.NewInt(7)), []uint64{0, 1, 0, 1}, []int64{0, 4, 0, 3}},
+		{"negative", NewBalance(big.NewInt(-10)), []uint64{1, 2, 4}, []int64{-1, -3, -6}},
+		{"nil", Balance{}, []uint64{1, 2}, []int64{0, 0}},
+		{"single", NewBalance(big.NewInt(7)), []uint64{3}, []int64{7}},
+		{"large weights", NewBalance(big.NewInt(3)), []uint64{math.MaxUint64, math.MaxUint64, 1}, []int64{2, 1, 0}},
+		{"large", NewBalance(large), []uint64{3, 5, 7, 11}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.b.Clone()
+			shares, err := tt.b.DistributeByWeights(tt.weights)
+			if err != nil {
+				t.Fatalf("DistributeByWeights(%v, %v) failed: %v", tt.b, tt.weights, err)
+			}
+			if len(shares) != len(tt.weights) {
+				t.Fatalf("DistributeByWeights(%v, %v) returned %d shares", tt.b, tt.weights, len(shares))
+			}
+			for i, want := range tt.want {
+				if !shares[i].Equal(NewBalance(big.NewInt(want))) {
+					t.Errorf("share %d = %v, want %v", i, shares[i].WeiString(), want)
+				}
+			}
+			// the shares sum up to exactly the balance
+			if total := Sum(shares...); !total.Equal(tt.b) {
+				t.Errorf("shares sum to %v, want %v", total, tt.b)
+			}
+			if !tt.b.Equal(orig) {
+				t.Error("DistributeByWeights modified the balance")
+			}
+		})
+	}
+
+	for _, weights := range [][]uint64{nil, {}, {0}, {0, 0}} {
+		if _, err := NewBalance(big.NewInt(10)).DistributeByWeights(weights); err == nil {
+			t.Errorf("DistributeByWeights(10, %v) expected error", weights)
+		}
+	}
+}
+
+func TestBalance_AbsNeg(t *testing.T) {
+	tests := []struct {
+		name       string
+		b          Balance
+		abs, neg   int64
+		wantOrigin int64
+	}{
+		{"nil", Balance{}, 0, 0, 0},
+		{"zero", NewBalance(big.NewInt(0)), 0, 0, 0},
+		{"positive", NewBalance(big.NewInt(5)), 5, -5, 5},
+		{"negative", NewBalance(big.NewInt(-5)), 5, 5, -5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abs := tt.b.Abs()
+			if !abs.Equal(NewBalance(big.NewInt(tt.abs))) {
+				t.Errorf("Abs() = %d, want %d", abs, tt.abs)
+			}
+			neg := tt.b.Neg()
+			if !neg.Equal(NewBalance(big.NewInt(tt.neg))) {
+				t.Errorf("Neg() = %d, want %d", neg, tt.neg)
+			}
+			// the receiver is untouched, and the results are fresh values
+			abs.Int.SetInt64(100)
+			neg.Int.SetInt64(100)
+			if !tt.b.Equal(NewBalance(big.NewInt(tt.wantOrigin))) {
+				t.Errorf("receiver changed to %d", tt.b)
+			}
+		})
+	}
+}
+
 func TestBalance_Comparisons(t *testing.T) {
 	tests := []struct {
 		a, b       int64
 		gt, lt, eq bool
+		cmp        int
 	}{
-		{100, 200, false, true, false},
-		{200, 100, true, false, false},
-		{100, 100, false, false, true},
-		{0, 100, false, true, false},
+		{100, 200, false, true, false, -1},
+		{200, 100, true, false, false, 1},
+		{100, 100, false, false, true, 0},
+		{0, 100, false, true, false, -1},
+		{-100, 0, false, true, false, -1},
 	}
 
 	for _, tt := range tests {
@@ -106,14 +652,350 @@ func TestBalance_Comparisons(t *testing.T) {
 			t.Errorf("GreaterThan(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.gt)
 		}
 
+		if got := a.GreaterThanOrEqual(b); got != (tt.gt || tt.eq) {
+			t.Errorf("GreaterThanOrEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.gt || tt.eq)
+		}
+
 		if got := a.LessThan(b); got != tt.lt {
 			t.Errorf("LessThan(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.lt)
 		}
 
+		if got := a.LessThanOrEqual(b); got != (tt.lt || tt.eq) {
+			t.Errorf("LessThanOrEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.lt || tt.eq)
+		}
+
 		if got := a.Equal(b); got != tt.eq {
 			t.Errorf("Equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.eq)
 		}
+
+		if got := a.Cmp(b); got != tt.cmp {
+			t.Errorf("Cmp(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.cmp)
+		}
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var zero Balance
+		one := NewBalance(big.NewInt(1))
+		if zero.Cmp(one) != -1 || one.Cmp(zero) != 1 || zero.Cmp(Balance{}) != 0 {
+			t.Error("Cmp does not treat nil as zero")
+		}
+		if !zero.GreaterThanOrEqual(zero) || !zero.LessThanOrEqual(zero) {
+			t.Error("inclusive comparisons do not treat nil as zero")
+		}
+		if zero.GreaterThanOrEqual(one) || !zero.LessThanOrEqual(one) {
+			t.Error("inclusive comparisons of nil and one are wrong")
+		}
+	})
+}
+
+func TestBalance_Sign(t *testing.T) {
+	tests := []struct {
+		name         string
+		b            Balance
+		wantSign     int
+		wantZero     bool
+		wantPositive bool
+		wantNegative bool
+	}{
+		{"nil", Balance{}, 0, true, false, false},
+		{"zero", NewBalance(big.NewInt(0)), 0, true, false, false},
+		{"positive", NewBalance(big.NewInt(1)), 1, false, true, false},
+		{"negative", NewBalance(big.NewInt(-1)), -1, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.b.Sign(); got != tt.wantSign {
+				t.Errorf("Sign() = %v, want %v", got, tt.wantSign)
+			}
+			if got := tt.b.IsZero(); got != tt.wantZero {
+				t.Errorf("IsZero() = %v, want %v", got, tt.wantZero)
+			}
+			if got := tt.b.IsPositive(); got != tt.wantPositive {
+				t.Errorf("IsPositive() = %v, want %v", got, tt.wantPositive)
+			}
+			if got := tt.b.IsNegative(); got != tt.wantNegative {
+				t.Errorf("IsNegative() = %v, want %v", got, tt.wantNegative)
+			}
+		})
+	}
+}
+
+func TestBalance_Clamp(t *testing.T) {
+	tests := []struct {
+		name            string
+		v, lower, upper int64
+		want            int64
+	}{
+		{"below min", 50, 100, 200, 100},
+		{"at min", 100, 100, 200, 100},
+		{"in range", 150, 100, 200, 150},
+		{"at max", 200, 100, 200, 200},
+		{"above max", 250, 100, 200, 200},
+		{"inverted bounds", 150, 200, 100, 200},
+		{"inverted bounds, below both", 50, 200, 100, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewBalance(big.NewInt(tt.v))
+			got := v.Clamp(NewBalance(big.NewInt(tt.lower)), NewBalance(big.NewInt(tt.upper)))
+			if !got.Equal(NewBalance(big.NewInt(tt.want))) {
+				t.Errorf("Clamp(%v, %v, %v) = %v, want %v", tt.v, tt.lower, tt.upper, got, tt.want)
+			}
+			// Verify the result does not alias the inputs
+			got.Int.SetInt64(-1)
+			if !v.Equal(NewBalance(big.NewInt(tt.v))) {
+				t.Error("Clamp result aliases the original balance")
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		got := Balance{}.Clamp(NewBalance(big.NewInt(-10)), NewBalance(big.NewInt(10)))
+		if !got.Equal(NewBalance(big.NewInt(0))) {
+			t.Errorf("Clamp of nil balance = %v, want 0", got)
+		}
+		got = NewBalance(big.NewInt(5)).Clamp(Balance{}, Balance{})
+		if !got.Equal(NewBalance(big.NewInt(0))) {
+			t.Errorf("Clamp to nil bounds = %v, want 0", got)
+		}
+	})
+}
+
+func TestBalance_CheckBounds(t *testing.T) {
+	balance := func(i int64) Balance {
+		return NewBalance(big.NewInt(i))
+	}
+	tests := []struct {
+		name     string
+		v        Balance
+		min, max Balance
+		wantErr  error
+	}{
+		{"below min", balance(50), balance(100), balance(200), ErrBelowMin},
+		{"at min", balance(100), balance(100), balance(200), nil},
+		{"in range", balance(150), balance(100), balance(200), nil},
+		{"at max", balance(200), balance(100), balance(200), nil},
+		{"above max", balance(250), balance(100), balance(200), ErrAboveMax},
+		{"no min", balance(-50), Balance{}, balance(200), nil},
+		{"no max", balance(1000), balance(100), Balance{}, nil},
+		{"unbounded", balance(-1000), Balance{}, Balance{}, nil},
+		{"nil value below min", Balance{}, balance(1), Balance{}, ErrBelowMin},
+		{"nil value above max", Balance{}, Balance{}, balance(-1), ErrAboveMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.CheckBounds(tt.min, tt.max)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckBounds(%v, %v, %v) = %v, want %v", tt.v, tt.min, tt.max, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("offending value", func(t *testing.T) {
+		err := balance(250).CheckBounds(balance(100), balance(200))
+		if err == nil || !strings.Contains(err.Error(), "250") {
+			t.Errorf("CheckBounds error %v does not contain the offending value", err)
+		}
+	})
+}
+
+func TestBalance_WithinTolerance(t *testing.T) {
+	balance := func(i int64) Balance {
+		return NewBalance(big.NewInt(i))
+	}
+	tests := []struct {
+		name     string
+		v, other Balance
+		tol      Balance
+		want     bool
+	}{
+		{"equal", balance(100), balance(100), Balance{}, true},
+		{"below, at tolerance", balance(90), balance(100), balance(10), true},
+		{"above, at tolerance", balance(110), balance(100), balance(10), true},
+		{"below, just outside", balance(89), balance(100), balance(10), false},
+		{"above, just outside", balance(111), balance(100), balance(10), false},
+		{"zero tolerance", balance(101), balance(100), balance(0), false},
+		{"negative tolerance", balance(100), balance(100), balance(-1), false},
+		{"negative balances", balance(-95), balance(-100), balance(5), true},
+		{"nil value", Balance{}, balance(3), balance(3), true},
+		{"nil other", balance(-4), Balance{}, balance(3), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, other, tol := tt.v.Clone(), tt.other.Clone(), tt.tol.Clone()
+			if got := tt.v.WithinTolerance(tt.other, tt.tol); got != tt.want {
+				t.Errorf("WithinTolerance(%v, %v, %v) = %v, want %v", tt.v, tt.other, tt.tol, got, tt.want)
+			}
+			if tt.v.Cmp(v) != 0 || tt.other.Cmp(other) != 0 || tt.tol.Cmp(tol) != 0 {
+				t.Error("WithinTolerance modified an operand")
+			}
+		})
+	}
+}
+
+func TestBalance_WithinPercent(t *testing.T) {
+	balance := func(i int64) Balance {
+		return NewBalance(big.NewInt(i))
+	}
+	tests := []struct {
+		name     string
+		v, other Balance
+		pct      float64
+		want     bool
+	}{
+		{"equal", balance(1000), balance(1000), 0, true},
+		{"below, at tolerance", balance(990), balance(1000), 1, true},
+		{"above, at tolerance", balance(1010), balance(1000), 1, true},
+		{"below, just outside", balance(989), balance(1000), 1, false},
+		{"above, just outside", balance(1011), balance(1000), 1, false},
+		{"fractional percent", balance(1_000_005), balance(1_000_000), 0.0005, true},
+		{"fractional percent, just outside", balance(1_000_006), balance(1_000_000), 0.0005, false},
+		{"decimal percent, at tolerance", balance(1_000_001), balance(1_000_000), 0.0001, true},
+		{"negative balances", balance(-1010), balance(-1000), 1, true},
+		{"zero other", balance(1), balance(0), 50, false},
+		{"both zero", Balance{}, balance(0), 0, true},
+		{"nil other", balance(1), Balance{}, 100, false},
+		{"negative percent", balance(1000), balance(1000), -1, false},
+		{"NaN percent", balance(1000), balance(1000), math.NaN(), false},
+		{"infinite percent", balance(1000), balance(1000), math.Inf(1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, other := tt.v.Clone(), tt.other.Clone()
+			if got := tt.v.WithinPercent(tt.other, tt.pct); got != tt.want {
+				t.Errorf("WithinPercent(%v, %v, %v) = %v, want %v", tt.v, tt.other, tt.pct, got, tt.want)
+			}
+			if tt.v.Cmp(v) != 0 || tt.other.Cmp(other) != 0 {
+				t.Error("WithinPercent modified an operand")
+			}
+		})
+	}
+}
+
+func TestSummarizeBalances(t *testing.T) {
+	balance := func(i int64) Balance {
+		return NewBalance(big.NewInt(i))
+	}
+	large, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := []struct {
+		name      string
+		bs        []Balance
+		wantTotal Balance
+		wantCount int
+		wantMax   Balance
+	}{
+		{"empty", nil, balance(0), 0, balance(0)},
+		{"single", []Balance{balance(42)}, balance(42), 1, balance(42)},
+		{"single nil", []Balance{{}}, balance(0), 1, balance(0)},
+		{"mixed", []Balance{balance(10), {}, NewBalance(large), balance(-5)},
+			NewBalance(new(big.Int).Add(large, big.NewInt(5))), 4, NewBalance(large)},
+		{"negative", []Balance{balance(-10), balance(-5), balance(-7)}, balance(-22), 3, balance(-5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, count, max := SummarizeBalances(tt.bs)
+			if !total.Equal(tt.wantTotal) {
+				t.Errorf("total = %v, want %v", total, tt.wantTotal)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %v, want %v", count, tt.wantCount)
+			}
+			if !max.Equal(tt.wantMax) {
+				t.Errorf("max = %v, want %v", max, tt.wantMax)
+			}
+		})
+	}
+
+	t.Run("no aliasing", func(t *testing.T) {
+		bs := []Balance{balance(1), balance(2)}
+		_, _, max := SummarizeBalances(bs)
+		max.Int.SetInt64(100)
+		if !bs[1].Equal(balance(2)) {
+			t.Error("max aliases an input balance")
+		}
+	})
+}
+
+func TestBalance_Conversions(t *testing.T) {
+	oneEth := NewBalance(big.NewInt(1e18))
+	if got := oneEth.ToGwei(); got.Cmp(big.NewFloat(1e9)) != 0 {
+		t.Errorf("ToGwei(1 ETH) = %v, want 1e9", got)
+	}
+	if got := oneEth.ToEther(); got.Cmp(big.NewFloat(1)) != 0 {
+		t.Errorf("ToEther(1 ETH) = %v, want 1", got)
+	}
+	if got := NewBalance(big.NewInt(1500000000)).ToGwei(); got.Cmp(big.NewFloat(1.5)) != 0 {
+		t.Errorf("ToGwei(1.5 Gwei) = %v, want 1.5", got)
+	}
+
+	// 1 Wei differences are representable at ETH scale
+	large, _ := new(big.Int).SetString("123456789000000000000000000", 10)
+	a := NewBalance(large)
+	b := a.Add(NewBalance(big.NewInt(1)))
+	if a.ToEther().Cmp(b.ToEther()) == 0 {
+		t.Errorf("ToEther(%v) = ToEther(%v), want a 1 Wei difference", a, b)
+	}
+
+	// ToWei returns a copy
+	wei := oneEth.ToWei()
+	if wei.Cmp(big.NewInt(1e18)) != 0 {
+		t.Errorf("ToWei(1 ETH) = %v, want 1e18", wei)
+	}
+	wei.SetInt64(0)
+	if !oneEth.Equal(NewBalance(big.NewInt(1e18))) {
+		t.Error("ToWei result aliases the balance")
+	}
+
+	var zero Balance
+	if zero.ToEther().Sign() != 0 || zero.ToGwei().Sign() != 0 || zero.ToWei().Sign() != 0 {
+		t.Error("conversions of nil balance are not zero")
+	}
+}
+
+func TestMinMaxSum(t *testing.T) {
+	balance := func(i int64) Balance {
+		return NewBalance(big.NewInt(i))
 	}
+	tests := []struct {
+		name          string
+		bs            []Balance
+		min, max, sum Balance
+	}{
+		{"none", nil, balance(0), balance(0), balance(0)},
+		{"single", []Balance{balance(5)}, balance(5), balance(5), balance(5)},
+		{"positive", []Balance{balance(3), balance(10), balance(7)}, balance(3), balance(10), balance(20)},
+		{"mixed signs", []Balance{balance(3), balance(-10), balance(7)}, balance(-10), balance(7), balance(0)},
+		{"negative", []Balance{balance(-3), balance(-1)}, balance(-3), balance(-1), balance(-4)},
+		{"nil", []Balance{{}, balance(-2), balance(2)}, balance(-2), balance(2), balance(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Min(tt.bs...); !got.Equal(tt.min) {
+				t.Errorf("Min() = %v, want %v", got, tt.min)
+			}
+			if got := Max(tt.bs...); !got.Equal(tt.max) {
+				t.Errorf("Max() = %v, want %v", got, tt.max)
+			}
+			if got := Sum(tt.bs...); !got.Equal(tt.sum) {
+				t.Errorf("Sum() = %v, want %v", got, tt.sum)
+			}
+		})
+	}
+
+	t.Run("no aliasing", func(t *testing.T) {
+		bs := []Balance{balance(1), balance(2)}
+		for _, got := range []Balance{Min(bs...), Max(bs...), Sum(bs[:1]...)} {
+			got.Int.SetInt64(100)
+		}
+		if !bs[0].Equal(balance(1)) || !bs[1].Equal(balance(2)) {
+			t.Errorf("results alias the inputs: %v", bs)
+		}
+	})
 }
 
 func TestBalance_LogValue(t *testing.T) {
@@ -145,3 +1027,509 @@ func TestBalance_LogValue(t *testing.T) {
 		t.Errorf("LogValue() for nil balance = %v, want '0 ETH'", got)
 	}
 }
+
+func TestBalance_FormatWith(t *testing.T) {
+	gasFormat := BalanceFormat{MinEther: 1, MinGwei: 0.001, Decimals: 9}
+	tests := []struct {
+		wei    string
+		format BalanceFormat
+		want   string
+	}{
+		{"1500000000000000000", DefaultBalanceFormat, "1.5 ETH"},
+		{"-1500000000000000000", DefaultBalanceFormat, "-1.5 ETH"},
+		{"-100", DefaultBalanceFormat, "-100 Wei"},
+		{"0", DefaultBalanceFormat, "0 Wei"},
+		{"123456000000000000000000", DefaultBalanceFormat, "123000 ETH"},
+		{"123456789000000000000000000000", DefaultBalanceFormat, "123000000000 ETH"},
+		{"1234567", DefaultBalanceFormat, "0.00123 Gwei"},
+		{"1234567000000000000", BalanceFormat{MinEther: 0.001, Digits: 6}, "1.23457 ETH"},
+		{"12000000000000", BalanceFormat{MinEther: 0, Digits: 3}, "0.000012 ETH"},
+		{"21000123456789", gasFormat, "21000.123456789 Gwei"},
+		{"2000000000000000000", gasFormat, "2.000000000 ETH"},
+		{"1", gasFormat, "1 Wei"},
+		{"1000000000", BalanceFormat{MinEther: 0.001, MinGwei: 0.001}, "1 Gwei"},
+	}
+
+	for _, tt := range tests {
+		i, _ := new(big.Int).SetString(tt.wei, 10)
+		b := NewBalance(i)
+		if got := b.FormatWith(tt.format); got != tt.want {
+			t.Errorf("FormatWith(%v Wei, %+v) = %v, want %v", tt.wei, tt.format, got, tt.want)
+		}
+		if got := b.LogValueWith(tt.format).String(); got != tt.want {
+			t.Errorf("LogValueWith(%v Wei, %+v) = %v, want %v", tt.wei, tt.format, got, tt.want)
+		}
+	}
+
+	if got := (Balance{}).FormatWith(DefaultBalanceFormat); got != "0 Wei" {
+		t.Errorf("FormatWith() for nil balance = %v, want '0 Wei'", got)
+	}
+}
+
+func TestBalance_String(t *testing.T) {
+	tests := []struct {
+		wei  string
+		want string
+	}{
+		{"1500000000000000000", "1.5 ETH"},
+		{"1000000000000000", "0.001 ETH"},
+		{"999999999999999", "1000000 Gwei"},
+		{"990000000000000", "990000 Gwei"},
+		{"1000000", "0.001 Gwei"},
+		{"999999", "999999 Wei"},
+		{"0", "0 Wei"},
+		{"-2000000000000000000", "-2 ETH"},
+	}
+
+	for _, tt := range tests {
+		i, _ := new(big.Int).SetString(tt.wei, 10)
+		b := NewBalance(i)
+		if got := b.String(); got != tt.want {
+			t.Errorf("String() for %v Wei = %v, want %v", tt.wei, got, tt.want)
+		}
+		if got := fmt.Sprintf("%s|%v", b, b); got != tt.want+"|"+tt.want {
+			t.Errorf("formatted %v Wei = %v, want %v", tt.wei, got, tt.want)
+		}
+		if got := b.WeiString(); got != tt.wei {
+			t.Errorf("WeiString() = %v, want %v", got, tt.wei)
+		}
+	}
+
+	// other verbs format the Wei amount
+	if got := fmt.Sprintf("%d %x", NewBalance(big.NewInt(255)), NewBalance(big.NewInt(255))); got != "255 ff" {
+		t.Errorf("formatted 255 Wei = %v, want '255 ff'", got)
+	}
+
+	var nilBalance Balance
+	if got := nilBalance.String(); got != "0 ETH" {
+		t.Errorf("String() for nil balance = %v, want '0 ETH'", got)
+	}
+	if got := nilBalance.WeiString(); got != "0" {
+		t.Errorf("WeiString() for nil balance = %v, want '0'", got)
+	}
+}
+
+func TestBalance_TextRoundTrip(t *testing.T) {
+	tests := []string{"0", "100", "-100", "1500000000000000000", "123456789012345678901234567890"}
+	for _, wei := range tests {
+		i, _ := new(big.Int).SetString(wei, 10)
+		b := NewBalance(i)
+		text, err := b.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) failed: %v", wei, err)
+		}
+		if string(text) != wei {
+			t.Errorf("MarshalText(%v) = %s, want %s", wei, text, wei)
+		}
+		var got Balance
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%s) failed: %v", text, err)
+		}
+		if !got.Equal(b) {
+			t.Errorf("UnmarshalText(%s) = %v, want %v", text, got, b)
+		}
+	}
+
+	// Test nil case
+	var nilBalance Balance
+	text, err := nilBalance.MarshalText()
+	if err != nil || string(text) != "0" {
+		t.Errorf("MarshalText() for nil balance = %s (%v), want '0'", text, err)
+	}
+
+	// Test invalid input
+	var b Balance
+	for _, input := range []string{"abc", "1.5", "15e-1", "0x10"} {
+		if err := b.UnmarshalText([]byte(input)); err == nil {
+			t.Errorf("UnmarshalText(%q) expected error", input)
+		}
+	}
+}
+
+func TestBalance_TextUnits(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.5 ETH", "1500000000000000000"},
+		{"  200 gwei\n", "200000000000"},
+		{"1000000 Wei", "1000000"},
+		{"-2 eth", "-2000000000000000000"},
+	}
+	for _, tt := range tests {
+		var b Balance
+		if err := b.UnmarshalText([]byte(tt.input)); err != nil {
+			t.Fatalf("UnmarshalText(%q) failed: %v", tt.input, err)
+		}
+		text, err := b.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) failed: %v", b, err)
+		}
+		if string(text) != tt.want {
+			t.Errorf("UnmarshalText(%q) round-trips as %s, want %s", tt.input, text, tt.want)
+		}
+		// the canonical form decodes to the same balance
+		var got Balance
+		if err := got.UnmarshalText(text); err != nil || !got.Equal(b) {
+			t.Errorf("UnmarshalText(%s) = %v (%v), want %v", text, got, err, b)
+		}
+	}
+}
+
+func TestBalance_Flag(t *testing.T) {
+	var amount Balance
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.TextVar(&amount, "amount", NewBalance(big.NewInt(1)), "amount to send")
+	if !amount.Equal(NewBalance(big.NewInt(1))) {
+		t.Errorf("default flag value = %v, want 1", amount)
+	}
+	if err := fs.Parse([]string{"-amount", "0.5 ETH"}); err != nil {
+		t.Fatalf("failed to parse flag: %v", err)
+	}
+	if !amount.Equal(NewBalance(big.NewInt(5e17))) {
+		t.Errorf("flag value = %v, want 0.5 ETH", amount)
+	}
+	if got := fs.Lookup("amount").Value.String(); got != "500000000000000000" {
+		t.Errorf("flag string = %s", got)
+	}
+	if err := fs.Parse([]string{"-amount", "1 BTC"}); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
+
+func TestBalance_TextTOML(t *testing.T) {
+	var cfg struct {
+		Amount Balance `toml:"amount"`
+	}
+	if _, err := toml.Decode(`amount = "1500000000000000000"`, &cfg); err != nil {
+		t.Fatalf("failed to decode TOML: %v", err)
+	}
+	want := NewBalance(big.NewInt(1500000000000000000))
+	if !cfg.Amount.Equal(want) {
+		t.Errorf("decoded TOML amount = %v, want %v", cfg.Amount, want)
+	}
+	// TOML encoding uses the text encoding as well
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		t.Fatalf("failed to encode TOML: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != `amount = "1500000000000000000"` {
+		t.Errorf("encoded TOML = %s", got)
+	}
+}
+
+func TestBalance_TextEnv(t *testing.T) {
+	t.Setenv("TEST_BALANCE", " 2000000000000000000 ")
+	var b Balance
+	if err := b.UnmarshalText([]byte(os.Getenv("TEST_BALANCE"))); err != nil {
+		t.Fatalf("UnmarshalText from env failed: %v", err)
+	}
+	if !b.Equal(NewBalance(big.NewInt(2000000000000000000))) {
+		t.Errorf("UnmarshalText from env = %v", b)
+	}
+
+	// an unset variable decodes as zero
+	if err := b.UnmarshalText([]byte(os.Getenv("TEST_BALANCE_UNSET"))); err != nil {
+		t.Fatalf("UnmarshalText of empty string failed: %v", err)
+	}
+	if b.Int.Sign() != 0 {
+		t.Errorf("UnmarshalText of empty string = %v, want 0", b)
+	}
+}
+
+func TestBalance_JSON(t *testing.T) {
+	tests := []string{"0", "100", "-1500000000000000000", "123456789012345678901234567890"}
+	for _, wei := range tests {
+		i, _ := new(big.Int).SetString(wei, 10)
+		b := NewBalance(i)
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%v) failed: %v", wei, err)
+		}
+		if string(data) != `"`+wei+`"` {
+			t.Errorf("MarshalJSON(%v) = %s, want %q", wei, data, wei)
+		}
+		var got Balance
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("UnmarshalJSON(%s) failed: %v", data, err)
+		}
+		if !got.Equal(b) {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", data, got, b)
+		}
+	}
+
+	// Embedded in a struct, including a nil balance
+	var cfg struct {
+		Amount Balance `json:"amount"`
+		Unset  Balance `json:"unset"`
+	}
+	cfg.Amount = NewBalance(big.NewInt(1500000000000000000))
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to encode struct: %v", err)
+	}
+	if string(data) != `{"amount":"1500000000000000000","unset":"0"}` {
+		t.Errorf("encoded struct = %s", data)
+	}
+
+	decodeTests := []struct {
+		input string
+		want  int64
+	}{
+		{`"42"`, 42},
+		{`42`, 42},
+		{`-42`, -42},
+		{`1e3`, 1000},
+		{`null`, 0},
+		{`""`, 0},
+	}
+	for _, tt := range decodeTests {
+		var got Balance
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Fatalf("UnmarshalJSON(%s) failed: %v", tt.input, err)
+		}
+		if !got.Equal(NewBalance(big.NewInt(tt.want))) {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{`1.5`, `15e-1`, `"1.5"`, `"abc"`, `true`, `{}`} {
+		var got Balance
+		if err := json.Unmarshal([]byte(input), &got); err == nil {
+			t.Errorf("UnmarshalJSON(%s) expected error", input)
+		}
+	}
+}
+
+func TestBalance_ZeroValue(t *testing.T) {
+	var zero Balance
+	one := NewBalance(big.NewInt(1))
+	isZero := func(name string, got Balance) {
+		t.Helper()
+		if got.Int == nil || got.Int.Sign() != 0 {
+			t.Errorf("%s of zero-value balance = %v, want 0", name, got.Int)
+		}
+	}
+
+	isZero("NewBalance(nil)", NewBalance(nil))
+	isZero("Add", zero.Add(zero))
+	if got := zero.Add(one); !got.Equal(one) {
+		t.Errorf("Add(0, 1) = %v, want 1", got)
+	}
+	if got := one.Sub(zero); !got.Equal(one) {
+		t.Errorf("Sub(1, 0) = %v, want 1", got)
+	}
+	isZero("Sub", zero.Sub(zero))
+	isZero("Mul", zero.Mul(2.5))
+	isZero("Div", zero.Div(2))
+	isZero("DivBig", zero.DivBig(big.NewInt(2)))
+	if zero.GreaterThan(zero) || zero.LessThan(zero) || !zero.Equal(zero) {
+		t.Error("zero-value balance does not compare equal to itself")
+	}
+	if !zero.LessThan(one) || !one.GreaterThan(zero) || zero.Equal(one) {
+		t.Error("zero-value balance does not compare as zero")
+	}
+	if !zero.Equal(NewBalance(big.NewInt(0))) {
+		t.Error("zero-value balance does not equal an explicit zero")
+	}
+	if zero.Sign() != 0 || !zero.IsZero() || zero.IsPositive() || zero.IsNegative() {
+		t.Error("zero-value balance sign is not zero")
+	}
+	isZero("Clamp", zero.Clamp(zero, one))
+	if err := zero.CheckBounds(zero, one); err != nil {
+		t.Errorf("CheckBounds of zero-value balance failed: %v", err)
+	}
+	if zero.ToEther().Sign() != 0 || zero.ToGwei().Sign() != 0 || zero.ToWei().Sign() != 0 {
+		t.Error("conversions of zero-value balance are not zero")
+	}
+	if got := zero.LogValue().String(); got != "0 ETH" {
+		t.Errorf("LogValue of zero-value balance = %v", got)
+	}
+	if text, err := zero.MarshalText(); err != nil || string(text) != "0" {
+		t.Errorf("MarshalText of zero-value balance = %s (%v)", text, err)
+	}
+	if data, err := zero.MarshalJSON(); err != nil || string(data) != `"0"` {
+		t.Errorf("MarshalJSON of zero-value balance = %s (%v)", data, err)
+	}
+	var decoded Balance
+	if err := decoded.UnmarshalText([]byte("")); err != nil {
+		t.Errorf("UnmarshalText into zero-value balance failed: %v", err)
+	}
+	isZero("UnmarshalText", decoded)
+	decoded = Balance{}
+	if err := decoded.UnmarshalJSON([]byte("null")); err != nil {
+		t.Errorf("UnmarshalJSON into zero-value balance failed: %v", err)
+	}
+	isZero("UnmarshalJSON", decoded)
+	total, count, max := SummarizeBalances([]Balance{zero, zero})
+	isZero("SummarizeBalances total", total)
+	isZero("SummarizeBalances max", max)
+	if count != 2 {
+		t.Errorf("SummarizeBalances count = %d, want 2", count)
+	}
+
+	// A config struct with a missing field decodes to a usable zero value
+	var cfg struct {
+		Amount Balance `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{}`), &cfg); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	isZero("missing field", cfg.Amount.Add(zero))
+}
+
+func TestBalance_SQL(t *testing.T) {
+	var _ driver.Valuer = Balance{}
+	var _ sql.Scanner = (*Balance)(nil)
+
+	large, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, b := range []Balance{NewBalance(large), NewBalance(big.NewInt(0)), {}} {
+		v, err := b.Value()
+		if err != nil {
+			t.Fatalf("Value(%v) failed: %v", b, err)
+		}
+		if v != b.WeiString() {
+			t.Errorf("Value(%v) = %v, want %v", b, v, b.WeiString())
+		}
+		var got Balance
+		if err := got.Scan(v); err != nil {
+			t.Fatalf("Scan(%v) failed: %v", v, err)
+		}
+		if !got.Equal(b) {
+			t.Errorf("Scan(%v) = %v, want %v", v, got, b)
+		}
+	}
+
+	tests := []struct {
+		src  any
+		want int64
+	}{
+		{"1500", 1500},
+		{[]byte("-42"), -42},
+		{int64(7), 7},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		got := NewBalance(big.NewInt(99))
+		if err := got.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%#v) failed: %v", tt.src, err)
+		}
+		if !got.Equal(NewBalance(big.NewInt(tt.want))) {
+			t.Errorf("Scan(%#v) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []any{float64(1.5), float64(1), "1.5", "abc", "1 ETH", true} {
+		var got Balance
+		if err := got.Scan(src); err == nil {
+			t.Errorf("Scan(%#v) expected error", src)
+		}
+	}
+}
+
+func TestBalance_Binary(t *testing.T) {
+	var _ encoding.BinaryMarshaler = Balance{}
+	var _ encoding.BinaryUnmarshaler = (*Balance)(nil)
+
+	large, _ := new(big.Int).SetString("123456789012345678901234567890123456789012345678901234567890", 10)
+	huge := new(big.Int).Lsh(big.NewInt(1), 4096)
+	balances := []Balance{
+		{},
+		NewBalance(big.NewInt(0)),
+		NewBalance(big.NewInt(1)),
+		NewBalance(big.NewInt(-1)),
+		NewBalance(big.NewInt(1e18)),
+		NewBalance(large),
+		NewBalance(new(big.Int).Neg(large)),
+		NewBalance(huge),
+		NewBalance(new(big.Int).Neg(huge)),
+	}
+	for _, b := range balances {
+		data, err := b.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%v) failed: %v", b, err)
+		}
+		got := NewBalance(big.NewInt(99))
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary(%x) failed: %v", data, err)
+		}
+		if !got.Equal(b) {
+			t.Errorf("binary round-trip of %v = %v", b.WeiString(), got.WeiString())
+		}
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		got := NewBalance(big.NewInt(99))
+		if err := got.UnmarshalBinary(nil); err != nil || got.Int == nil || !got.IsZero() {
+			t.Errorf("UnmarshalBinary(nil) = %v (%v), want zero", got, err)
+		}
+	})
+
+	t.Run("empty amount", func(t *testing.T) {
+		got := NewBalance(big.NewInt(99))
+		if err := got.UnmarshalBinary([]byte{1, 0}); err != nil || got.Int == nil || !got.IsZero() {
+			t.Errorf("UnmarshalBinary(0100) = %v (%v), want zero", got, err)
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		data, _ := NewBalance(big.NewInt(-258)).MarshalBinary()
+		// the gob encoding of the big.Int: its version shifted left, with the sign in the lowest bit
+		if want := []byte{1, 3, 3, 1, 2}; !bytes.Equal(data, want) {
+			t.Errorf("MarshalBinary(-258) = %x, want %x", data, want)
+		}
+	})
+
+	for _, data := range [][]byte{
+		{2, 0},          // unknown version
+		{1},             // missing length
+		{1, 3, 2, 1},    // truncated
+		{1, 2, 2, 1, 2}, // trailing bytes
+		{1, 1, 4},       // unknown big.Int gob version
+		{1, 0x80},       // invalid length
+	} {
+		var got Balance
+		if err := got.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%x) expected error", data)
+		}
+	}
+}
+
+func TestBalance_Gob(t *testing.T) {
+	type state struct {
+		Funded  Balance
+		Missing Balance
+		Debt    Balance
+	}
+	large, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	in := state{
+		Funded: NewBalance(large),
+		Debt:   NewBalance(big.NewInt(-42)),
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("gob encode failed: %v", err)
+	}
+	var out state
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("gob decode failed: %v", err)
+	}
+	if !out.Funded.Equal(in.Funded) || !out.Missing.IsZero() || !out.Debt.Equal(in.Debt) {
+		t.Errorf("gob round-trip = %+v, want %+v", out, in)
+	}
+
+	// registered, so a Balance can be sent as interface value
+	var iface any = NewBalance(big.NewInt(-7))
+	buf.Reset()
+	if err := gob.NewEncoder(&buf).Encode(&iface); err != nil {
+		t.Fatalf("gob encode of interface failed: %v", err)
+	}
+	var got any
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("gob decode of interface failed: %v", err)
+	}
+	if b, ok := got.(Balance); !ok || !b.Equal(NewBalance(big.NewInt(-7))) {
+		t.Errorf("gob round-trip of interface = %#v", got)
+	}
+}
@@ -61,6 +61,16 @@ func (m *RWMap[K, V]) Delete(key K) {
 	delete(m.inner, key)
 }
 
+// GetAndDelete removes the value at the given key, and returns the removed value, if any.
+// Of concurrent calls for the same key, only one gets the value.
+func (m *RWMap[K, V]) GetAndDelete(key K) (value V, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok = m.inner[key]
+	delete(m.inner, key)
+	return
+}
+
 // Range calls f sequentially for each key and value present in the map.
 // If f returns false, range stops the iteration.
 func (m *RWMap[K, V]) Range(f func(key K, value V) bool) {
@@ -58,6 +58,15 @@ func TestRWMap(t *testing.T) {
 	m.Set(10, 123)
 	require.True(t, m.Has(10))
 
+	// get and remove a value
+	v, ok = m.GetAndDelete(10)
+	require.True(t, ok)
+	require.Equal(t, int64(123), v)
+	require.False(t, m.Has(10))
+	_, ok = m.GetAndDelete(10)
+	require.False(t, ok, "already removed")
+	m.Set(10, 123)
+
 	// remove a non-existent value
 	m.Delete(132983213)
 
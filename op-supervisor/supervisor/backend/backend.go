@@ -238,19 +238,25 @@ func (su *SupervisorBackend) openChainDBs(chainID eth.ChainID) error {
 	if err != nil {
 		return fmt.Errorf("failed to open logDB of chain %s: %w", chainID, err)
 	}
-	su.chainDBs.AddLogDB(chainID, logDB)
+	if err := su.chainDBs.AddLogDBStrict(chainID, logDB); err != nil {
+		return fmt.Errorf("failed to register logDB of chain %s: %w", chainID, errors.Join(err, logDB.Close()))
+	}
 
 	localDB, err := db.OpenLocalDerivedFromDB(su.logger, chainID, su.dataDir, cm)
 	if err != nil {
 		return fmt.Errorf("failed to open local derived-from DB of chain %s: %w", chainID, err)
 	}
-	su.chainDBs.AddLocalDerivedFromDB(chainID, localDB)
+	if err := su.chainDBs.AddLocalDerivedFromDBStrict(chainID, localDB); err != nil {
+		return fmt.Errorf("failed to register local derived-from DB of chain %s: %w", chainID, errors.Join(err, localDB.Close()))
+	}
 
 	crossDB, err := db.OpenCrossDerivedFromDB(su.logger, chainID, su.dataDir, cm)
 	if err != nil {
 		return fmt.Errorf("failed to open cross derived-from DB of chain %s: %w", chainID, err)
 	}
-	su.chainDBs.AddCrossDerivedFromDB(chainID, crossDB)
+	if err := su.chainDBs.AddCrossDerivedFromDBStrict(chainID, crossDB); err != nil {
+		return fmt.Errorf("failed to register cross derived-from DB of chain %s: %w", chainID, errors.Join(err, crossDB.Close()))
+	}
 
 	su.chainDBs.AddCrossUnsafeTracker(chainID)
 
@@ -498,7 +504,8 @@ func (su *SupervisorBackend) Finalized(ctx context.Context, chainID eth.ChainID)
 }
 
 func (su *SupervisorBackend) FinalizedL1() eth.BlockRef {
-	return su.chainDBs.FinalizedL1()
+	ref, _ := su.chainDBs.FinalizedL1()
+	return ref
 }
 
 func (su *SupervisorBackend) CrossDerivedFrom(ctx context.Context, chainID eth.ChainID, derived eth.BlockID) (derivedFrom eth.BlockRef, err error) {
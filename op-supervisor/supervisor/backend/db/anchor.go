@@ -2,6 +2,7 @@ package db
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
@@ -9,33 +10,42 @@ import (
 
 // maybeInitSafeDB initializes the chain database if it is not already initialized
 // it checks if the Local Safe database is empty, and loads it with the Anchor Point if so
-func (db *ChainsDB) maybeInitSafeDB(id eth.ChainID, anchor types.DerivedBlockRefPair) {
+func (db *ChainsDB) maybeInitSafeDB(id eth.ChainID, anchor types.DerivedBlockRefPair) error {
 	_, err := db.LocalSafe(id)
 	if errors.Is(err, types.ErrFuture) {
 		db.logger.Debug("initializing chain database", "chain", id)
+		// Local-safe is initialized first, since cross-safe cannot advance past it.
+		localErr := db.updateLocalSafe(id, anchor.DerivedFrom, anchor.Derived)
+		var crossErr error
 		if err := db.UpdateCrossSafe(id, anchor.DerivedFrom, anchor.Derived); err != nil {
 			db.logger.Warn("failed to initialize cross safe", "chain", id, "error", err)
+			crossErr = fmt.Errorf("failed to initialize cross safe: %w", err)
 		}
-		db.UpdateLocalSafe(id, anchor.DerivedFrom, anchor.Derived)
+		return errors.Join(localErr, crossErr)
 	} else if err != nil {
 		db.logger.Warn("failed to check if chain database is initialized", "chain", id, "error", err)
+		return fmt.Errorf("failed to check if chain database is initialized: %w", err)
 	} else {
 		db.logger.Debug("chain database already initialized", "chain", id)
 	}
+	return nil
 }
 
-func (db *ChainsDB) maybeInitEventsDB(id eth.ChainID, anchor types.DerivedBlockRefPair) {
+func (db *ChainsDB) maybeInitEventsDB(id eth.ChainID, anchor types.DerivedBlockRefPair) error {
 	_, _, _, err := db.OpenBlock(id, 0)
 	if errors.Is(err, types.ErrFuture) {
 		db.logger.Debug("initializing events database", "chain", id)
 		err := db.SealBlock(id, anchor.Derived)
 		if err != nil {
 			db.logger.Warn("failed to seal initial block", "chain", id, "error", err)
+			return fmt.Errorf("failed to seal initial block: %w", err)
 		}
 		db.logger.Debug("initialized events database", "chain", id)
 	} else if err != nil {
 		db.logger.Warn("failed to check if logDB is initialized", "chain", id, "error", err)
+		return fmt.Errorf("failed to check if logDB is initialized: %w", err)
 	} else {
 		db.logger.Debug("events database already initialized", "chain", id)
 	}
+	return nil
 }
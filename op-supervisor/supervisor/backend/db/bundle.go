@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// InteropMessage is a proposed cross-chain message:
+// a reference to an initiating log on the source chain, to be executed on the target chain.
+type InteropMessage struct {
+	SourceChain eth.ChainID
+	BlockNum    uint64
+	LogIdx      uint32
+	Timestamp   uint64
+	LogHash     common.Hash
+
+	TargetChain eth.ChainID
+}
+
+// MessageVerdict is the outcome of verifying a single InteropMessage.
+type MessageVerdict struct {
+	// Safety is the safety level of the referenced source log.
+	// This is types.Invalid if the log does not match the source chain data.
+	Safety types.SafetyLevel
+	// Valid is true if the source log exists and is at least cross-safe.
+	Valid bool
+	// Err explains why the message is not valid, if available.
+	Err error
+}
+
+// BundleResult is the outcome of verifying a bundle of interop messages.
+type BundleResult struct {
+	// Messages holds the verdict of each message, in the same order as the bundle.
+	Messages []MessageVerdict
+	// Valid is true if every message in the bundle is valid.
+	Valid bool
+}
+
+// VerifyMessageBundle checks that every message in the bundle references an existing source log,
+// and that this log is at least cross-safe.
+// Message-specific failures, such as an unknown chain, a missing log, a conflicting log
+// or a source chain without a cross-safe head,
+// are reported in the verdict of the message, and make the bundle invalid.
+// An error is only returned if the verification itself could not be completed.
+func (db *ChainsDB) VerifyMessageBundle(msgs []InteropMessage) (BundleResult, error) {
+	result := BundleResult{
+		Messages: make([]MessageVerdict, len(msgs)),
+		Valid:    true,
+	}
+	for i, msg := range msgs {
+		verdict, err := db.verifyMessage(msg)
+		if err != nil {
+			return BundleResult{}, fmt.Errorf("failed to verify message %d: %w", i, err)
+		}
+		result.Messages[i] = verdict
+		result.Valid = result.Valid && verdict.Valid
+	}
+	return result, nil
+}
+
+func (db *ChainsDB) verifyMessage(msg InteropMessage) (MessageVerdict, error) {
+	if !db.depSet.HasChain(msg.TargetChain) {
+		return MessageVerdict{Safety: types.Invalid, Err: fmt.Errorf("%w: target %v", types.ErrUnknownChain, msg.TargetChain)}, nil
+	}
+	_, err := db.Check(msg.SourceChain, msg.BlockNum, msg.Timestamp, msg.LogIdx, msg.LogHash)
+	switch {
+	case errors.Is(err, types.ErrFuture):
+		// the log may still appear, but is not known to be any safer than unsafe
+		return MessageVerdict{Safety: types.LocalUnsafe, Err: err}, nil
+	case errors.Is(err, types.ErrConflict), errors.Is(err, types.ErrUnknownChain):
+		return MessageVerdict{Safety: types.Invalid, Err: err}, nil
+	case err != nil:
+		return MessageVerdict{}, err
+	}
+	safety, err := db.Safest(msg.SourceChain, msg.BlockNum, msg.LogIdx)
+	switch {
+	case errors.Is(err, types.ErrFuture), errors.Is(err, types.ErrAwaitReplacementBlock):
+		// the source chain has no usable cross-safe head, so the log is not known to be any safer than unsafe
+		return MessageVerdict{Safety: types.LocalUnsafe, Err: err}, nil
+	case err != nil:
+		return MessageVerdict{}, fmt.Errorf("failed to determine safety of source block %d on chain %v: %w",
+			msg.BlockNum, msg.SourceChain, err)
+	}
+	if !safety.AtLeastAsSafe(types.CrossSafe) {
+		return MessageVerdict{Safety: safety, Err: fmt.Errorf("source log is only %s", safety)}, nil
+	}
+	return MessageVerdict{Safety: safety, Valid: true}, nil
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestVerifyMessageBundle(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	source := eth.ChainIDFromUInt64(900)
+	target := eth.ChainIDFromUInt64(901)
+
+	chainDB.AddLogDB(source, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(source, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(source, newTestDerivedFromDB(t))
+	chainDB.AddCrossUnsafeTracker(source)
+
+	// blocks 1 and 2 each contain a single log
+	logHash := func(num uint64) common.Hash {
+		return crypto.Keccak256Hash([]byte{byte(num)})
+	}
+	require.NoError(t, chainDB.SealBlock(source, testRef("L2", 0)))
+	for i := uint64(1); i <= 2; i++ {
+		require.NoError(t, chainDB.AddLog(source, logHash(i), testRef("L2", i-1).ID(), 0, nil))
+		require.NoError(t, chainDB.SealBlock(source, testRef("L2", i)))
+	}
+	// only block 1 is cross-safe, block 2 is only local-safe
+	for i := uint64(0); i <= 2; i++ {
+		chainDB.UpdateLocalSafe(source, testRef("L1", i), testRef("L2", i))
+	}
+	crossDB, _ := chainDB.crossDBs.Get(source)
+	for i := uint64(0); i <= 1; i++ {
+		require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+
+	msg := func(num uint64) InteropMessage {
+		return InteropMessage{
+			SourceChain: source,
+			BlockNum:    num,
+			LogIdx:      0,
+			Timestamp:   testRef("L2", num).Time,
+			LogHash:     logHash(num),
+			TargetChain: target,
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		result, err := chainDB.VerifyMessageBundle([]InteropMessage{msg(1)})
+		require.NoError(t, err)
+		require.True(t, result.Valid)
+		require.Len(t, result.Messages, 1)
+		require.True(t, result.Messages[0].Valid)
+		require.Equal(t, types.CrossSafe, result.Messages[0].Safety)
+		require.NoError(t, result.Messages[0].Err)
+	})
+	t.Run("unsafe source", func(t *testing.T) {
+		result, err := chainDB.VerifyMessageBundle([]InteropMessage{msg(1), msg(2)})
+		require.NoError(t, err)
+		require.False(t, result.Valid)
+		require.True(t, result.Messages[0].Valid)
+		require.False(t, result.Messages[1].Valid)
+		require.Equal(t, types.LocalSafe, result.Messages[1].Safety)
+		require.Error(t, result.Messages[1].Err)
+	})
+	t.Run("invalid references", func(t *testing.T) {
+		conflicting := msg(1)
+		conflicting.LogHash = logHash(2)
+		unknownSource := msg(1)
+		unknownSource.SourceChain = eth.ChainIDFromUInt64(902)
+		unknownTarget := msg(1)
+		unknownTarget.TargetChain = eth.ChainIDFromUInt64(123)
+		future := msg(3)
+		result, err := chainDB.VerifyMessageBundle([]InteropMessage{conflicting, unknownSource, unknownTarget, future})
+		require.NoError(t, err)
+		require.False(t, result.Valid)
+		require.ErrorIs(t, result.Messages[0].Err, types.ErrConflict)
+		require.Equal(t, types.Invalid, result.Messages[0].Safety)
+		require.ErrorIs(t, result.Messages[1].Err, types.ErrUnknownChain)
+		require.ErrorIs(t, result.Messages[2].Err, types.ErrUnknownChain)
+		require.ErrorIs(t, result.Messages[3].Err, types.ErrFuture)
+		require.Equal(t, types.LocalUnsafe, result.Messages[3].Safety)
+		for _, v := range result.Messages {
+			require.False(t, v.Valid)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		result, err := chainDB.VerifyMessageBundle(nil)
+		require.NoError(t, err)
+		require.True(t, result.Valid)
+		require.Empty(t, result.Messages)
+	})
+}
+
+func TestVerifyMessageBundleNoCrossSafe(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	source := eth.ChainIDFromUInt64(900)
+	target := eth.ChainIDFromUInt64(901)
+
+	chainDB.AddLogDB(source, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(source, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(source, newTestDerivedFromDB(t))
+	chainDB.AddCrossUnsafeTracker(source)
+
+	// block 1 contains a single log, but nothing is cross-safe yet
+	logHash := crypto.Keccak256Hash([]byte{1})
+	require.NoError(t, chainDB.SealBlock(source, testRef("L2", 0)))
+	require.NoError(t, chainDB.AddLog(source, logHash, testRef("L2", 0).ID(), 0, nil))
+	require.NoError(t, chainDB.SealBlock(source, testRef("L2", 1)))
+
+	result, err := chainDB.VerifyMessageBundle([]InteropMessage{{
+		SourceChain: source,
+		BlockNum:    1,
+		LogIdx:      0,
+		Timestamp:   testRef("L2", 1).Time,
+		LogHash:     logHash,
+		TargetChain: target,
+	}})
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Len(t, result.Messages, 1)
+	require.False(t, result.Messages[0].Valid)
+	require.Equal(t, types.LocalUnsafe, result.Messages[0].Safety)
+	require.ErrorIs(t, result.Messages[0].Err, types.ErrFuture)
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// ChainCheckpoint holds the heads of the DBs of a single chain, as captured by Checkpoint.
+// A head is only set if the corresponding Has flag is set: the DB had no data otherwise.
+type ChainCheckpoint struct {
+	HasLogs      bool
+	HasLocalSafe bool
+	HasCrossSafe bool
+
+	// LatestSealed is the latest sealed block of the log DB.
+	LatestSealed eth.BlockID
+	// LocalSafe and CrossSafe are the latest entries of the derived-from DBs.
+	LocalSafe types.DerivedBlockSealPair
+	CrossSafe types.DerivedBlockSealPair
+}
+
+// Checkpoint is a capture of the heads of the DBs of all chains, that can be restored with RestoreTo.
+type Checkpoint struct {
+	Chains map[eth.ChainID]ChainCheckpoint
+}
+
+// Checkpoint captures the heads of the log DB and the local and cross derived-from DBs of every chain.
+// Every chain that has any DB attached must have all three DBs attached.
+// The DBs are read one by one: to capture a consistent view of all chains,
+// the chains should not be written to while the checkpoint is captured.
+// Checkpoints cannot be captured while a DB awaits a replacement block.
+func (db *ChainsDB) Checkpoint() (Checkpoint, error) {
+	cp := Checkpoint{Chains: make(map[eth.ChainID]ChainCheckpoint)}
+	for _, chain := range db.Chains() {
+		logDB, localDB, crossDB, err := db.checkpointDBs(chain)
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		var chainCp ChainCheckpoint
+		chainCp.LatestSealed, chainCp.HasLogs = logDB.LatestSealedBlock()
+		chainCp.LocalSafe, chainCp.HasLocalSafe, err = checkpointHead(localDB)
+		if err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to checkpoint local-safe of chain %s: %w", chain, err)
+		}
+		chainCp.CrossSafe, chainCp.HasCrossSafe, err = checkpointHead(crossDB)
+		if err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to checkpoint cross-safe of chain %s: %w", chain, err)
+		}
+		cp.Chains[chain] = chainCp
+	}
+	return cp, nil
+}
+
+// RestoreTo rewinds the log DB and the local and cross derived-from DBs of every chain of the checkpoint
+// back to the heads of the checkpoint. Cross-unsafe is reset to cross-safe if it is past the restored log DB.
+// Chains that are not part of the checkpoint are not changed.
+//
+// All chains are validated before any chain is rewound: the DBs of every chain must still contain
+// the checkpoint heads, with matching hashes. Restoring forward is refused with ErrFuture,
+// and heads that no longer match return ErrConflict.
+func (db *ChainsDB) RestoreTo(cp Checkpoint) error {
+	chains := make([]eth.ChainID, 0, len(cp.Chains))
+	for chain := range cp.Chains {
+		chains = append(chains, chain)
+	}
+	slices.SortFunc(chains, eth.ChainID.Cmp)
+	for _, chain := range chains {
+		if err := db.validateCheckpoint(chain, cp.Chains[chain]); err != nil {
+			return fmt.Errorf("cannot restore chain %s: %w", chain, err)
+		}
+	}
+	for _, chain := range chains {
+		if err := db.restoreChain(chain, cp.Chains[chain]); err != nil {
+			return fmt.Errorf("failed to restore chain %s: %w", chain, err)
+		}
+	}
+	return nil
+}
+
+func (db *ChainsDB) checkpointDBs(chain eth.ChainID) (LogStorage, LocalDerivedFromStorage, CrossDerivedFromStorage, error) {
+	logDB, ok := db.logDBs.Get(chain)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("logDB not found: %w: %s", types.ErrUnknownChain, chain)
+	}
+	localDB, ok := db.localDBs.Get(chain)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("localDB not found: %w: %s", types.ErrUnknownChain, chain)
+	}
+	crossDB, ok := db.crossDBs.Get(chain)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("crossDB not found: %w: %s", types.ErrUnknownChain, chain)
+	}
+	return logDB, localDB, crossDB, nil
+}
+
+// checkpointHead returns the latest entry of the derived-from DB, and false if the DB is empty.
+func checkpointHead(dfDB LocalDerivedFromStorage) (types.DerivedBlockSealPair, bool, error) {
+	pair, err := dfDB.Latest()
+	if errors.Is(err, types.ErrFuture) {
+		return types.DerivedBlockSealPair{}, false, nil
+	} else if err != nil {
+		return types.DerivedBlockSealPair{}, false, err
+	}
+	return pair, true, nil
+}
+
+func (db *ChainsDB) validateCheckpoint(chain eth.ChainID, cp ChainCheckpoint) error {
+	logDB, localDB, crossDB, err := db.checkpointDBs(chain)
+	if err != nil {
+		return err
+	}
+	if cp.HasLogs {
+		seal, err := logDB.FindSealedBlock(cp.LatestSealed.Number)
+		if errors.Is(err, types.ErrFuture) {
+			return fmt.Errorf("cannot restore logDB forward to %s: %w", cp.LatestSealed, err)
+		} else if err != nil {
+			return fmt.Errorf("failed to find checkpoint block %s in logDB: %w", cp.LatestSealed, err)
+		}
+		if seal.Hash != cp.LatestSealed.Hash {
+			return fmt.Errorf("checkpoint block %s does not match logDB block %s: %w",
+				cp.LatestSealed, seal, types.ErrConflict)
+		}
+	} else if id, ok := logDB.LatestSealedBlock(); ok {
+		return fmt.Errorf("cannot rewind logDB with block %s to empty checkpoint: %w", id, types.ErrConflict)
+	}
+	if err := validateCheckpointHead(localDB, cp.HasLocalSafe, cp.LocalSafe); err != nil {
+		return fmt.Errorf("invalid local-safe checkpoint: %w", err)
+	}
+	if err := validateCheckpointHead(crossDB, cp.HasCrossSafe, cp.CrossSafe); err != nil {
+		return fmt.Errorf("invalid cross-safe checkpoint: %w", err)
+	}
+	return nil
+}
+
+// validateCheckpointHead checks that the derived-from DB can be rewound to the checkpoint head.
+func validateCheckpointHead(dfDB LocalDerivedFromStorage, has bool, head types.DerivedBlockSealPair) error {
+	if !has {
+		if dfDB.Len() > 0 {
+			return fmt.Errorf("cannot rewind DB with %d entries to empty checkpoint: %w", dfDB.Len(), types.ErrConflict)
+		}
+		return nil
+	}
+	ok, err := dfDB.ContainsDerivedPair(head.DerivedFrom.ID(), head.Derived.ID())
+	if errors.Is(err, types.ErrFuture) {
+		return fmt.Errorf("cannot restore forward to %s: %w", head, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to find checkpoint head %s: %w", head, err)
+	}
+	if !ok {
+		return fmt.Errorf("checkpoint head %s is not in DB: %w", head, types.ErrConflict)
+	}
+	return nil
+}
+
+func (db *ChainsDB) restoreChain(chain eth.ChainID, cp ChainCheckpoint) error {
+	logDB, localDB, crossDB, err := db.checkpointDBs(chain)
+	if err != nil {
+		return err
+	}
+	if cp.HasLocalSafe {
+		if err := localDB.Rewind(cp.LocalSafe, false); err != nil {
+			return fmt.Errorf("failed to rewind localDB to %s: %w", cp.LocalSafe, err)
+		}
+		db.markModified(chain)
+	}
+	if cp.HasCrossSafe {
+		if err := crossDB.Rewind(cp.CrossSafe, false); err != nil {
+			return fmt.Errorf("failed to rewind crossDB to %s: %w", cp.CrossSafe, err)
+		}
+		db.markModified(chain)
+	}
+	if cp.HasLogs {
+		if err := logDB.Rewind(cp.LatestSealed); err != nil {
+			return fmt.Errorf("failed to rewind logDB to %s: %w", cp.LatestSealed, err)
+		}
+		db.markModified(chain)
+		if cp.HasCrossSafe {
+			if err := db.ResetCrossUnsafeIfNewerThan(chain, cp.LatestSealed.Number+1); err != nil {
+				return fmt.Errorf("failed to reset cross-unsafe: %w", err)
+			}
+		}
+	}
+	return nil
+}
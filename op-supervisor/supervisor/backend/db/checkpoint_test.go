@@ -0,0 +1,158 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestCheckpoint(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chains := []eth.ChainID{
+		eth.ChainIDFromUInt64(900),
+		eth.ChainIDFromUInt64(901),
+		eth.ChainIDFromUInt64(902),
+	}
+	for _, chain := range chains {
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+		chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossUnsafeTracker(chain)
+	}
+	// advance seals L2 blocks up to the given number on every chain,
+	// with one L2 block derived from each L1 block, and cross-safe trailing local-safe by one block.
+	next := uint64(0)
+	advance := func(to uint64) {
+		for ; next <= to; next++ {
+			for _, chain := range chains {
+				require.NoError(t, chainDB.SealBlock(chain, testRef("L2", next)))
+				chainDB.UpdateLocalSafe(chain, testRef("L1", next), testRef("L2", next))
+				if next > 0 {
+					require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", next), testRef("L2", next-1)))
+				}
+				require.NoError(t, chainDB.UpdateCrossUnsafe(chain, types.BlockSealFromRef(testRef("L2", next))))
+			}
+		}
+	}
+	requireHeads := func(num uint64) {
+		for _, chain := range chains {
+			unsafe, err := chainDB.LocalUnsafe(chain)
+			require.NoError(t, err)
+			require.Equal(t, testRef("L2", num).ID(), unsafe.ID())
+			localSafe, err := chainDB.LocalSafe(chain)
+			require.NoError(t, err)
+			require.Equal(t, testRef("L1", num).ID(), localSafe.DerivedFrom.ID())
+			require.Equal(t, testRef("L2", num).ID(), localSafe.Derived.ID())
+			crossSafe, err := chainDB.CrossSafe(chain)
+			require.NoError(t, err)
+			require.Equal(t, testRef("L1", num).ID(), crossSafe.DerivedFrom.ID())
+			require.Equal(t, testRef("L2", num-1).ID(), crossSafe.Derived.ID())
+			crossUnsafe, err := chainDB.CrossUnsafe(chain)
+			require.NoError(t, err)
+			require.LessOrEqual(t, crossUnsafe.Number, num)
+		}
+	}
+
+	advance(3)
+	early, err := chainDB.Checkpoint()
+	require.NoError(t, err)
+	require.Len(t, early.Chains, len(chains))
+	for _, chain := range chains {
+		cp := early.Chains[chain]
+		require.True(t, cp.HasLogs)
+		require.True(t, cp.HasLocalSafe)
+		require.True(t, cp.HasCrossSafe)
+		require.Equal(t, testRef("L2", 3).ID(), cp.LatestSealed)
+	}
+	advance(5)
+	late, err := chainDB.Checkpoint()
+	require.NoError(t, err)
+	advance(8)
+	requireHeads(8)
+
+	require.NoError(t, chainDB.RestoreTo(late))
+	requireHeads(5)
+	require.NoError(t, chainDB.RestoreTo(early))
+	requireHeads(3)
+	// restoring to the current heads is a no-op
+	require.NoError(t, chainDB.RestoreTo(early))
+	requireHeads(3)
+
+	t.Run("forward", func(t *testing.T) {
+		err := chainDB.RestoreTo(late)
+		require.ErrorIs(t, err, types.ErrFuture)
+		requireHeads(3)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		for _, mutate := range []func(cp *ChainCheckpoint){
+			func(cp *ChainCheckpoint) { cp.LatestSealed.Hash = common.Hash{0xaa} },
+			func(cp *ChainCheckpoint) { cp.LocalSafe.Derived.Hash = common.Hash{0xaa} },
+			func(cp *ChainCheckpoint) { cp.CrossSafe.DerivedFrom.Hash = common.Hash{0xaa} },
+		} {
+			cp := Checkpoint{Chains: map[eth.ChainID]ChainCheckpoint{}}
+			for chain, chainCp := range early.Chains {
+				cp.Chains[chain] = chainCp
+			}
+			// the last chain is validated last, and must stop the first chains from being rewound too
+			last := chains[len(chains)-1]
+			chainCp := cp.Chains[last]
+			chainCp.LatestSealed = testRef("L2", 1).ID()
+			chainCp.LocalSafe = types.DerivedBlockSealPair{
+				DerivedFrom: types.BlockSealFromRef(testRef("L1", 1)),
+				Derived:     types.BlockSealFromRef(testRef("L2", 1)),
+			}
+			chainCp.CrossSafe = types.DerivedBlockSealPair{
+				DerivedFrom: types.BlockSealFromRef(testRef("L1", 1)),
+				Derived:     types.BlockSealFromRef(testRef("L2", 0)),
+			}
+			mutate(&chainCp)
+			cp.Chains[last] = chainCp
+			err := chainDB.RestoreTo(cp)
+			require.ErrorIs(t, err, types.ErrConflict)
+			requireHeads(3)
+		}
+	})
+
+	t.Run("unknown chain", func(t *testing.T) {
+		cp := Checkpoint{Chains: map[eth.ChainID]ChainCheckpoint{
+			eth.ChainIDFromUInt64(1234): {},
+		}}
+		require.ErrorIs(t, chainDB.RestoreTo(cp), types.ErrUnknownChain)
+	})
+}
+
+func TestCheckpointEmpty(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+
+	cp, err := chainDB.Checkpoint()
+	require.NoError(t, err)
+	require.Equal(t, ChainCheckpoint{}, cp.Chains[chain])
+	require.NoError(t, chainDB.RestoreTo(cp))
+
+	// data cannot be dropped entirely
+	require.NoError(t, chainDB.SealBlock(chain, testRef("L2", 0)))
+	require.ErrorIs(t, chainDB.RestoreTo(cp), types.ErrConflict)
+
+	// all DBs of a chain are required
+	other := eth.ChainIDFromUInt64(901)
+	chainDB.AddLogDB(other, newTestLogDB(t))
+	_, err = chainDB.Checkpoint()
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+}
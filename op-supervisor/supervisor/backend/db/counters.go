@@ -0,0 +1,85 @@
+package db
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/locks"
+)
+
+// chainCounters tracks cheap activity counters of a chain.
+// These are updated incrementally in the write paths,
+// so they can be read without traversing or locking the underlying DBs.
+type chainCounters struct {
+	logs             atomic.Uint64
+	localSafeUpdates atomic.Uint64
+	// lastUpdate is the time of the last counted write, in unix nanoseconds
+	lastUpdate atomic.Int64
+	// lastModified is the time of the last write of any kind, in unix nanoseconds
+	lastModified atomic.Int64
+}
+
+func (c *chainCounters) touch(now time.Time) {
+	c.lastUpdate.Store(now.UnixNano())
+}
+
+// ChainCounters is a point-in-time copy of the activity counters of a chain.
+type ChainCounters struct {
+	// Logs is the number of logs added to the chain since startup
+	Logs uint64
+	// LocalSafeUpdates is the number of local-safe updates of the chain since startup
+	LocalSafeUpdates uint64
+	// LastUpdate is the time of the last counted write, or zero if there was none
+	LastUpdate time.Time
+}
+
+// counters returns the counters of the given chain, creating them if necessary.
+func (db *ChainsDB) counters(chain eth.ChainID) *chainCounters {
+	c, ok := db.chainCounters.Get(chain)
+	if !ok {
+		locks.InitPtrMaybe(&db.chainCounters, chain)
+		c, _ = db.chainCounters.Get(chain)
+	}
+	return c
+}
+
+// CountersSnapshot returns the activity counters of each chain that has seen any writes.
+// Unlike queries of the DBs themselves, this does not contend with ingestion,
+// making it suitable for high-frequency polling.
+func (db *ChainsDB) CountersSnapshot() map[eth.ChainID]ChainCounters {
+	out := make(map[eth.ChainID]ChainCounters)
+	db.chainCounters.Range(func(chain eth.ChainID, c *chainCounters) bool {
+		var lastUpdate time.Time
+		if t := c.lastUpdate.Load(); t != 0 {
+			lastUpdate = time.Unix(0, t)
+		}
+		out[chain] = ChainCounters{
+			Logs:             c.logs.Load(),
+			LocalSafeUpdates: c.localSafeUpdates.Load(),
+			LastUpdate:       lastUpdate,
+		}
+		return true
+	})
+	return out
+}
+
+// markModified records that one of the DBs of the chain was written to.
+func (db *ChainsDB) markModified(chain eth.ChainID) {
+	db.counters(chain).lastModified.Store(db.clock.Now().UnixNano())
+}
+
+// LastModified returns the wall-clock time of the most recent write to any of the DBs of the chain,
+// as seen by the clock of the ChainsDB. This is the time of ingestion, not the timestamp of the chain data.
+// False is returned if the chain has not been written to since startup.
+func (db *ChainsDB) LastModified(chainID eth.ChainID) (time.Time, bool) {
+	c, ok := db.chainCounters.Get(chainID)
+	if !ok {
+		return time.Time{}, false
+	}
+	t := c.lastModified.Load()
+	if t == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, t), true
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/clock"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/logs"
+)
+
+type stubLogsMetrics struct{}
+
+func (stubLogsMetrics) RecordDBEntryCount(kind string, count int64) {}
+func (stubLogsMetrics) RecordDBSearchEntriesRead(count int64)       {}
+
+// newTestLogDB creates an in-memory log DB, to back unsafe data in tests.
+func newTestLogDB(t testing.TB) *logs.DB {
+	logger := testlog.Logger(t, log.LevelInfo)
+	store := &entrydb.MemEntryStore[logs.EntryType, logs.Entry]{}
+	logDB, err := logs.NewFromEntryStore(logger, stubLogsMetrics{}, store, false)
+	require.NoError(t, err)
+	return logDB
+}
+
+func TestCountersSnapshot(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainDB.AddLogDB(chainA, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	chainDB.AddLocalDerivedFromDB(chainB, newTestDerivedFromDB(t))
+
+	require.Empty(t, chainDB.CountersSnapshot())
+
+	start := time.Now()
+	genesis := testRef("L2", 0)
+	require.NoError(t, chainDB.SealBlock(chainA, genesis))
+	for i := uint32(0); i < 3; i++ {
+		require.NoError(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte{byte(i)}), genesis.ID(), i, nil))
+	}
+	// failed writes are not counted
+	require.Error(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte{0xff}), genesis.ID(), 10, nil))
+	require.Error(t, chainDB.AddLog(chainB, crypto.Keccak256Hash([]byte{0xff}), genesis.ID(), 0, nil))
+
+	chainDB.UpdateLocalSafe(chainA, testRef("L1", 0), testRef("L2", 0))
+	chainDB.UpdateLocalSafe(chainB, testRef("L1", 0), testRef("L2", 0))
+	chainDB.UpdateLocalSafe(chainB, testRef("L1", 1), testRef("L2", 1))
+	chainDB.UpdateLocalSafe(chainB, testRef("L1", 5), testRef("L2", 5)) // out of order, fails
+
+	snapshot := chainDB.CountersSnapshot()
+	require.Len(t, snapshot, 2)
+	require.Equal(t, uint64(3), snapshot[chainA].Logs)
+	require.Equal(t, uint64(1), snapshot[chainA].LocalSafeUpdates)
+	require.Zero(t, snapshot[chainB].Logs)
+	require.Equal(t, uint64(2), snapshot[chainB].LocalSafeUpdates)
+	for _, c := range snapshot {
+		require.False(t, c.LastUpdate.Before(start))
+		require.False(t, c.LastUpdate.After(time.Now()))
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	clk := clock.NewDeterministicClock(time.Unix(1000, 0))
+	chainDB.AttachClock(clk)
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainDB.AddLogDB(chainA, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chainA, newTestDerivedFromDB(t))
+
+	_, ok := chainDB.LastModified(chainA)
+	require.False(t, ok)
+
+	genesis := testRef("L2", 0)
+	require.NoError(t, chainDB.SealBlock(chainA, genesis))
+	modified, ok := chainDB.LastModified(chainA)
+	require.True(t, ok)
+	require.Equal(t, clk.Now(), modified)
+
+	clk.AdvanceTime(time.Second)
+	require.NoError(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte{0}), genesis.ID(), 0, nil))
+	modified, ok = chainDB.LastModified(chainA)
+	require.True(t, ok)
+	require.Equal(t, clk.Now(), modified)
+
+	clk.AdvanceTime(time.Second)
+	chainDB.UpdateLocalSafe(chainA, testRef("L1", 0), genesis)
+	modified, ok = chainDB.LastModified(chainA)
+	require.True(t, ok)
+	require.Equal(t, clk.Now(), modified)
+	lastWrite := clk.Now()
+
+	// reads and failed writes do not count as modifications
+	clk.AdvanceTime(time.Second)
+	_, err := chainDB.LocalSafe(chainA)
+	require.NoError(t, err)
+	_, err = chainDB.LocalUnsafe(chainA)
+	require.NoError(t, err)
+	_, _, _, err = chainDB.OpenBlock(chainA, 0)
+	require.NoError(t, err)
+	require.Error(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte{1}), genesis.ID(), 5, nil))
+	chainDB.UpdateLocalSafe(chainA, testRef("L1", 5), testRef("L2", 5))
+	modified, ok = chainDB.LastModified(chainA)
+	require.True(t, ok)
+	require.Equal(t, lastWrite, modified)
+
+	// other chains are tracked separately
+	_, ok = chainDB.LastModified(chainB)
+	require.False(t, ok)
+}
+
+func BenchmarkAddLog(b *testing.B) {
+	chainDB := NewChainsDB(testlog.Logger(b, log.LevelInfo), nil)
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(b))
+	parent := testRef("L2", 0)
+	require.NoError(b, chainDB.SealBlock(chain, parent))
+	logHash := crypto.Keccak256Hash([]byte("log"))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := chainDB.AddLog(chain, logHash, parent.ID(), uint32(i), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCountersSnapshot(b *testing.B) {
+	chainDB := NewChainsDB(testlog.Logger(b, log.LevelInfo), nil)
+	for i := uint64(0); i < 10; i++ {
+		chainDB.counters(eth.ChainIDFromUInt64(900 + i)).logs.Add(1)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = chainDB.CountersSnapshot()
+	}
+}
@@ -1,14 +1,18 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/clock"
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-service/locks"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/fromda"
@@ -28,6 +32,10 @@ type LogStorage interface {
 
 	Rewind(newHead eth.BlockID) error
 
+	// DeleteLogsAfter drops the logs after logIdx, of the block that is being built on top of the given block.
+	// The given block must be the latest sealed block.
+	DeleteLogsAfter(block eth.BlockID, logIdx uint32) error
+
 	LatestSealedBlock() (id eth.BlockID, ok bool)
 
 	// FindSealedBlock finds the requested block by number, to check if it exists,
@@ -37,6 +45,9 @@ type LogStorage interface {
 
 	IteratorStartingAt(sealedNum uint64, logsSince uint32) (logs.Iterator, error)
 
+	// ReverseIteratorEndingAt iterates over the logs before the given position, from later to earlier logs.
+	ReverseIteratorEndingAt(sealedNum uint64, logsUntil uint32) (logs.ReverseIterator, error)
+
 	// Contains returns no error iff the specified logHash is recorded in the specified blockNum and logIdx.
 	// If the log is out of reach, then ErrFuture is returned.
 	// If the log is determined to conflict with the canonical chain, then ErrConflict is returned.
@@ -46,11 +57,26 @@ type LogStorage interface {
 	// This seal may be fully zeroed, without error, if the block isn't fully known yet.
 	Contains(blockNum uint64, logIdx uint32, logHash common.Hash) (includedIn types.BlockSeal, err error)
 
+	// GetLog returns the hash and executing message (if any) of a single log, without reading the rest of the block.
+	GetLog(blockNum uint64, logIdx uint32) (logHash common.Hash, execMsg *types.ExecutingMessage, err error)
+
+	// CountLogs returns the number of logs of the given sealed block, without collecting its executing messages.
+	CountLogs(blockNum uint64) (uint32, error)
+
+	// FindLogByHash returns every position of a sealed block where a log with the given hash was recorded.
+	// This scans the full DB.
+	FindLogByHash(logHash common.Hash) ([]types.LogPosition, error)
+
 	// OpenBlock accumulates the ExecutingMessage events for a block and returns them
 	OpenBlock(blockNum uint64) (ref eth.BlockRef, logCount uint32, execMsgs map[uint32]*types.ExecutingMessage, err error)
 }
 
+// LocalDerivedFromStorage is the storage of L2 blocks and the L1 blocks they were derived from.
+// All lookups return block seals and pairs by value: callers may freely modify results,
+// without affecting the stored data or the results of subsequent lookups.
 type LocalDerivedFromStorage interface {
+	io.Closer
+
 	First() (pair types.DerivedBlockSealPair, err error)
 	Latest() (pair types.DerivedBlockSealPair, err error)
 	Invalidated() (pair types.DerivedBlockSealPair, err error)
@@ -66,15 +92,30 @@ type LocalDerivedFromStorage interface {
 	PreviousDerivedFrom(derivedFrom eth.BlockID) (prevDerivedFrom types.BlockSeal, err error)
 	PreviousDerived(derived eth.BlockID) (prevDerived types.BlockSeal, err error)
 	RewindToL2(derived uint64) error
+	RewindToL1(derivedFrom uint64) error
+	// NearestDerivedFrom returns the last pair derived from an L1 block at or below the given L1 number.
+	NearestDerivedFrom(l1Number uint64) (pair types.DerivedBlockSealPair, err error)
+	// Rewind drops the entries after the given pair, and the pair itself if including is set.
+	Rewind(target types.DerivedBlockSealPair, including bool) error
+	// FirstDerivedWhere returns the first pair of the first L2 block that matches the monotonic condition.
+	FirstDerivedWhere(match func(derived types.BlockSeal) bool) (pair types.DerivedBlockSealPair, err error)
+	// ContainsDerivedPair checks if the given L2 block was derived from the given L1 block, by both number and hash.
+	ContainsDerivedPair(derivedFrom, derived eth.BlockID) (bool, error)
+	// Len returns the number of entries.
+	Len() int
 }
 
 var _ LocalDerivedFromStorage = (*fromda.DB)(nil)
 
 type CrossDerivedFromStorage interface {
 	LocalDerivedFromStorage
-	// This will start to differ with reorg support
+	// CrossRewind drops the invalidated block, and everything after it, from the cross-safe data,
+	// and reports whether anything was dropped.
+	CrossRewind(invalidated types.DerivedBlockRefPair) (rewound bool, err error)
 }
 
+var _ CrossDerivedFromStorage = (*fromda.DB)(nil)
+
 var _ LogStorage = (*logs.DB)(nil)
 
 // ChainsDB is a database that stores logs and derived-from data for multiple chains.
@@ -106,6 +147,16 @@ type ChainsDB struct {
 
 	// emitter used to signal when the DB changes, for other modules to react to
 	emitter event.Emitter
+
+	// chainCounters tracks activity per chain, for cheap monitoring
+	chainCounters locks.RWMap[eth.ChainID, *chainCounters]
+
+	// clock provides the wall-clock time of writes
+	clock clock.Clock
+
+	// onEventErr, if not nil, is called with the events that failed to be handled.
+	// If nil, handler failures are only logged.
+	onEventErr func(ev event.Event, err error)
 }
 
 var _ event.AttachEmitter = (*ChainsDB)(nil)
@@ -114,6 +165,7 @@ func NewChainsDB(l log.Logger, depSet depset.DependencySet) *ChainsDB {
 	return &ChainsDB{
 		logger: l,
 		depSet: depSet,
+		clock:  clock.SystemClock,
 	}
 }
 
@@ -121,23 +173,63 @@ func (db *ChainsDB) AttachEmitter(em event.Emitter) {
 	db.emitter = em
 }
 
+// AttachEventErrorHandler attaches a callback, to surface the errors of handling events in OnEvent.
+// By default, such errors are only logged. A nil callback restores the default.
+func (db *ChainsDB) AttachEventErrorHandler(fn func(ev event.Event, err error)) {
+	db.onEventErr = fn
+}
+
+// AttachClock replaces the clock that is used to timestamp writes. This defaults to the system clock.
+func (db *ChainsDB) AttachClock(clk clock.Clock) {
+	db.clock = clk
+}
+
+// handledEvents lists the events that ChainsDB reacts to in OnEvent.
+// This must be kept in sync with the OnEvent switch.
+var handledEvents = []event.Event{
+	superevents.AnchorEvent{},
+	superevents.LocalDerivedEvent{},
+	superevents.FinalizedL1RequestEvent{},
+	superevents.ReplaceBlockEvent{},
+}
+
+// HandledEventTypes returns the names of the event types that ChainsDB reacts to,
+// for embedders to know what ChainsDB consumes from the event bus.
+func (db *ChainsDB) HandledEventTypes() []string {
+	out := make([]string, 0, len(handledEvents))
+	for _, ev := range handledEvents {
+		out = append(out, ev.String())
+	}
+	return out
+}
+
+// OnEvent handles the events that update the DBs.
+// Failures of the handlers are logged, and passed to the event error handler, if one is attached.
+// An event that the DB handles is reported as handled, even if handling it failed.
 func (db *ChainsDB) OnEvent(ev event.Event) bool {
+	var err error
 	switch x := ev.(type) {
 	case superevents.AnchorEvent:
-		db.maybeInitEventsDB(x.ChainID, x.Anchor)
-		db.maybeInitSafeDB(x.ChainID, x.Anchor)
+		err = errors.Join(
+			db.maybeInitEventsDB(x.ChainID, x.Anchor),
+			db.maybeInitSafeDB(x.ChainID, x.Anchor))
 	case superevents.LocalDerivedEvent:
-		db.UpdateLocalSafe(x.ChainID, x.Derived.DerivedFrom, x.Derived.Derived)
+		err = db.updateLocalSafe(x.ChainID, x.Derived.DerivedFrom, x.Derived.Derived)
 	case superevents.FinalizedL1RequestEvent:
-		db.onFinalizedL1(x.FinalizedL1)
+		err = db.onFinalizedL1(x.FinalizedL1)
 	case superevents.ReplaceBlockEvent:
-		db.onReplaceBlock(x.ChainID, x.Replacement.Replacement, x.Replacement.Invalidated)
+		err = db.onReplaceBlock(x.ChainID, x.Replacement.Replacement, x.Replacement.Invalidated)
 	default:
 		return false
 	}
+	if err != nil && db.onEventErr != nil {
+		db.onEventErr(ev, err)
+	}
 	return true
 }
 
+// AddLogDB registers the log DB of the chain, overwriting any existing one with a warning.
+// Prefer AddLogDBStrict, which refuses to overwrite, to surface double registrations.
 func (db *ChainsDB) AddLogDB(chainID eth.ChainID, logDB LogStorage) {
 	if db.logDBs.Has(chainID) {
 		db.logger.Warn("overwriting existing log DB for chain", "chain", chainID)
@@ -146,6 +238,8 @@ func (db *ChainsDB) AddLogDB(chainID eth.ChainID, logDB LogStorage) {
 	db.logDBs.Set(chainID, logDB)
 }
 
+// AddLocalDerivedFromDB registers the local derived-from DB of the chain, overwriting any existing one with a warning.
+// Prefer AddLocalDerivedFromDBStrict, which refuses to overwrite.
 func (db *ChainsDB) AddLocalDerivedFromDB(chainID eth.ChainID, dfDB LocalDerivedFromStorage) {
 	if db.localDBs.Has(chainID) {
 		db.logger.Warn("overwriting existing local derived-from DB for chain", "chain", chainID)
@@ -154,6 +248,8 @@ func (db *ChainsDB) AddLocalDerivedFromDB(chainID eth.ChainID, dfDB LocalDerived
 	db.localDBs.Set(chainID, dfDB)
 }
 
+// AddCrossDerivedFromDB registers the cross derived-from DB of the chain, overwriting any existing one with a warning.
+// Prefer AddCrossDerivedFromDBStrict, which refuses to overwrite.
 func (db *ChainsDB) AddCrossDerivedFromDB(chainID eth.ChainID, dfDB CrossDerivedFromStorage) {
 	if db.crossDBs.Has(chainID) {
 		db.logger.Warn("overwriting existing cross derived-from DB for chain", "chain", chainID)
@@ -162,6 +258,33 @@ func (db *ChainsDB) AddCrossDerivedFromDB(chainID eth.ChainID, dfDB CrossDerived
 	db.crossDBs.Set(chainID, dfDB)
 }
 
+// AddLogDBStrict registers the log DB of the chain,
+// and returns an ErrConflict error, without overwriting, if the chain already has a log DB.
+func (db *ChainsDB) AddLogDBStrict(chainID eth.ChainID, logDB LogStorage) error {
+	if !db.logDBs.Default(chainID, func() LogStorage { return logDB }) {
+		return fmt.Errorf("log DB of chain %s is already registered: %w", chainID, types.ErrConflict)
+	}
+	return nil
+}
+
+// AddLocalDerivedFromDBStrict registers the local derived-from DB of the chain,
+// and returns an ErrConflict error, without overwriting, if the chain already has a local derived-from DB.
+func (db *ChainsDB) AddLocalDerivedFromDBStrict(chainID eth.ChainID, dfDB LocalDerivedFromStorage) error {
+	if !db.localDBs.Default(chainID, func() LocalDerivedFromStorage { return dfDB }) {
+		return fmt.Errorf("local derived-from DB of chain %s is already registered: %w", chainID, types.ErrConflict)
+	}
+	return nil
+}
+
+// AddCrossDerivedFromDBStrict registers the cross derived-from DB of the chain,
+// and returns an ErrConflict error, without overwriting, if the chain already has a cross derived-from DB.
+func (db *ChainsDB) AddCrossDerivedFromDBStrict(chainID eth.ChainID, dfDB CrossDerivedFromStorage) error {
+	if !db.crossDBs.Default(chainID, func() CrossDerivedFromStorage { return dfDB }) {
+		return fmt.Errorf("cross derived-from DB of chain %s is already registered: %w", chainID, types.ErrConflict)
+	}
+	return nil
+}
+
 func (db *ChainsDB) AddCrossUnsafeTracker(chainID eth.ChainID) {
 	if db.crossUnsafe.Has(chainID) {
 		db.logger.Warn("overwriting existing cross-unsafe tracker for chain", "chain", chainID)
@@ -169,6 +292,47 @@ func (db *ChainsDB) AddCrossUnsafeTracker(chainID eth.ChainID) {
 	db.crossUnsafe.Set(chainID, &locks.RWValue[types.BlockSeal]{})
 }
 
+// LogDB returns the log DB of the chain, or false if the chain has none.
+// The returned handle is live, not a copy: it is shared with the ChainsDB,
+// and relies on its own internal locking for concurrent use.
+func (db *ChainsDB) LogDB(chainID eth.ChainID) (LogStorage, bool) {
+	return db.logDBs.Get(chainID)
+}
+
+// LocalDB returns the local derived-from DB of the chain, or false if the chain has none.
+// Like LogDB, the returned handle is live.
+func (db *ChainsDB) LocalDB(chainID eth.ChainID) (LocalDerivedFromStorage, bool) {
+	return db.localDBs.Get(chainID)
+}
+
+// CrossDB returns the cross derived-from DB of the chain, or false if the chain has none.
+// Like LogDB, the returned handle is live.
+func (db *ChainsDB) CrossDB(chainID eth.ChainID) (CrossDerivedFromStorage, bool) {
+	return db.crossDBs.Get(chainID)
+}
+
+// ChainsWithoutCrossDB returns the chains that have a log DB or local derived-from DB,
+// but no cross derived-from DB, sorted by chain ID.
+func (db *ChainsDB) ChainsWithoutCrossDB() []eth.ChainID {
+	missing := make(map[eth.ChainID]struct{})
+	db.logDBs.Range(func(chain eth.ChainID, _ LogStorage) bool {
+		missing[chain] = struct{}{}
+		return true
+	})
+	db.localDBs.Range(func(chain eth.ChainID, _ LocalDerivedFromStorage) bool {
+		missing[chain] = struct{}{}
+		return true
+	})
+	out := make([]eth.ChainID, 0, len(missing))
+	for chain := range missing {
+		if !db.crossDBs.Has(chain) {
+			out = append(out, chain)
+		}
+	}
+	slices.SortFunc(out, eth.ChainID.Cmp)
+	return out
+}
+
 // ResumeFromLastSealedBlock prepares the chains db to resume recording events after a restart.
 // It rewinds the database to the last block that is guaranteed to have been fully recorded to the database,
 // to ensure it can resume recording from the first log of the next block.
@@ -195,13 +359,102 @@ func (db *ChainsDB) DependencySet() depset.DependencySet {
 	return db.depSet
 }
 
+// closeWorkers is the maximum number of log DBs that are closed concurrently.
+const closeWorkers = 8
+
 func (db *ChainsDB) Close() error {
-	var combined error
+	return db.CloseCtx(context.Background())
+}
+
+// CloseCtx closes the log DBs of all chains, concurrently, and joins the errors of all chains.
+// The log DBs are collected first, so closing does not happen while iterating over the chains.
+// If the context is cancelled, no more log DBs are closed, and the context error is included in the result.
+// A close that is in progress is not interrupted.
+func (db *ChainsDB) CloseCtx(ctx context.Context) error {
+	type chainLogDB struct {
+		id    eth.ChainID
+		logDB LogStorage
+	}
+	var toClose []chainLogDB
 	db.logDBs.Range(func(id eth.ChainID, logDB LogStorage) bool {
-		if err := logDB.Close(); err != nil {
-			combined = errors.Join(combined, fmt.Errorf("failed to close log db for chain %v: %w", id, err))
-		}
+		toClose = append(toClose, chainLogDB{id: id, logDB: logDB})
 		return true
 	})
+
+	var (
+		mu       sync.Mutex
+		combined error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, closeWorkers)
+	for _, c := range toClose {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			combined = errors.Join(combined, fmt.Errorf("interrupted closing log dbs: %w", err))
+			mu.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func(c chainLogDB) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := c.logDB.Close(); err != nil {
+				mu.Lock()
+				combined = errors.Join(combined, fmt.Errorf("failed to close log db for chain %v: %w", c.id, err))
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
 	return combined
 }
+
+// RemoveChain detaches a chain at runtime: its log, local-safe and cross-safe DBs are closed,
+// and its log, local-safe, cross-safe and cross-unsafe data is removed from the ChainsDB.
+// The chain is removed from the maps before the DBs are closed, so queries and events of the chain
+// that start after the removal fail with ErrUnknownChain.
+// Operations that already retrieved a DB of the chain are not waited for, and may fail once the DB is closed:
+// the processing of the chain should be stopped before removing it.
+// Each DB is taken out of its map atomically, and closed only by the call that took it,
+// so concurrent removals of the same chain never close a DB twice.
+// Chains of the dependency set may be removed too, e.g. when reconfiguring the supervisor,
+// but cross-chain checks involving the chain will fail until it is added back.
+// It returns ErrUnknownChain if none of the data of the chain is present.
+func (db *ChainsDB) RemoveChain(chainID eth.ChainID) error {
+	logDB, hasLogs := db.logDBs.GetAndDelete(chainID)
+	localDB, hasLocal := db.localDBs.GetAndDelete(chainID)
+	crossDB, hasCross := db.crossDBs.GetAndDelete(chainID)
+	_, hasCrossUnsafe := db.crossUnsafe.GetAndDelete(chainID)
+	db.chainCounters.Delete(chainID)
+	if !hasLogs && !hasLocal && !hasCross && !hasCrossUnsafe {
+		return fmt.Errorf("cannot remove chain %s: %w", chainID, types.ErrUnknownChain)
+	}
+	if db.depSet != nil && db.depSet.HasChain(chainID) {
+		db.logger.Warn("Removing chain that is still in the dependency set", "chain", chainID)
+	}
+	var result error
+	if hasLogs {
+		if err := logDB.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close log db for chain %v: %w", chainID, err))
+		}
+	}
+	if hasLocal {
+		if err := localDB.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close local-safe db for chain %v: %w", chainID, err))
+		}
+	}
+	if hasCross {
+		if err := crossDB.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close cross-safe db for chain %v: %w", chainID, err))
+		}
+	}
+	if result != nil {
+		return result
+	}
+	db.logger.Info("Removed chain", "chain", chainID)
+	return nil
+}
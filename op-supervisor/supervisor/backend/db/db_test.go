@@ -0,0 +1,431 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/superevents"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// TestHandledEventTypes checks that the handled events match the cases of the OnEvent type-switch.
+func TestHandledEventTypes(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "db.go", nil, 0)
+	require.NoError(t, err)
+
+	var cases []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "OnEvent" || fn.Recv == nil {
+			return true
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			clause, ok := n.(*ast.CaseClause)
+			if !ok {
+				return true
+			}
+			for _, expr := range clause.List {
+				sel, ok := expr.(*ast.SelectorExpr)
+				require.True(t, ok, "expected event type from another package")
+				cases = append(cases, fmt.Sprintf("%s.%s", sel.X, sel.Sel))
+			}
+			return false
+		})
+		return false
+	})
+	require.NotEmpty(t, cases, "expected to find the OnEvent switch cases")
+
+	var handled []string
+	for _, ev := range handledEvents {
+		handled = append(handled, fmt.Sprintf("%T", ev))
+	}
+	require.ElementsMatch(t, cases, handled, "OnEvent switch and handledEvents must match")
+
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	names := chainsDB.HandledEventTypes()
+	require.Len(t, names, len(handledEvents))
+	require.Contains(t, names, superevents.AnchorEvent{}.String())
+	require.NotContains(t, names, superevents.LocalUnsafeReceivedEvent{}.String())
+}
+
+func TestOnEventErrorHandler(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainsDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainsDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+
+	derived := func(num uint64) superevents.LocalDerivedEvent {
+		return superevents.LocalDerivedEvent{
+			ChainID: chain,
+			Derived: types.DerivedBlockRefPair{DerivedFrom: testRef("L1", num), Derived: testRef("L2", num)},
+		}
+	}
+	// without a handler, errors are swallowed, and the event is still handled
+	require.True(t, chainsDB.OnEvent(derived(0)))
+	require.True(t, chainsDB.OnEvent(derived(5)))
+
+	type failure struct {
+		ev  event.Event
+		err error
+	}
+	var failures []failure
+	chainsDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		failures = append(failures, failure{ev: ev, err: err})
+	})
+
+	require.True(t, chainsDB.OnEvent(derived(1)))
+	require.Empty(t, failures, "successful handling is not reported")
+
+	// a gap in the derived chain is rejected by the local-safe DB
+	require.True(t, chainsDB.OnEvent(derived(5)))
+	require.Len(t, failures, 1)
+	require.Equal(t, derived(5), failures[0].ev)
+	require.ErrorIs(t, failures[0].err, types.ErrOutOfOrder)
+
+	// an unknown chain
+	unknown := derived(2)
+	unknown.ChainID = eth.ChainIDFromUInt64(901)
+	require.True(t, chainsDB.OnEvent(unknown))
+	require.Len(t, failures, 2)
+	require.ErrorIs(t, failures[1].err, types.ErrUnknownChain)
+
+	// finality cannot go backwards
+	require.True(t, chainsDB.OnEvent(superevents.FinalizedL1RequestEvent{FinalizedL1: testRef("L1", 3)}))
+	require.True(t, chainsDB.OnEvent(superevents.FinalizedL1RequestEvent{FinalizedL1: testRef("L1", 2)}))
+	require.Len(t, failures, 3)
+	require.ErrorIs(t, failures[2].err, types.ErrOutOfOrder)
+
+	// events that are not handled are not reported
+	require.False(t, chainsDB.OnEvent(superevents.LocalUnsafeReceivedEvent{ChainID: chain}))
+	require.Len(t, failures, 3)
+
+	chainsDB.AttachEventErrorHandler(nil)
+	require.True(t, chainsDB.OnEvent(derived(5)))
+	require.Len(t, failures, 3)
+}
+
+func TestChainsWithoutCrossDB(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	require.Empty(t, chainsDB.ChainsWithoutCrossDB())
+
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	chainD := eth.ChainIDFromUInt64(903)
+
+	// fully wired
+	chainsDB.AddLogDB(chainA, newTestLogDB(t))
+	chainsDB.AddLocalDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	chainsDB.AddCrossDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	// only a log DB
+	chainsDB.AddLogDB(chainC, newTestLogDB(t))
+	// log and local DB
+	chainsDB.AddLogDB(chainB, newTestLogDB(t))
+	chainsDB.AddLocalDerivedFromDB(chainB, newTestDerivedFromDB(t))
+	// only a local DB
+	chainsDB.AddLocalDerivedFromDB(chainD, newTestDerivedFromDB(t))
+
+	require.Equal(t, []eth.ChainID{chainB, chainC, chainD}, chainsDB.ChainsWithoutCrossDB())
+
+	chainsDB.AddCrossDerivedFromDB(chainC, newTestDerivedFromDB(t))
+	require.Equal(t, []eth.ChainID{chainB, chainD}, chainsDB.ChainsWithoutCrossDB())
+}
+
+func TestDBAccessors(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+
+	logDB := newTestLogDB(t)
+	localDB := newTestDerivedFromDB(t)
+	crossDB := newTestDerivedFromDB(t)
+	chainsDB.AddLogDB(chainA, logDB)
+	chainsDB.AddLocalDerivedFromDB(chainA, localDB)
+	chainsDB.AddCrossDerivedFromDB(chainA, crossDB)
+
+	gotLogs, ok := chainsDB.LogDB(chainA)
+	require.True(t, ok)
+	require.Same(t, logDB, gotLogs)
+	gotLocal, ok := chainsDB.LocalDB(chainA)
+	require.True(t, ok)
+	require.Same(t, localDB, gotLocal)
+	gotCross, ok := chainsDB.CrossDB(chainA)
+	require.True(t, ok)
+	require.Same(t, crossDB, gotCross)
+
+	// the handles are live
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 0), testRef("L2", 0)))
+	pair, err := chainsDB.CrossSafe(chainA)
+	require.NoError(t, err)
+	require.Equal(t, types.BlockSealFromRef(testRef("L2", 0)), pair.Derived)
+
+	_, ok = chainsDB.LogDB(chainB)
+	require.False(t, ok)
+	_, ok = chainsDB.LocalDB(chainB)
+	require.False(t, ok)
+	_, ok = chainsDB.CrossDB(chainB)
+	require.False(t, ok)
+}
+
+func TestAddDBs(t *testing.T) {
+	chain := eth.ChainIDFromUInt64(900)
+	t.Run("lenient", func(t *testing.T) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		logDB, localDB, crossDB := newTestLogDB(t), newTestDerivedFromDB(t), newTestDerivedFromDB(t)
+		chainsDB.AddLogDB(chain, logDB)
+		chainsDB.AddLocalDerivedFromDB(chain, localDB)
+		chainsDB.AddCrossDerivedFromDB(chain, crossDB)
+
+		// duplicates overwrite
+		logDB2, localDB2, crossDB2 := newTestLogDB(t), newTestDerivedFromDB(t), newTestDerivedFromDB(t)
+		chainsDB.AddLogDB(chain, logDB2)
+		chainsDB.AddLocalDerivedFromDB(chain, localDB2)
+		chainsDB.AddCrossDerivedFromDB(chain, crossDB2)
+		got, _ := chainsDB.LogDB(chain)
+		require.Same(t, logDB2, got)
+		gotLocal, _ := chainsDB.LocalDB(chain)
+		require.Same(t, localDB2, gotLocal)
+		gotCross, _ := chainsDB.CrossDB(chain)
+		require.Same(t, crossDB2, gotCross)
+	})
+	t.Run("strict", func(t *testing.T) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		logDB, localDB, crossDB := newTestLogDB(t), newTestDerivedFromDB(t), newTestDerivedFromDB(t)
+		require.NoError(t, chainsDB.AddLogDBStrict(chain, logDB))
+		require.NoError(t, chainsDB.AddLocalDerivedFromDBStrict(chain, localDB))
+		require.NoError(t, chainsDB.AddCrossDerivedFromDBStrict(chain, crossDB))
+
+		// duplicates are refused, and do not overwrite
+		require.ErrorIs(t, chainsDB.AddLogDBStrict(chain, newTestLogDB(t)), types.ErrConflict)
+		require.ErrorIs(t, chainsDB.AddLocalDerivedFromDBStrict(chain, newTestDerivedFromDB(t)), types.ErrConflict)
+		require.ErrorIs(t, chainsDB.AddCrossDerivedFromDBStrict(chain, newTestDerivedFromDB(t)), types.ErrConflict)
+		got, _ := chainsDB.LogDB(chain)
+		require.Same(t, logDB, got)
+		gotLocal, _ := chainsDB.LocalDB(chain)
+		require.Same(t, localDB, gotLocal)
+		gotCross, _ := chainsDB.CrossDB(chain)
+		require.Same(t, crossDB, gotCross)
+
+		// a DB registered leniently is also refused in strict mode
+		other := eth.ChainIDFromUInt64(901)
+		chainsDB.AddLogDB(other, newTestLogDB(t))
+		require.ErrorIs(t, chainsDB.AddLogDBStrict(other, newTestLogDB(t)), types.ErrConflict)
+	})
+}
+
+// closeErrLogDB is a log DB that fails to close.
+type closeErrLogDB struct {
+	LogStorage
+}
+
+func (closeErrLogDB) Close() error {
+	return errors.New("close failure")
+}
+
+func TestRemoveChain(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+
+	for _, chain := range []eth.ChainID{chainA, chainB} {
+		chainsDB.AddLogDB(chain, newTestLogDB(t))
+		chainsDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainsDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainsDB.AddCrossUnsafeTracker(chain)
+	}
+
+	require.NoError(t, chainsDB.RemoveChain(chainA))
+	require.False(t, chainsDB.logDBs.Has(chainA))
+	require.False(t, chainsDB.localDBs.Has(chainA))
+	require.False(t, chainsDB.crossDBs.Has(chainA))
+	require.False(t, chainsDB.crossUnsafe.Has(chainA))
+	_, err := chainsDB.LocalSafe(chainA)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+
+	// the other chain is not affected
+	require.True(t, chainsDB.logDBs.Has(chainB))
+	require.True(t, chainsDB.localDBs.Has(chainB))
+	require.True(t, chainsDB.crossDBs.Has(chainB))
+	require.True(t, chainsDB.crossUnsafe.Has(chainB))
+
+	// removing twice, or removing an unknown chain, fails
+	require.ErrorIs(t, chainsDB.RemoveChain(chainA), types.ErrUnknownChain)
+	require.ErrorIs(t, chainsDB.RemoveChain(eth.ChainIDFromUInt64(903)), types.ErrUnknownChain)
+
+	// a chain with only partial data can be removed
+	chainC := eth.ChainIDFromUInt64(902)
+	chainsDB.AddLocalDerivedFromDB(chainC, newTestDerivedFromDB(t))
+	require.NoError(t, chainsDB.RemoveChain(chainC))
+	require.False(t, chainsDB.localDBs.Has(chainC))
+
+	// a close failure is returned, but the chain is still removed
+	chainsDB.AddLogDB(chainC, closeErrLogDB{})
+	require.ErrorContains(t, chainsDB.RemoveChain(chainC), "close failure")
+	require.False(t, chainsDB.logDBs.Has(chainC))
+}
+
+// countCloseLogDB is a log DB that counts how often it is closed.
+type countCloseLogDB struct {
+	LogStorage
+	closes atomic.Int32
+}
+
+func (d *countCloseLogDB) Close() error {
+	d.closes.Add(1)
+	return nil
+}
+
+// countCloseDerivedFromDB is a derived-from DB that counts how often it is closed.
+type countCloseDerivedFromDB struct {
+	CrossDerivedFromStorage
+	closes atomic.Int32
+}
+
+func (d *countCloseDerivedFromDB) Close() error {
+	d.closes.Add(1)
+	return nil
+}
+
+func TestRemoveChainClosesOnce(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chain := eth.ChainIDFromUInt64(900)
+	logDB := &countCloseLogDB{LogStorage: newTestLogDB(t)}
+	localDB := &countCloseDerivedFromDB{CrossDerivedFromStorage: newTestDerivedFromDB(t)}
+	crossDB := &countCloseDerivedFromDB{CrossDerivedFromStorage: newTestDerivedFromDB(t)}
+	chainsDB.AddLogDB(chain, logDB)
+	chainsDB.AddLocalDerivedFromDB(chain, localDB)
+	chainsDB.AddCrossDerivedFromDB(chain, crossDB)
+	chainsDB.AddCrossUnsafeTracker(chain)
+
+	// concurrent removals of the same chain: exactly one succeeds, and closes each DB
+	const removals = 8
+	var wg sync.WaitGroup
+	var removed atomic.Int32
+	for i := 0; i < removals; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := chainsDB.RemoveChain(chain)
+			if err == nil {
+				removed.Add(1)
+			} else {
+				require.ErrorIs(t, err, types.ErrUnknownChain)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), removed.Load())
+	require.Equal(t, int32(1), logDB.closes.Load())
+	require.Equal(t, int32(1), localDB.closes.Load())
+	require.Equal(t, int32(1), crossDB.closes.Load())
+}
+
+func TestRemoveChainConcurrentEvents(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelInfo), sampleDepSet(t))
+	chainsDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainsDB.AddLogDB(chain, newTestLogDB(t))
+	chainsDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainsDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainsDB.AddCrossUnsafeTracker(chain)
+
+	var mu sync.Mutex
+	var failures []error
+	chainsDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := uint64(0); i < 100; i++ {
+			chainsDB.OnEvent(superevents.LocalDerivedEvent{
+				ChainID: chain,
+				Derived: types.DerivedBlockRefPair{DerivedFrom: testRef("L1", i), Derived: testRef("L2", i)},
+			})
+		}
+	}()
+	require.NoError(t, chainsDB.RemoveChain(chain))
+	wg.Wait()
+
+	// events after the removal fail with an unknown chain, rather than using the removed DBs
+	mu.Lock()
+	defer mu.Unlock()
+	for _, err := range failures {
+		require.ErrorIs(t, err, types.ErrUnknownChain)
+	}
+	require.False(t, chainsDB.localDBs.Has(chain))
+}
+
+// trackCloseLogDB is a log DB that records whether it was closed, and optionally fails to close.
+type trackCloseLogDB struct {
+	LogStorage
+	closed atomic.Bool
+	err    error
+}
+
+func (d *trackCloseLogDB) Close() error {
+	d.closed.Store(true)
+	return d.err
+}
+
+func TestCloseCtx(t *testing.T) {
+	t.Run("all closed", func(t *testing.T) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		var dbs []*trackCloseLogDB
+		for i := uint64(0); i < 3*closeWorkers; i++ {
+			d := &trackCloseLogDB{}
+			dbs = append(dbs, d)
+			chainsDB.AddLogDB(eth.ChainIDFromUInt64(1000+i), d)
+		}
+		require.NoError(t, chainsDB.Close())
+		for _, d := range dbs {
+			require.True(t, d.closed.Load())
+		}
+	})
+	t.Run("errors of all chains", func(t *testing.T) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		chainA := eth.ChainIDFromUInt64(900)
+		chainB := eth.ChainIDFromUInt64(901)
+		chainC := eth.ChainIDFromUInt64(902)
+		errA := errors.New("failure A")
+		errB := errors.New("failure B")
+		chainsDB.AddLogDB(chainA, &trackCloseLogDB{err: errA})
+		chainsDB.AddLogDB(chainB, &trackCloseLogDB{err: errB})
+		okDB := &trackCloseLogDB{}
+		chainsDB.AddLogDB(chainC, okDB)
+		err := chainsDB.CloseCtx(context.Background())
+		require.ErrorIs(t, err, errA)
+		require.ErrorIs(t, err, errB)
+		require.ErrorContains(t, err, "chain "+chainA.String())
+		require.ErrorContains(t, err, "chain "+chainB.String())
+		require.True(t, okDB.closed.Load())
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		d := &trackCloseLogDB{}
+		chainsDB.AddLogDB(eth.ChainIDFromUInt64(900), d)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, chainsDB.CloseCtx(ctx), context.Canceled)
+		require.False(t, d.closed.Load())
+	})
+}
@@ -49,6 +49,7 @@ type dataAccess interface {
 }
 
 type EntryDB[T EntryType, E Entry[T], B Binary[T, E]] struct {
+	path         string
 	data         dataAccess
 	lastEntryIdx EntryIdx
 
@@ -75,6 +76,7 @@ func NewEntryDB[T EntryType, E Entry[T], B Binary[T, E]](logger log.Logger, path
 	var b B
 	size := info.Size() / int64(b.EntrySize())
 	db := &EntryDB[T, E, B]{
+		path:         path,
 		data:         file,
 		lastEntryIdx: EntryIdx(size - 1),
 	}
@@ -122,11 +124,7 @@ func (e *EntryDB[T, E, B]) Append(entries ...E) error {
 			return fmt.Errorf("failed to recover from previous write error: %w", truncateErr)
 		}
 	}
-	data := make([]byte, 0, len(entries)*e.b.EntrySize())
-	for i := range entries {
-		data = e.b.Append(data, &entries[i])
-	}
-	if n, err := e.data.Write(data); err != nil {
+	if n, err := e.data.Write(e.encode(entries)); err != nil {
 		if n == 0 {
 			// Didn't write any data, so no recovery required
 			return err
@@ -155,6 +153,48 @@ func (e *EntryDB[T, E, B]) Truncate(idx EntryIdx) error {
 	return nil
 }
 
+// Rewrite replaces all entries of the database with the given entries.
+// The entries are written to a temporary file, which is then renamed over the database file:
+// if the rewrite fails, or the process crashes, the database holds either the old or the new entries.
+func (e *EntryDB[T, E, B]) Rewrite(entries ...E) error {
+	if e.path == "" {
+		return errors.New("cannot rewrite database without file path")
+	}
+	tmpPath := e.path + ".rewrite"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create rewrite file at %v: %w", tmpPath, err)
+	}
+	abort := func(err error) error {
+		return errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if _, err := file.Write(e.encode(entries)); err != nil {
+		return abort(fmt.Errorf("failed to write %d entries: %w", len(entries), err))
+	}
+	if err := file.Sync(); err != nil {
+		return abort(fmt.Errorf("failed to sync rewrite file: %w", err))
+	}
+	if err := os.Rename(tmpPath, e.path); err != nil {
+		return abort(fmt.Errorf("failed to replace database at %v: %w", e.path, err))
+	}
+	old := e.data
+	e.data = file
+	e.lastEntryIdx = EntryIdx(len(entries) - 1)
+	e.cleanupFailedWrite = false
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("failed to close replaced database file: %w", err)
+	}
+	return nil
+}
+
+func (e *EntryDB[T, E, B]) encode(entries []E) []byte {
+	data := make([]byte, 0, len(entries)*e.b.EntrySize())
+	for i := range entries {
+		data = e.b.Append(data, &entries[i])
+	}
+	return data
+}
+
 // recover an invalid database by truncating back to the last complete event.
 func (e *EntryDB[T, E, B]) recover() error {
 	if err := e.data.Truncate(e.Size() * int64(e.b.EntrySize())); err != nil {
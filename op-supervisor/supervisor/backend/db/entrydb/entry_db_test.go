@@ -139,6 +139,50 @@ func TestTruncate(t *testing.T) {
 	})
 }
 
+func TestRewrite(t *testing.T) {
+	t.Run("Replace", func(t *testing.T) {
+		logger := testlog.Logger(t, log.LvlInfo)
+		file := filepath.Join(t.TempDir(), "entries.db")
+		db, err := NewEntryDB[TestEntryType, TestEntry, TestEntryBinary](logger, file)
+		require.NoError(t, err)
+		require.NoError(t, db.Append(createEntry(1), createEntry(2), createEntry(3)))
+
+		require.NoError(t, db.Rewrite(createEntry(3), createEntry(4)))
+		require.EqualValues(t, 2, db.Size())
+		requireRead(t, db, 0, createEntry(3))
+		requireRead(t, db, 1, createEntry(4))
+		require.NoError(t, db.Append(createEntry(5)))
+		requireRead(t, db, 2, createEntry(5))
+		require.NoError(t, db.Close())
+
+		// The rewritten entries are persisted
+		db, err = NewEntryDB[TestEntryType, TestEntry, TestEntryBinary](logger, file)
+		require.NoError(t, err)
+		defer db.Close()
+		require.EqualValues(t, 3, db.Size())
+		requireRead(t, db, 0, createEntry(3))
+		requireRead(t, db, 2, createEntry(5))
+		_, err = os.Stat(file + ".rewrite")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("FailedRewriteKeepsEntries", func(t *testing.T) {
+		logger := testlog.Logger(t, log.LvlInfo)
+		file := filepath.Join(t.TempDir(), "entries.db")
+		db, err := NewEntryDB[TestEntryType, TestEntry, TestEntryBinary](logger, file)
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, db.Append(createEntry(1), createEntry(2)))
+
+		// Block the temporary file, so the rewrite fails
+		require.NoError(t, os.Mkdir(file+".rewrite", 0o755))
+		require.Error(t, db.Rewrite(createEntry(3)))
+		require.EqualValues(t, 2, db.Size())
+		requireRead(t, db, 0, createEntry(1))
+		requireRead(t, db, 1, createEntry(2))
+	})
+}
+
 func TestTruncateTrailingPartialEntries(t *testing.T) {
 	logger := testlog.Logger(t, log.LvlInfo)
 	file := filepath.Join(t.TempDir(), "entries.db")
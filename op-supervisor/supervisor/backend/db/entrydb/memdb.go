@@ -30,7 +30,12 @@ func (s *MemEntryStore[T, E]) Append(entries ...E) error {
 }
 
 func (s *MemEntryStore[T, E]) Truncate(idx EntryIdx) error {
-	s.entries = s.entries[:min(s.Size()-1, int64(idx+1))]
+	s.entries = s.entries[:min(s.Size(), int64(idx+1))]
+	return nil
+}
+
+func (s *MemEntryStore[T, E]) Rewrite(entries ...E) error {
+	s.entries = append([]E(nil), entries...)
 	return nil
 }
 
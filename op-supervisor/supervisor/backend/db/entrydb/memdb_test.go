@@ -0,0 +1,63 @@
+package entrydb
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemEntryStoreTruncate(t *testing.T) {
+	create := func(t *testing.T) *MemEntryStore[TestEntryType, TestEntry] {
+		s := &MemEntryStore[TestEntryType, TestEntry]{}
+		require.NoError(t, s.Append(createEntry(1), createEntry(2), createEntry(3)))
+		return s
+	}
+
+	t.Run("Partial", func(t *testing.T) {
+		s := create(t)
+		require.NoError(t, s.Truncate(1))
+		require.EqualValues(t, 2, s.Size()) // 0 and 1 are preserved
+		entry, err := s.Read(1)
+		require.NoError(t, err)
+		require.Equal(t, createEntry(2), entry)
+		_, err = s.Read(2)
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("LastEntry", func(t *testing.T) {
+		// like the file-backed EntryDB, truncating to the last entry keeps it
+		s := create(t)
+		require.NoError(t, s.Truncate(2))
+		require.EqualValues(t, 3, s.Size())
+		entry, err := s.Read(2)
+		require.NoError(t, err)
+		require.Equal(t, createEntry(3), entry)
+	})
+
+	t.Run("PastLastEntry", func(t *testing.T) {
+		s := create(t)
+		require.NoError(t, s.Truncate(10))
+		require.EqualValues(t, 3, s.Size())
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		s := create(t)
+		require.NoError(t, s.Truncate(-1))
+		require.EqualValues(t, 0, s.Size())
+		_, err := s.Read(0)
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestMemEntryStoreRewrite(t *testing.T) {
+	s := &MemEntryStore[TestEntryType, TestEntry]{}
+	require.NoError(t, s.Append(createEntry(1), createEntry(2), createEntry(3)))
+	entries := []TestEntry{createEntry(3), createEntry(4)}
+	require.NoError(t, s.Rewrite(entries...))
+	require.EqualValues(t, 2, s.Size())
+	entries[0] = createEntry(5)
+	entry, err := s.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, createEntry(3), entry, "store does not share the given entries")
+}
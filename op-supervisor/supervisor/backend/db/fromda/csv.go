@@ -0,0 +1,49 @@
+package fromda
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+)
+
+var csvHeader = []string{
+	"derived_from_number", "derived_from_hash", "derived_from_time",
+	"derived_number", "derived_hash", "derived_time",
+	"invalidated",
+}
+
+// EncodeCSV writes a header, and then one row per entry, with the derived-from and derived block numbers,
+// hashes and timestamps, and whether the entry is invalidated.
+// The DB is read-locked while encoding, so the rows are a consistent snapshot.
+func (db *DB) EncodeCSV(w io.Writer) error {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	lastIndex := db.store.LastEntryIdx()
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		row := []string{
+			strconv.FormatUint(link.derivedFrom.Number, 10),
+			link.derivedFrom.Hash.String(),
+			strconv.FormatUint(link.derivedFrom.Timestamp, 10),
+			strconv.FormatUint(link.derived.Number, 10),
+			link.derived.Hash.String(),
+			strconv.FormatUint(link.derived.Timestamp, 10),
+			strconv.FormatBool(link.invalidated),
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write entry %d: %w", i, err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,65 @@
+package fromda
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestEncodeCSV(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		var buf bytes.Buffer
+		require.NoError(t, db.EncodeCSV(&buf))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, csvHeader, rows[0])
+		rows = rows[1:]
+		require.Len(t, rows, int(db.store.Size()))
+
+		requireRow := func(row []string, derivedFrom, derived types.BlockSeal, invalidated bool) {
+			require.Equal(t, strconv.FormatUint(derivedFrom.Number, 10), row[0])
+			require.Equal(t, derivedFrom.Hash, common.HexToHash(row[1]))
+			require.Equal(t, strconv.FormatUint(derivedFrom.Timestamp, 10), row[2])
+			require.Equal(t, strconv.FormatUint(derived.Number, 10), row[3])
+			require.Equal(t, derived.Hash, common.HexToHash(row[4]))
+			require.Equal(t, strconv.FormatUint(derived.Timestamp, 10), row[5])
+			require.Equal(t, strconv.FormatBool(invalidated), row[6])
+		}
+		requireRow(rows[0], mockL1(0), mockL2(0), false)
+		requireRow(rows[1], mockL1(1), mockL2(1), false)
+		requireRow(rows[2], mockL1(2), mockL2(1), false)
+		requireRow(rows[3], mockL1(2), mockL2(2), true)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				var buf bytes.Buffer
+				require.NoError(t, db.EncodeCSV(&buf))
+				rows, err := csv.NewReader(&buf).ReadAll()
+				require.NoError(t, err)
+				require.Equal(t, [][]string{csvHeader}, rows)
+			})
+	})
+}
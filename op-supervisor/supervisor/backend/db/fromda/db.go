@@ -2,11 +2,15 @@ package fromda
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
 	"sync"
+	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
@@ -14,39 +18,70 @@ import (
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 )
 
+// dbIDs assigns every DB a unique id, see lockPair.
+var dbIDs atomic.Uint64
+
 type EntryStore interface {
 	Size() int64
 	LastEntryIdx() entrydb.EntryIdx
 	Read(idx entrydb.EntryIdx) (Entry, error)
 	Append(entries ...Entry) error
 	Truncate(idx entrydb.EntryIdx) error
+	// Rewrite replaces all entries. The store must never be observed with partially replaced entries,
+	// so a failed rewrite leaves the original entries in place.
+	Rewrite(entries ...Entry) error
 	Close() error
 }
 
 // DB implements an append only database for log data and cross-chain dependencies.
 // Each entry is fixed size, and denotes an increment in L1 (derived-from) and/or L2 (derived) block.
 // Data is an append-only log, that can be binary searched for any necessary derivation-link data.
+// Lookups decode entries into fresh values, and never return references to shared state.
 type DB struct {
+	// id orders the locks of DBs that are locked together, see lockPair.
+	id     uint64
 	log    log.Logger
 	m      Metrics
 	store  EntryStore
 	rwLock sync.RWMutex
+	// rewinds counts the truncations of the store, so snapshots can detect that their entries were rewritten.
+	rewinds uint64
+	// onInvalidated, if not nil, is called with each block that is invalidated.
+	onInvalidated func(invalidated types.DerivedBlockRefPair)
+	// pendingInvalidated holds the invalidations that were written, but not passed to onInvalidated yet.
+	pendingInvalidated []types.DerivedBlockRefPair
+}
+
+// Option configures optional behavior of the DB.
+type Option func(db *DB)
+
+// WithInvalidationHook sets a callback that is called with each block that is invalidated,
+// after the invalidation entry is written, including invalidations written by MergeInto and Import.
+// The callback is called without holding the DB lock.
+func WithInvalidationHook(fn func(invalidated types.DerivedBlockRefPair)) Option {
+	return func(db *DB) {
+		db.onInvalidated = fn
+	}
 }
 
-func NewFromFile(logger log.Logger, m Metrics, path string) (*DB, error) {
+func NewFromFile(logger log.Logger, m Metrics, path string, opts ...Option) (*DB, error) {
 	store, err := entrydb.NewEntryDB[EntryType, Entry, EntryBinary](logger, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
-	return NewFromEntryStore(logger, m, store)
+	return NewFromEntryStore(logger, m, store, opts...)
 }
 
-func NewFromEntryStore(logger log.Logger, m Metrics, store EntryStore) (*DB, error) {
+func NewFromEntryStore(logger log.Logger, m Metrics, store EntryStore, opts ...Option) (*DB, error) {
 	db := &DB{
+		id:    dbIDs.Add(1),
 		log:   logger,
 		m:     m,
 		store: store,
 	}
+	for _, opt := range opts {
+		opt(db)
+	}
 	db.m.RecordDBDerivedEntryCount(db.store.Size())
 	return db, nil
 }
@@ -66,6 +101,199 @@ func (db *DB) First() (pair types.DerivedBlockSealPair, err error) {
 	return last.sealOrErr()
 }
 
+// Len returns the number of entries in the DB.
+func (db *DB) Len() int {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	return int(db.store.Size())
+}
+
+// At returns the entry at the given index, and whether the entry was invalidated.
+// Indices range from 0 up to Len: ErrSkipped is returned for a negative index, and ErrFuture past the last entry.
+// This is meant for debug tooling: indices are not stable,
+// and refer to different entries after a rewind, or after pruning with PruneBefore.
+func (db *DB) At(index int) (pair types.DerivedBlockSealPair, invalidated bool, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if index < 0 {
+		return types.DerivedBlockSealPair{}, false, fmt.Errorf("negative entry index %d: %w", index, types.ErrSkipped)
+	}
+	link, err := db.readAt(entrydb.EntryIdx(index))
+	if err != nil {
+		return types.DerivedBlockSealPair{}, false, fmt.Errorf("failed to read entry %d: %w", index, err)
+	}
+	return types.DerivedBlockSealPair{DerivedFrom: link.derivedFrom, Derived: link.derived}, link.invalidated, nil
+}
+
+// DerivedStats summarizes the span of a DB.
+type DerivedStats struct {
+	Entries int
+
+	FirstDerivedFrom uint64
+	LastDerivedFrom  uint64
+	FirstDerived     uint64
+	LastDerived      uint64
+
+	// TailInvalidated is true if the last entry is invalidated, and awaits a replacement block.
+	TailInvalidated bool
+	// Invalidated is the number of invalidated entries.
+	Invalidated int
+}
+
+// Stats returns the span of the DB, from the first and last entries.
+// Counting the invalidated entries scans all entries, so this is linear in the size of the DB.
+// Returns types.ErrFuture if the DB is empty.
+func (db *DB) Stats() (DerivedStats, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	lastIndex := db.store.LastEntryIdx()
+	if lastIndex < 0 {
+		return DerivedStats{}, types.ErrFuture
+	}
+	first, err := db.readAt(0)
+	if err != nil {
+		return DerivedStats{}, fmt.Errorf("failed to read first entry: %w", err)
+	}
+	last, err := db.readAt(lastIndex)
+	if err != nil {
+		return DerivedStats{}, fmt.Errorf("failed to read last entry: %w", err)
+	}
+	stats := DerivedStats{
+		Entries:          int(lastIndex) + 1,
+		FirstDerivedFrom: first.derivedFrom.Number,
+		LastDerivedFrom:  last.derivedFrom.Number,
+		FirstDerived:     first.derived.Number,
+		LastDerived:      last.derived.Number,
+		TailInvalidated:  last.invalidated,
+	}
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return DerivedStats{}, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if link.invalidated {
+			stats.Invalidated++
+		}
+	}
+	return stats, nil
+}
+
+// VerifyLinksTo checks that the first entry of this DB continues from the given parent tail,
+// i.e. the last entry of a prior DB, such that the two can be treated as one contiguous history.
+// Both the derived and the derived-from block of the first entry must either repeat
+// the block of the parent tail, or be the next block, and at least one of them must be the next block.
+// Entries do not record the parent-hash of a block, so the hash of a next block cannot be
+// checked against the parent tail; a repeated block must match the hash of the parent tail.
+// ErrFuture is returned if this DB is empty.
+func (db *DB) VerifyLinksTo(parentTail types.DerivedBlockSealPair) error {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	return db.verifyLinksTo(parentTail)
+}
+
+func (db *DB) verifyLinksTo(parentTail types.DerivedBlockSealPair) error {
+	if db.store.Size() == 0 {
+		return types.ErrFuture
+	}
+	first, err := db.readAt(0)
+	if err != nil {
+		return fmt.Errorf("failed to read first derivation data: %w", err)
+	}
+	derivedFromNext, err := checkSealLink(parentTail.DerivedFrom, first.derivedFrom)
+	if err != nil {
+		return fmt.Errorf("derived-from block of first entry %s does not link to %s: %w", first, parentTail, err)
+	}
+	derivedNext, err := checkSealLink(parentTail.Derived, first.derived)
+	if err != nil {
+		return fmt.Errorf("derived block of first entry %s does not link to %s: %w", first, parentTail, err)
+	}
+	if !derivedFromNext && !derivedNext {
+		return fmt.Errorf("first entry %s repeats parent tail %s: %w", first, parentTail, types.ErrConflict)
+	}
+	return nil
+}
+
+// checkSealLink checks that next either repeats prev, or is at the next height.
+// It returns true if next is at the next height.
+func checkSealLink(prev, next types.BlockSeal) (bool, error) {
+	switch {
+	case next.Number == prev.Number:
+		if next.Hash != prev.Hash {
+			return false, fmt.Errorf("block %s conflicts with %s at same height: %w", next, prev, types.ErrConflict)
+		}
+		return false, nil
+	case next.Number == prev.Number+1:
+		if next.Timestamp < prev.Timestamp {
+			return false, fmt.Errorf("block %s is older than parent %s: %w", next, prev, types.ErrConflict)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("block %s does not follow %s: %w", next, prev, types.ErrOutOfOrder)
+	}
+}
+
+// Verify checks that the stored entries are internally consistent, e.g. after a crash,
+// by checking the invariants that addLink enforces between each entry and the entry before it:
+// the derived and derived-from blocks each either repeat, with the same hash, or advance by one block,
+// at least one of them advances, and only the last entry may be invalidated.
+// A derived block may change hash without advancing, if it replaced an invalidated block in a new derived-from block.
+// Entries do not record parent-hashes, so the hash of an advanced block cannot be checked.
+// This reads all entries; the error reports the index of the first inconsistent entry.
+func (db *DB) Verify() error {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	return db.verifyLocked()
+}
+
+// verifyLocked is Verify, for callers that already hold the rwLock.
+func (db *DB) verifyLocked() error {
+	lastIndex := db.store.LastEntryIdx()
+	var prev LinkEntry
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if link.invalidated && (i == 0 || i < lastIndex) {
+			return fmt.Errorf("entry %d %s is invalidated, but is not the last entry after the first: %w", i, link, types.ErrDataCorruption)
+		}
+		if i > 0 {
+			derivedFromNext, err := checkSealLink(prev.derivedFrom, link.derivedFrom)
+			if err != nil {
+				return fmt.Errorf("derived-from block of entry %d %s does not follow entry %d %s: %w", i, link, i-1, prev, err)
+			}
+			// A replacement of an invalidated block takes the height of the block it replaces,
+			// which may still be present in the entry before it, derived from an older L1 block.
+			replacement := derivedFromNext && link.derived.Number == prev.derived.Number
+			derivedNext, err := checkSealLink(prev.derived, link.derived)
+			if err != nil && !replacement {
+				return fmt.Errorf("derived block of entry %d %s does not follow entry %d %s: %w", i, link, i-1, prev, err)
+			}
+			if !derivedFromNext && !derivedNext {
+				return fmt.Errorf("entry %d %s repeats entry %d: %w", i, link, i-1, types.ErrConflict)
+			}
+		}
+		prev = link
+	}
+	return nil
+}
+
+// StoreVersion returns the layout version of the entries in the store,
+// as determined by the first entry. An empty store is reported as LatestVersion,
+// since any entries written to it will use the latest layout.
+func (db *DB) StoreVersion() (uint8, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if db.store.Size() == 0 {
+		return LatestVersion, nil
+	}
+	e, err := db.store.Read(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read first entry: %w", err)
+	}
+	return e.Type().Version()
+}
+
 func (db *DB) PreviousDerived(derived eth.BlockID) (prevDerived types.BlockSeal, err error) {
 	db.rwLock.RLock()
 	defer db.rwLock.RUnlock()
@@ -108,6 +336,39 @@ func (db *DB) Latest() (pair types.DerivedBlockSealPair, err error) {
 	return link.sealOrErr()
 }
 
+// LatestDerivedRef returns the latest derived block as a block ref,
+// with the parent-hash taken from the derived block of the entry preceding the first entry of the latest derived block.
+// Like PreviousDerived, the first derived block in the DB has a zeroed parent-hash.
+// If the last entry is invalidated, this returns a types.ErrAwaitReplacementBlock error.
+func (db *DB) LatestDerivedRef() (eth.BlockRef, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	link, err := db.latest()
+	if err != nil {
+		return eth.BlockRef{}, err
+	}
+	pair, err := link.sealOrErr()
+	if err != nil {
+		return eth.BlockRef{}, err
+	}
+	selfIndex, _, err := db.firstDerivedFrom(pair.Derived.Number)
+	if err != nil {
+		return eth.BlockRef{}, fmt.Errorf("failed to find first derived %d: %w", pair.Derived.Number, err)
+	}
+	if selfIndex == 0 {
+		return pair.Derived.ForceWithParent(eth.BlockID{}), nil
+	}
+	prev, err := db.readAt(selfIndex - 1)
+	if err != nil {
+		return eth.BlockRef{}, fmt.Errorf("cannot find previous derived before %s: %w", pair.Derived, err)
+	}
+	ref, err := pair.Derived.WithParent(prev.derived.ID())
+	if err != nil {
+		return eth.BlockRef{}, fmt.Errorf("%w: %w", types.ErrDataCorruption, err)
+	}
+	return ref, nil
+}
+
 func (db *DB) Invalidated() (pair types.DerivedBlockSealPair, err error) {
 	db.rwLock.RLock()
 	defer db.rwLock.RUnlock()
@@ -124,6 +385,351 @@ func (db *DB) Invalidated() (pair types.DerivedBlockSealPair, err error) {
 	}, nil
 }
 
+// LastValidBeforeInvalidation returns the last valid pair, preceding the invalidated entry at the tail of the DB.
+// This returns a types.ErrConflict error if the last entry is not invalidated.
+func (db *DB) LastValidBeforeInvalidation() (pair types.DerivedBlockSealPair, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	lastIndex := db.store.LastEntryIdx()
+	if lastIndex < 0 {
+		return types.DerivedBlockSealPair{}, types.ErrFuture
+	}
+	last, err := db.readAt(lastIndex)
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to read last derivation data: %w", err)
+	}
+	if !last.invalidated {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("last entry %s is not invalidated: %w", last, types.ErrConflict)
+	}
+	if lastIndex == 0 {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("first entry %s cannot be invalidated: %w", last, types.ErrDataCorruption)
+	}
+	prev, err := db.readAt(lastIndex - 1)
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to read entry before invalidated entry %s: %w", last, err)
+	}
+	return prev.sealOrErr()
+}
+
+// Throughput returns the ratio of new L2 blocks to L1 blocks, over the last l1Window L1 blocks in the DB.
+// Empty L1 blocks, that repeat the last derived L2 block, count towards the L1 blocks, but not the L2 blocks.
+// Returns types.ErrFuture if the DB does not contain l1Window L1 blocks yet.
+func (db *DB) Throughput(l1Window uint64) (float64, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if l1Window == 0 {
+		return 0, fmt.Errorf("L1 window must not be empty")
+	}
+	last, err := db.latest()
+	if err != nil {
+		return 0, err
+	}
+	first, err := db.readAt(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read first derivation data: %w", err)
+	}
+	if l1Count := last.derivedFrom.Number - first.derivedFrom.Number + 1; l1Count < l1Window {
+		return 0, fmt.Errorf("need %d L1 blocks, but only have %d: %w", l1Window, l1Count, types.ErrFuture)
+	}
+	windowStart := last.derivedFrom.Number + 1 - l1Window
+	var l2Count uint64
+	if windowStart == first.derivedFrom.Number {
+		// The first L2 block is considered to be derived within the window.
+		l2Count = last.derived.Number - first.derived.Number + 1
+	} else {
+		// Anything up to the last L2 block derived before the window is not counted.
+		_, prev, err := db.lastDerivedAt(windowStart - 1)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find last derived before L1 window start %d: %w", windowStart, err)
+		}
+		l2Count = last.derived.Number - prev.derived.Number
+	}
+	return float64(l2Count) / float64(l1Window), nil
+}
+
+// InvalidationsSince counts the invalidated entries that are derived from L1 blocks at or after l1Number.
+// Returns types.ErrFuture if l1Number is past the latest derived-from block.
+// This scans all entries from l1Number onwards.
+func (db *DB) InvalidationsSince(l1Number uint64) (int, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	last, err := db.latest()
+	if err != nil {
+		return 0, err
+	}
+	if l1Number > last.derivedFrom.Number {
+		return 0, fmt.Errorf("derived-from %d is past latest %s: %w", l1Number, last.derivedFrom, types.ErrFuture)
+	}
+	start, _, err := db.find(false, func(link LinkEntry) int {
+		if link.derivedFrom.Number < l1Number {
+			return -1
+		}
+		return 0
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find first entry derived from %d: %w", l1Number, err)
+	}
+	count := 0
+	for i := start; i <= db.store.LastEntryIdx(); i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if link.invalidated {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FindDivergence compares the expected derived blocks, in ascending order, to the DB,
+// and returns the index in expected of the first block that the DB disagrees with,
+// along with the block that the DB has at that height.
+// A block at a height that is invalidated in the DB is considered to disagree.
+// Heights past the latest derived block cannot disagree, and end the comparison.
+// If the DB agrees with all the expected blocks it has, -1 is returned.
+// ErrSkipped is returned if an expected block is older than the first derived block in the DB.
+func (db *DB) FindDivergence(expected []eth.BlockID) (int, types.BlockSeal, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	for i, id := range expected {
+		// Take the last entry: this will be the latest canonical view,
+		// if the block was previously invalidated.
+		_, link, err := db.lastDerivedFrom(id.Number)
+		if errors.Is(err, types.ErrFuture) {
+			break
+		} else if err != nil {
+			return 0, types.BlockSeal{}, fmt.Errorf("failed to find derived block %d: %w", id.Number, err)
+		}
+		if link.invalidated || link.derived.ID() != id {
+			return i, link.derived, nil
+		}
+	}
+	return -1, types.BlockSeal{}, nil
+}
+
+// lockPair locks two distinct DBs in the order of their ids, so that concurrent operations on the same two DBs
+// cannot deadlock, regardless of which of the two DBs each operation reads from or writes to.
+// The src DB is read-locked. The dst DB is write-locked if write is set, and read-locked otherwise.
+// The returned function releases both locks.
+func lockPair(src, dst *DB, write bool) (unlock func()) {
+	lockDst, unlockDst := dst.rwLock.RLock, dst.rwLock.RUnlock
+	if write {
+		lockDst, unlockDst = dst.rwLock.Lock, dst.rwLock.Unlock
+	}
+	if src.id < dst.id {
+		src.rwLock.RLock()
+		lockDst()
+	} else {
+		lockDst()
+		src.rwLock.RLock()
+	}
+	return func() {
+		unlockDst()
+		src.rwLock.RUnlock()
+	}
+}
+
+// CommonAncestor returns the last entry that the two DBs agree on, to diagnose where the histories of two nodes,
+// that derive the same chain, diverged. Entries agree if their derived-from and derived block seals match,
+// and they are both invalidated or both not. False is returned if the DBs do not share any entry.
+//
+// Derivation is deterministic, so the DBs agree on all entries up to where they diverged.
+// This is used to binary-search for the last shared entry, within the range of entries that both DBs have,
+// e.g. if one of the DBs was pruned: O(log^2(n)) entries are read.
+// Both DBs are locked for reading during the search, in a stable order, see lockPair.
+func CommonAncestor(a, b *DB) (pair types.DerivedBlockSealPair, ok bool, err error) {
+	if a == b {
+		a.rwLock.RLock()
+		defer a.rwLock.RUnlock()
+	} else {
+		defer lockPair(a, b, false)()
+	}
+	n := int(a.store.Size())
+	if n == 0 || b.store.Size() == 0 {
+		return types.DerivedBlockSealPair{}, false, nil
+	}
+	bFirst, err := b.readAt(0)
+	if err != nil {
+		return types.DerivedBlockSealPair{}, false, fmt.Errorf("failed to read first entry: %w", err)
+	}
+	var searchErr error
+	// the first entry of a that is not before the first entry of b
+	startCmp := lookupCmp(bFirst.derivedFrom.Number, bFirst.derived.Number)
+	start := sort.Search(n, func(i int) bool {
+		link, err := a.readAt(entrydb.EntryIdx(i))
+		if err != nil {
+			searchErr = err
+			return false
+		}
+		return startCmp(link) >= 0
+	})
+	// the first entry of a, from the start, that b does not agree with
+	end := start + sort.Search(n-start, func(i int) bool {
+		link, err := a.readAt(entrydb.EntryIdx(start + i))
+		if err != nil {
+			searchErr = err
+			return false
+		}
+		_, other, err := b.lookup(link.derivedFrom.Number, link.derived.Number)
+		if errors.Is(err, types.ErrFuture) || errors.Is(err, types.ErrSkipped) {
+			return true
+		} else if err != nil {
+			searchErr = err
+			return false
+		}
+		return other != link
+	})
+	if searchErr != nil {
+		return types.DerivedBlockSealPair{}, false, fmt.Errorf("failed to search for common ancestor: %w", searchErr)
+	}
+	if end == start {
+		return types.DerivedBlockSealPair{}, false, nil
+	}
+	link, err := a.readAt(entrydb.EntryIdx(end - 1))
+	if err != nil {
+		return types.DerivedBlockSealPair{}, false, fmt.Errorf("failed to read common ancestor entry %d: %w", end-1, err)
+	}
+	return types.DerivedBlockSealPair{DerivedFrom: link.derivedFrom, Derived: link.derived}, true, nil
+}
+
+// Fingerprint returns a hash over the encoding of all entries in the DB, including invalidation entries.
+// DBs with identical histories have identical fingerprints, regardless of how the entries were written,
+// so this can cheaply check if two DBs are consistent, without comparing all entries.
+func (db *DB) Fingerprint() (common.Hash, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	hasher := crypto.NewKeccakState()
+	lastIndex := db.store.LastEntryIdx()
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		entry, err := db.store.Read(i)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		hasher.Write(entry[:])
+	}
+	var out common.Hash
+	_, _ = hasher.Read(out[:])
+	return out, nil
+}
+
+// EntriesSince returns the entries with an index after the given index, in order,
+// with a flag per entry, that is true if the entry is an invalidation entry.
+// The index of the last returned entry, index+len(entries), is the tip that a follower can continue from.
+// An index of -1 returns all entries. ErrFuture is returned if the index is past the tip.
+// The parent hashes are not stored, but attached from the preceding entries, where available.
+func (db *DB) EntriesSince(index int64) ([]types.DerivedBlockRefPair, []bool, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	lastIndex := db.store.LastEntryIdx()
+	if index < -1 {
+		return nil, nil, fmt.Errorf("invalid entry index %d", index)
+	}
+	if entrydb.EntryIdx(index) > lastIndex {
+		return nil, nil, fmt.Errorf("entry index %d is past tip %d: %w", index, lastIndex, types.ErrFuture)
+	}
+	count := int(lastIndex - entrydb.EntryIdx(index))
+	pairs := make([]types.DerivedBlockRefPair, 0, count)
+	invalidated := make([]bool, 0, count)
+	if count == 0 {
+		return pairs, invalidated, nil
+	}
+	first, err := db.readAt(entrydb.EntryIdx(index + 1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read entry %d: %w", index+1, err)
+	}
+	// Start from the last entry that precedes both the derived-from and derived block of the first entry,
+	// so the parents of the first entry are known.
+	start := entrydb.EntryIdx(index + 1)
+	for start > 0 {
+		link, err := db.readAt(start - 1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read entry %d: %w", start-1, err)
+		}
+		start--
+		if link.derivedFrom.Number < first.derivedFrom.Number && link.derived.Number < first.derived.Number {
+			break
+		}
+	}
+	var derivedFrom, derived parentTracker
+	for i := start; i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		pair := types.DerivedBlockRefPair{
+			DerivedFrom: derivedFrom.next(link.derivedFrom),
+			Derived:     derived.next(link.derived),
+		}
+		if i > entrydb.EntryIdx(index) {
+			pairs = append(pairs, pair)
+			invalidated = append(invalidated, link.invalidated)
+		}
+	}
+	return pairs, invalidated, nil
+}
+
+// parentTracker attaches parents to a non-decreasing sequence of block seals.
+type parentTracker struct {
+	parent, current *types.BlockSeal
+}
+
+// next returns the seal as block ref, with the parent set to the last preceding seal of the previous height,
+// or a zero parent, if that is not known.
+func (t *parentTracker) next(seal types.BlockSeal) eth.BlockRef {
+	if t.current != nil && t.current.Number != seal.Number {
+		t.parent = t.current
+	}
+	t.current = &seal
+	if t.parent != nil && t.parent.Number+1 == seal.Number {
+		return seal.ForceWithParent(t.parent.ID())
+	}
+	return seal.ForceWithParent(eth.BlockID{})
+}
+
+// ReplacementRecord is an invalidated block, and the block that replaced it, if any.
+type ReplacementRecord struct {
+	Invalidated types.DerivedBlockSealPair
+	// Replacement is nil if the invalidation is unresolved, i.e. still awaiting a replacement.
+	Replacement *types.DerivedBlockSealPair
+}
+
+// ReplacementHistory returns a record of each invalidation entry in the DB, in order,
+// paired with the entry that replaced it: the next entry, if it derived a block of the same height.
+// An invalidation that is not followed by a replacement, such as an invalidation at the tail of the DB,
+// is reported with a nil Replacement.
+// Note that ReplaceInvalidatedBlock removes the invalidation entry it resolves,
+// so resolved invalidations are only found in histories that retain them.
+func (db *DB) ReplacementHistory() ([]ReplacementRecord, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	var records []ReplacementRecord
+	lastIndex := db.store.LastEntryIdx()
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if !link.invalidated {
+			continue
+		}
+		record := ReplacementRecord{
+			Invalidated: types.DerivedBlockSealPair{DerivedFrom: link.derivedFrom, Derived: link.derived},
+		}
+		if i < lastIndex {
+			next, err := db.readAt(i + 1)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read entry %d: %w", i+1, err)
+			}
+			if !next.invalidated && next.derived.Number == link.derived.Number {
+				record.Replacement = &types.DerivedBlockSealPair{DerivedFrom: next.derivedFrom, Derived: next.derived}
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
 // latest is like Latest, but without lock, for internal use.
 func (db *DB) latest() (link LinkEntry, err error) {
 	lastIndex := db.store.LastEntryIdx()
@@ -156,6 +762,178 @@ func (db *DB) LastDerivedAt(derivedFrom eth.BlockID) (derived types.BlockSeal, e
 	return link.derived, nil
 }
 
+// FirstDerivedAt returns the first L2 block derived from the given L1 block.
+// Together with LastDerivedAt this bounds the range of L2 blocks derived from the L1 block.
+// This may return types.ErrAwaitReplacementBlock if the entry was invalidated and needs replacement.
+func (db *DB) FirstDerivedAt(derivedFrom eth.BlockID) (derived types.BlockSeal, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	_, link, err := db.firstDerivedAt(derivedFrom.Number)
+	if err != nil {
+		return types.BlockSeal{}, err
+	}
+	if link.derivedFrom.ID() != derivedFrom {
+		return types.BlockSeal{}, fmt.Errorf("searched for first derived-from %s but found %s: %w",
+			derivedFrom, link.derivedFrom, types.ErrConflict)
+	}
+	if link.invalidated {
+		return types.BlockSeal{}, types.ErrAwaitReplacementBlock
+	}
+	return link.derived, nil
+}
+
+// FirstDerivedWhere returns the first entry of the first L2 block that matches the condition.
+// The condition must be monotonic: once it holds for an L2 block, it must hold for all later L2 blocks,
+// so the entries can be binary-searched.
+// Returns types.ErrFuture if no L2 block matches.
+// This may return types.ErrAwaitReplacementBlock if the entry was invalidated and needs replacement.
+func (db *DB) FirstDerivedWhere(match func(derived types.BlockSeal) bool) (pair types.DerivedBlockSealPair, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	_, link, err := db.find(false, func(link LinkEntry) int {
+		if match(link.derived) {
+			return 0
+		}
+		return -1
+	})
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to find first matching derived block: %w", err)
+	}
+	return link.sealOrErr()
+}
+
+// FirstDerivedFromInRange returns the first L2 block derived from each L1 block in the inclusive range, in order.
+// L1 blocks without a recorded derivation, or with an invalidated first derivation, are skipped,
+// so the result may be empty.
+// Returns types.ErrFuture if toL1 is past the latest derived-from block.
+func (db *DB) FirstDerivedFromInRange(fromL1, toL1 uint64) ([]types.DerivedBlockSealPair, error) {
+	if fromL1 > toL1 {
+		return nil, fmt.Errorf("invalid L1 range [%d, %d]", fromL1, toL1)
+	}
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	last, err := db.latest()
+	if err != nil {
+		return nil, err
+	}
+	if toL1 > last.derivedFrom.Number {
+		return nil, fmt.Errorf("derived-from %d is past latest %s: %w", toL1, last.derivedFrom, types.ErrFuture)
+	}
+	var out []types.DerivedBlockSealPair
+	for n := fromL1; n <= toL1; n++ {
+		_, link, err := db.firstDerivedAt(n)
+		if errors.Is(err, types.ErrSkipped) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to find first derived at %d: %w", n, err)
+		}
+		if link.invalidated {
+			continue
+		}
+		out = append(out, types.DerivedBlockSealPair{DerivedFrom: link.derivedFrom, Derived: link.derived})
+	}
+	return out, nil
+}
+
+// ForEachDerivedFrom calls fn with each L2 block derived from the L1 block with the given number, in order.
+// An L1 block that did not derive a new L2 block repeats the last L2 block.
+// Iteration stops at the first error returned by fn, and that error is returned.
+// Returns types.ErrFuture if the L1 block is past the latest derived-from block,
+// and types.ErrSkipped if no entry was derived from the L1 block.
+// This returns types.ErrAwaitReplacementBlock, after visiting the valid entries,
+// if the last entry of the L1 block was invalidated and needs replacement.
+func (db *DB) ForEachDerivedFrom(derivedFrom uint64, fn func(derived types.BlockSeal) error) error {
+	links, err := db.linksDerivedAt(derivedFrom)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if link.invalidated {
+			return types.ErrAwaitReplacementBlock
+		}
+		if err := fn(link.derived); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linksDerivedAt returns the contiguous run of entries derived from the L1 block with the given number.
+// The entries are collected under the read lock, so callbacks can be called without holding it.
+func (db *DB) linksDerivedAt(derivedFrom uint64) ([]LinkEntry, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	i, link, err := db.firstDerivedAt(derivedFrom)
+	if err != nil {
+		return nil, err
+	}
+	links := []LinkEntry{link}
+	lastIndex := db.store.LastEntryIdx()
+	for i++; i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if link.derivedFrom.Number != derivedFrom {
+			break
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// NearestDerivedFrom returns the last entry with the greatest derived-from number that is at or below l1Number.
+// This supports approximate lookups, where the exact L1 block may not be known.
+// If l1Number is past the latest derived-from block, the latest entry is returned.
+// Returns types.ErrFuture if all entries are derived from newer L1 blocks,
+// and types.ErrNotFound if the DB has no entries, e.g. after pruning.
+// This may return types.ErrAwaitReplacementBlock if the entry was invalidated and needs replacement.
+func (db *DB) NearestDerivedFrom(l1Number uint64) (pair types.DerivedBlockSealPair, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if db.store.Size() == 0 {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("no entries to find derived-from %d in: %w", l1Number, types.ErrNotFound)
+	}
+	first, err := db.readAt(0)
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to read first derivation data: %w", err)
+	}
+	if l1Number < first.derivedFrom.Number {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("derived-from %d is before first %s: %w", l1Number, first.derivedFrom, types.ErrFuture)
+	}
+	// Reverse: prioritize the last entry at or below the given L1 number.
+	_, link, err := db.find(true, func(link LinkEntry) int {
+		if link.derivedFrom.Number > l1Number {
+			return -1
+		}
+		return 0
+	})
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to find nearest derived-from %d: %w", l1Number, err)
+	}
+	return link.sealOrErr()
+}
+
+// DerivedFromByHash finds the first entry where the L2 block with the given hash was derived,
+// for when only the L2 block hash is known, e.g. during reorg reconciliation.
+// The entries are not indexed by hash, so this scans the DB in full in the worst case: O(n) entry reads.
+// Returns types.ErrNotFound if no such L2 block is known.
+// This may return types.ErrAwaitReplacementBlock if the entry was invalidated and needs replacement.
+func (db *DB) DerivedFromByHash(derivedHash common.Hash) (pair types.DerivedBlockSealPair, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	for i := entrydb.EntryIdx(0); i <= db.store.LastEntryIdx(); i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return types.DerivedBlockSealPair{}, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		if link.derived.Hash == derivedHash {
+			return link.sealOrErr()
+		}
+	}
+	return types.DerivedBlockSealPair{}, fmt.Errorf("derived block %s: %w", derivedHash, types.ErrNotFound)
+}
+
 // NextDerived finds the next L2 block after derived, and what it was derived from.
 // This may return types.ErrAwaitReplacementBlock if the entry was invalidated and needs replacement.
 func (db *DB) NextDerived(derived eth.BlockID) (pair types.DerivedBlockSealPair, err error) {
@@ -199,6 +977,25 @@ func (db *DB) IsDerived(derived eth.BlockID) error {
 	return nil
 }
 
+// ContainsDerivedPair checks if the given L2 block was derived from the given L1 block, by both number and hash.
+// Unlike IsDerived, this checks the specific L1 block, which may be any of the L1 blocks that repeated the L2 block.
+// This returns false if there is no such entry, if the hashes do not match, or if the entry was invalidated.
+// This returns an ErrFuture if the pair is past the latest entry.
+func (db *DB) ContainsDerivedPair(derivedFrom, derived eth.BlockID) (bool, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	_, link, err := db.lookup(derivedFrom.Number, derived.Number)
+	if errors.Is(err, types.ErrSkipped) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if link.invalidated {
+		return false, nil
+	}
+	return link.derivedFrom.ID() == derivedFrom && link.derived.ID() == derived, nil
+}
+
 // DerivedFrom determines where a L2 block was first derived from.
 // (a L2 block may repeat if the following L1 blocks are empty and don't produce additional L2 blocks)
 func (db *DB) DerivedFrom(derived eth.BlockID) (derivedFrom types.BlockSeal, err error) {
@@ -300,13 +1097,18 @@ func (db *DB) firstDerivedFrom(derived uint64) (entrydb.EntryIdx, LinkEntry, err
 }
 
 func (db *DB) lookup(derivedFrom, derived uint64) (entrydb.EntryIdx, LinkEntry, error) {
-	return db.find(false, func(link LinkEntry) int {
+	return db.find(false, lookupCmp(derivedFrom, derived))
+}
+
+// lookupCmp orders entries by derived block, then by derived-from block, for lookup.
+func lookupCmp(derivedFrom, derived uint64) func(link LinkEntry) int {
+	return func(link LinkEntry) int {
 		res := cmp.Compare(link.derived.Number, derived)
 		if res == 0 {
 			return cmp.Compare(link.derivedFrom.Number, derivedFrom)
 		}
 		return res
-	})
+	}
 }
 
 func (db *DB) lastDerivedAt(derivedFrom uint64) (entrydb.EntryIdx, LinkEntry, error) {
@@ -326,7 +1128,12 @@ func (db *DB) firstDerivedAt(derivedFrom uint64) (entrydb.EntryIdx, LinkEntry, e
 // The cmpFn entries to the left should return -1, entries to the right 1.
 // If reverse, the cmpFn should be flipped too, and the last entry for which cmpFn(link) is 0 will be found.
 func (db *DB) find(reverse bool, cmpFn func(link LinkEntry) int) (entrydb.EntryIdx, LinkEntry, error) {
-	n := db.store.Size()
+	return findIn(db.store.Size(), db.readAt, reverse, cmpFn)
+}
+
+// findIn is like find, but searches the first n entries, as read with readAt.
+func findIn(n int64, readAt func(i entrydb.EntryIdx) (LinkEntry, error),
+	reverse bool, cmpFn func(link LinkEntry) int) (entrydb.EntryIdx, LinkEntry, error) {
 	if n == 0 {
 		return -1, LinkEntry{}, types.ErrFuture
 	}
@@ -337,7 +1144,7 @@ func (db *DB) find(reverse bool, cmpFn func(link LinkEntry) int) (entrydb.EntryI
 		if reverse {
 			at = entrydb.EntryIdx(n) - 1 - at
 		}
-		entry, err := db.readAt(at)
+		entry, err := readAt(at)
 		if err != nil {
 			searchErr = err
 			return false
@@ -357,7 +1164,7 @@ func (db *DB) find(reverse bool, cmpFn func(link LinkEntry) int) (entrydb.EntryI
 	if reverse {
 		result = int(n) - 1 - result
 	}
-	link, err := db.readAt(entrydb.EntryIdx(result))
+	link, err := readAt(entrydb.EntryIdx(result))
 	if err != nil {
 		return -1, LinkEntry{}, fmt.Errorf("failed to read final result entry %d: %w", result, err)
 	}
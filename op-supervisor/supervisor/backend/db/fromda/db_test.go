@@ -1,10 +1,14 @@
 package fromda
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"math/rand" // nosemgrep
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -15,6 +19,7 @@ import (
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 )
 
@@ -713,6 +718,268 @@ func TestRewind(t *testing.T) {
 	})
 }
 
+// cancelAfterCtx is a context that is cancelled after its Err method was called n times.
+type cancelAfterCtx struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestRewindCtx(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+	target := types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(1)}
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref3))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		requireUnchanged := func() {
+			require.Equal(t, 4, db.Len())
+			pair, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, mockL1(3), pair.DerivedFrom)
+			require.Equal(t, mockL2(3), pair.Derived)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, db.RewindCtx(ctx, target, false), context.Canceled)
+		requireUnchanged()
+
+		// Cancel at every point of the lookup, up to and including the final check before truncating.
+		for n := 1; ; n++ {
+			ctx := &cancelAfterCtx{Context: context.Background(), n: n}
+			err := db.RewindCtx(ctx, target, false)
+			if err == nil {
+				break
+			}
+			require.ErrorIs(t, err, context.Canceled)
+			requireUnchanged()
+		}
+		pair, err := db.Latest()
+		require.NoError(t, err)
+		require.Equal(t, target, pair)
+		require.Equal(t, 2, db.Len())
+	})
+}
+
+func TestRewindVariantsCtx(t *testing.T) {
+	l1Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL1(i), mockL1(i-1).Hash)
+	}
+	l2Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL2(i), mockL2(i-1).Hash)
+	}
+	variants := map[string]func(ctx context.Context, db *DB) error{
+		"RewindToL1": func(ctx context.Context, db *DB) error {
+			return db.RewindToL1Ctx(ctx, 2)
+		},
+		"RewindToL2": func(ctx context.Context, db *DB) error {
+			return db.RewindToL2Ctx(ctx, 2)
+		},
+		"RewindToL1Timestamp": func(ctx context.Context, db *DB) error {
+			return db.RewindToL1TimestampCtx(ctx, mockL1(2).Timestamp)
+		},
+		"RewindToL2Timestamp": func(ctx context.Context, db *DB) error {
+			return db.RewindToL2TimestampCtx(ctx, mockL2(2).Timestamp)
+		},
+		"PruneBefore": func(ctx context.Context, db *DB) error {
+			_, err := db.PruneBeforeCtx(ctx, 2)
+			return err
+		},
+	}
+	for name, fn := range variants {
+		t.Run(name, func(t *testing.T) {
+			runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+				for i := uint64(1); i <= 4; i++ {
+					require.NoError(t, db.AddDerived(l1Ref(i), l2Ref(i)))
+				}
+			}, func(t *testing.T, db *DB, m *stubMetrics) {
+				requireUnchanged := func() {
+					require.Equal(t, 4, db.Len())
+					pair, err := db.First()
+					require.NoError(t, err)
+					require.Equal(t, mockL2(1), pair.Derived)
+					pair, err = db.Latest()
+					require.NoError(t, err)
+					require.Equal(t, mockL2(4), pair.Derived)
+				}
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				require.ErrorIs(t, fn(ctx, db), context.Canceled)
+				requireUnchanged()
+
+				// Cancel at every point, up to and including the final check before modifying the store.
+				for n := 1; ; n++ {
+					err := fn(&cancelAfterCtx{Context: context.Background(), n: n}, db)
+					if err == nil {
+						break
+					}
+					require.ErrorIs(t, err, context.Canceled)
+					requireUnchanged()
+				}
+				require.Less(t, db.Len(), 4)
+			})
+		})
+	}
+}
+
+func TestCrossRewind(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		// L2 block 2 is repeated, the rewind must drop all of its entries
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		// never cross-safe, nothing to rewind
+		future := types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: toRef(mockL2(3), mockL2(2).Hash)}
+		rewound, err := db.CrossRewind(future)
+		require.NoError(t, err)
+		require.False(t, rewound)
+		pair, err := db.Latest()
+		require.NoError(t, err)
+		require.Equal(t, mockL2(2), pair.Derived)
+
+		// a different block at the same height cannot be invalidated
+		other := l2Ref2
+		other.Hash = common.Hash{0xaa}
+		_, err = db.CrossRewind(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: other})
+		require.ErrorIs(t, err, types.ErrConflict)
+
+		rewound, err = db.CrossRewind(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2})
+		require.NoError(t, err)
+		require.True(t, rewound)
+		pair, err = db.Latest()
+		require.NoError(t, err)
+		require.Equal(t, mockL1(1), pair.DerivedFrom)
+		require.Equal(t, mockL2(1), pair.Derived)
+		require.Equal(t, int64(2), m.DBDerivedEntryCount)
+		_, err = db.Invalidated()
+		require.ErrorIs(t, err, types.ErrConflict, "no invalidation marker is added")
+
+		// the first entry anchors the DB, and cannot be rewound
+		_, err = db.CrossRewind(types.DerivedBlockRefPair{DerivedFrom: l1Ref0, Derived: l2Ref0})
+		require.ErrorIs(t, err, types.ErrPreviousToFirst)
+	})
+}
+
+func TestRewindToL1Timestamp(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		// two L2 blocks share the timestamp of L1 block 1
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref3))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		requireLatest := func(derivedFrom, derived types.BlockSeal) {
+			pair, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, derivedFrom, pair.DerivedFrom)
+			require.Equal(t, derived, pair.Derived)
+		}
+
+		// all entries are older, nothing changes
+		require.NoError(t, db.RewindToL1Timestamp(l1Ref3.Time+100))
+		requireLatest(mockL1(3), mockL2(3))
+
+		// in between L1 blocks 2 and 3
+		require.NoError(t, db.RewindToL1Timestamp(l1Ref2.Time+5))
+		requireLatest(mockL1(2), mockL2(2))
+
+		// exactly at L1 block 1, the last entry of the L1 block is kept
+		require.NoError(t, db.RewindToL1Timestamp(l1Ref1.Time))
+		requireLatest(mockL1(1), mockL2(2))
+		require.Equal(t, int64(3), m.DBDerivedEntryCount)
+
+		require.ErrorIs(t, db.RewindToL1Timestamp(l1Ref0.Time-1), types.ErrSkipped)
+		requireLatest(mockL1(1), mockL2(2))
+	})
+}
+
+func TestRewindToL2Timestamp(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l1Ref4 := toRef(mockL1(4), mockL1(3).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		// L1 blocks 2 and 3 are empty, and repeat L2 block 1, with the same timestamp
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref4, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref4, l2Ref3))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		requireLatest := func(derivedFrom, derived types.BlockSeal) {
+			pair, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, derivedFrom, pair.DerivedFrom)
+			require.Equal(t, derived, pair.Derived)
+		}
+
+		require.ErrorIs(t, db.RewindToL2Timestamp(l2Ref3.Time+1), types.ErrFuture)
+		requireLatest(mockL1(4), mockL2(3))
+
+		// exactly at L2 block 3
+		require.NoError(t, db.RewindToL2Timestamp(l2Ref3.Time))
+		requireLatest(mockL1(4), mockL2(3))
+
+		// in between L2 blocks 1 and 2, rewinds to L2 block 2
+		require.NoError(t, db.RewindToL2Timestamp(l2Ref1.Time+5))
+		requireLatest(mockL1(4), mockL2(2))
+
+		// exactly at the repeated L2 block 1, only the first entry is kept
+		require.NoError(t, db.RewindToL2Timestamp(l2Ref1.Time))
+		requireLatest(mockL1(1), mockL2(1))
+		require.Equal(t, int64(2), m.DBDerivedEntryCount)
+
+		// older than all entries, rewinds to the first entry
+		require.NoError(t, db.RewindToL2Timestamp(0))
+		requireLatest(mockL1(0), mockL2(0))
+	})
+}
+
 func TestInvalidateAndReplace(t *testing.T) {
 	l1Block0 := mockL1(0)
 	l1Block1 := mockL1(1)
@@ -758,6 +1025,20 @@ func TestInvalidateAndReplace(t *testing.T) {
 		require.Equal(t, invalidated.DerivedFrom.ID(), pair.DerivedFrom.ID())
 		require.Equal(t, invalidated.Derived.ID(), pair.Derived.ID())
 
+		// a replacement at the wrong height is rejected, and leaves the DB unchanged
+		size := db.store.Size()
+		for _, wrongHeight := range []uint64{l2Ref2.Number - 1, l2Ref2.Number + 1} {
+			wrong := l2Ref2
+			wrong.Hash = common.Hash{0xff, 0xff, 0xff}
+			wrong.Number = wrongHeight
+			_, err = db.ReplaceInvalidatedBlock(wrong, invalidated.Derived.Hash)
+			require.ErrorIs(t, err, types.ErrConflict)
+			require.Equal(t, size, db.store.Size())
+			pair, err = db.Invalidated()
+			require.NoError(t, err)
+			require.Equal(t, invalidated.Derived.ID(), pair.Derived.ID())
+		}
+
 		replacement := l2Ref2
 		replacement.Hash = common.Hash{0xff, 0xff, 0xff}
 		require.NotEqual(t, l2Ref2.Hash, replacement.Hash) // different L2 block as replacement
@@ -868,3 +1149,1520 @@ func TestInvalidateAndReplaceNonFirst(t *testing.T) {
 		require.ErrorIs(t, db.IsDerived(l2Ref3.ID()), types.ErrConflict, "invalidated block is not valid in canonical chain")
 	})
 }
+
+// TestLookupsReturnCopies checks that modifying a lookup result does not affect the DB.
+func TestLookupsReturnCopies(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l2Block0 := mockL2(0)
+	l2Block1 := mockL2(1)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(toRef(l1Block0, common.Hash{}), toRef(l2Block0, common.Hash{})))
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(l2Block1, l2Block0.Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		pair, err := db.Latest()
+		require.NoError(t, err)
+		pair.Derived.Hash = common.Hash{0xaa}
+		pair.DerivedFrom.Number = 100
+		pair, err = db.Latest()
+		require.NoError(t, err)
+		require.Equal(t, l1Block1, pair.DerivedFrom)
+		require.Equal(t, l2Block1, pair.Derived)
+
+		first, err := db.First()
+		require.NoError(t, err)
+		first.Derived.Timestamp = 0
+		first, err = db.First()
+		require.NoError(t, err)
+		require.Equal(t, l2Block0, first.Derived)
+
+		derived, err := db.LastDerivedAt(l1Block1.ID())
+		require.NoError(t, err)
+		derived.Hash = common.Hash{0xbb}
+		derived, err = db.LastDerivedAt(l1Block1.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block1, derived)
+
+		derivedFrom, err := db.DerivedFrom(l2Block1.ID())
+		require.NoError(t, err)
+		derivedFrom.Hash = common.Hash{0xcc}
+		derivedFrom, err = db.DerivedFrom(l2Block1.ID())
+		require.NoError(t, err)
+		require.Equal(t, l1Block1, derivedFrom)
+	})
+}
+
+func TestNearestDerivedFrom(t *testing.T) {
+	l1Block5 := mockL1(5)
+	l1Block6 := mockL1(6)
+	l1Block7 := mockL1(7)
+	l1Block8 := mockL1(8)
+
+	l2Block0 := mockL2(0)
+	l2Block1 := mockL2(1)
+	l2Block2 := mockL2(2)
+	l2Block3 := mockL2(3)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {}, func(t *testing.T, db *DB, m *stubMetrics) {
+		_, err := db.NearestDerivedFrom(l1Block5.Number)
+		require.ErrorIs(t, err, types.ErrNotFound)
+	})
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(toRef(l1Block5, mockL1(4).Hash), toRef(l2Block0, common.Hash{})))
+		// multiple L2 blocks derived from the same L1 block
+		require.NoError(t, db.AddDerived(toRef(l1Block6, l1Block5.Hash), toRef(l2Block1, l2Block0.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block6, l1Block5.Hash), toRef(l2Block2, l2Block1.Hash)))
+		// empty L1 block, repeating the last L2 block
+		require.NoError(t, db.AddDerived(toRef(l1Block7, l1Block6.Hash), toRef(l2Block2, l2Block1.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block8, l1Block7.Hash), toRef(l2Block3, l2Block2.Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		// exact, first entry
+		pair, err := db.NearestDerivedFrom(l1Block5.Number)
+		require.NoError(t, err)
+		require.Equal(t, l1Block5, pair.DerivedFrom)
+		require.Equal(t, l2Block0, pair.Derived)
+
+		// exact, the last of multiple L2 blocks is returned
+		pair, err = db.NearestDerivedFrom(l1Block6.Number)
+		require.NoError(t, err)
+		require.Equal(t, l1Block6, pair.DerivedFrom)
+		require.Equal(t, l2Block2, pair.Derived)
+
+		// exact, empty L1 block
+		pair, err = db.NearestDerivedFrom(l1Block7.Number)
+		require.NoError(t, err)
+		require.Equal(t, l1Block7, pair.DerivedFrom)
+		require.Equal(t, l2Block2, pair.Derived)
+
+		// exact, tip
+		pair, err = db.NearestDerivedFrom(l1Block8.Number)
+		require.NoError(t, err)
+		require.Equal(t, l1Block8, pair.DerivedFrom)
+		require.Equal(t, l2Block3, pair.Derived)
+
+		// below the first entry
+		_, err = db.NearestDerivedFrom(4)
+		require.ErrorIs(t, err, types.ErrFuture)
+
+		// above the tip
+		pair, err = db.NearestDerivedFrom(9)
+		require.NoError(t, err)
+		require.Equal(t, l1Block8, pair.DerivedFrom)
+		require.Equal(t, l2Block3, pair.Derived)
+	})
+}
+
+func TestThroughput(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l1Block2 := mockL1(2)
+	l1Block3 := mockL1(3)
+	l1Block4 := mockL1(4)
+	l1Block5 := mockL1(5)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		_, err := db.Throughput(1)
+		require.ErrorIs(t, err, types.ErrFuture)
+
+		require.NoError(t, db.AddDerived(toRef(l1Block0, common.Hash{}), toRef(mockL2(0), common.Hash{})))
+		// two L2 blocks in one L1 block
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(mockL2(1), mockL2(0).Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(mockL2(2), mockL2(1).Hash)))
+		// two empty L1 blocks
+		require.NoError(t, db.AddDerived(toRef(l1Block2, l1Block1.Hash), toRef(mockL2(2), mockL2(1).Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block3, l1Block2.Hash), toRef(mockL2(2), mockL2(1).Hash)))
+		// three L2 blocks in one L1 block
+		require.NoError(t, db.AddDerived(toRef(l1Block4, l1Block3.Hash), toRef(mockL2(3), mockL2(2).Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block4, l1Block3.Hash), toRef(mockL2(4), mockL2(3).Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block4, l1Block3.Hash), toRef(mockL2(5), mockL2(4).Hash)))
+		// empty L1 block at the tip
+		require.NoError(t, db.AddDerived(toRef(l1Block5, l1Block4.Hash), toRef(mockL2(5), mockL2(4).Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		_, err := db.Throughput(0)
+		require.Error(t, err)
+
+		// window of L1 blocks, to the expected ratio
+		for window, expected := range map[uint64]float64{
+			1: 0,    // only the empty tip
+			2: 1.5,  // 3 L2 blocks over L1 blocks 4, 5
+			3: 1,    // 3 L2 blocks over L1 blocks 3, 4, 5
+			4: 0.75, // 3 L2 blocks over L1 blocks 2, 3, 4, 5
+			5: 1,    // 5 L2 blocks over L1 blocks 1 to 5
+			6: 1,    // 6 L2 blocks, including the first, over all L1 blocks
+		} {
+			got, err := db.Throughput(window)
+			require.NoError(t, err)
+			require.Equal(t, expected, got, "window %d", window)
+		}
+
+		_, err = db.Throughput(7)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestCopyTo(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l1Block2 := mockL1(2)
+
+	l1Ref0 := toRef(l1Block0, common.Hash{})
+	l1Ref1 := toRef(l1Block1, l1Block0.Hash)
+	l1Ref2 := toRef(l1Block2, l1Block1.Hash)
+
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		// the tail is an invalidation entry
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{
+			DerivedFrom: l1Ref2,
+			Derived:     l2Ref2,
+		}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.ErrorIs(t, db.CopyTo(db), types.ErrConflict)
+
+		dstPath := filepath.Join(t.TempDir(), "copy.db")
+		dstMetrics := &stubMetrics{}
+		dst, err := NewFromFile(testlog.Logger(t, log.LvlInfo), dstMetrics, dstPath)
+		require.NoError(t, err)
+		require.NoError(t, db.CopyTo(dst))
+		require.Equal(t, m.DBDerivedEntryCount, dstMetrics.DBDerivedEntryCount)
+
+		// the copy has the exact same entries
+		requireSameEntries := func(t *testing.T, a, b *DB) {
+			require.Equal(t, a.store.Size(), b.store.Size())
+			for i := entrydb.EntryIdx(0); i <= a.store.LastEntryIdx(); i++ {
+				x, err := a.store.Read(i)
+				require.NoError(t, err)
+				y, err := b.store.Read(i)
+				require.NoError(t, err)
+				require.Equal(t, x, y, "entry %d", i)
+			}
+		}
+		requireSameEntries(t, db, dst)
+
+		_, err = dst.Latest()
+		require.ErrorIs(t, err, types.ErrAwaitReplacementBlock)
+		pair, err := dst.Invalidated()
+		require.NoError(t, err)
+		require.Equal(t, l1Block2.ID(), pair.DerivedFrom.ID())
+		require.Equal(t, l2Ref2.ID(), pair.Derived.ID())
+
+		// cannot copy into a DB that already has data
+		require.ErrorIs(t, db.CopyTo(dst), types.ErrConflict)
+
+		// the copy persists
+		require.NoError(t, dst.Close())
+		checkDBInvariants(t, dstPath, dstMetrics)
+		dst, err = NewFromFile(testlog.Logger(t, log.LvlInfo), dstMetrics, dstPath)
+		require.NoError(t, err)
+		requireSameEntries(t, db, dst)
+		require.NoError(t, dst.Close())
+	})
+}
+
+func TestCopyToInconsistent(t *testing.T) {
+	logger := testlog.Logger(t, log.LvlInfo)
+	// the source skips L2 block 1, which was never checked, since the entries were written to the store directly
+	store := &entrydb.MemEntryStore[EntryType, Entry]{}
+	first := LinkEntry{derivedFrom: mockL1(0), derived: mockL2(0)}
+	skipped := LinkEntry{derivedFrom: mockL1(1), derived: mockL2(2)}
+	require.NoError(t, store.Append(first.encode(), skipped.encode()))
+	db, err := NewFromEntryStore(logger, &stubMetrics{}, store)
+	require.NoError(t, err)
+	dstMetrics := &stubMetrics{}
+	dst, err := NewFromEntryStore(logger, dstMetrics, &entrydb.MemEntryStore[EntryType, Entry]{})
+	require.NoError(t, err)
+
+	require.Error(t, db.CopyTo(dst))
+	require.Equal(t, 0, dst.Len())
+	require.Equal(t, int64(0), dstMetrics.DBDerivedEntryCount)
+}
+
+func TestCopyToConcurrent(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		return db
+	}
+	// Copies in opposite directions between the same DBs must not deadlock:
+	// only one of them can succeed, since the other DB is no longer empty.
+	for i := 0; i < 100; i++ {
+		a, b := newDB(t), newDB(t)
+		require.NoError(t, a.AddDerived(toRef(mockL1(0), common.Hash{}), toRef(mockL2(0), common.Hash{})))
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, dbs := range [][2]*DB{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[j] = dbs[0].CopyTo(dbs[1])
+			}()
+		}
+		wg.Wait()
+		require.NoError(t, errs[0])
+		require.ErrorIs(t, errs[1], types.ErrConflict)
+	}
+}
+
+func TestInvalidationsSince(t *testing.T) {
+	t.Run("invalidated tail", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			_, err := db.InvalidationsSince(0)
+			require.ErrorIs(t, err, types.ErrFuture)
+
+			require.NoError(t, db.AddDerived(toRef(mockL1(0), common.Hash{}), toRef(mockL2(0), common.Hash{})))
+			require.NoError(t, db.AddDerived(toRef(mockL1(1), mockL1(0).Hash), toRef(mockL2(1), mockL2(0).Hash)))
+			require.NoError(t, db.AddDerived(toRef(mockL1(2), mockL1(1).Hash), toRef(mockL2(2), mockL2(1).Hash)))
+			require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{
+				DerivedFrom: toRef(mockL1(2), mockL1(1).Hash),
+				Derived:     toRef(mockL2(2), mockL2(1).Hash),
+			}))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			for l1, expected := range []int{1, 1, 1} {
+				count, err := db.InvalidationsSince(uint64(l1))
+				require.NoError(t, err)
+				require.Equal(t, expected, count, "since L1 %d", l1)
+			}
+			_, err := db.InvalidationsSince(3)
+			require.ErrorIs(t, err, types.ErrFuture)
+		})
+	})
+
+	t.Run("history", func(t *testing.T) {
+		// Invalidated entries are normally replaced, so write a history that retains them directly.
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		links := []LinkEntry{
+			{derivedFrom: mockL1(5), derived: mockL2(10)},
+			{derivedFrom: mockL1(6), derived: mockL2(11), invalidated: true},
+			{derivedFrom: mockL1(6), derived: mockL2(12)},
+			{derivedFrom: mockL1(7), derived: mockL2(12)},
+			{derivedFrom: mockL1(8), derived: mockL2(13), invalidated: true},
+			{derivedFrom: mockL1(8), derived: mockL2(14), invalidated: true},
+			{derivedFrom: mockL1(9), derived: mockL2(15)},
+		}
+		for _, link := range links {
+			require.NoError(t, store.Append(link.encode()))
+		}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+
+		for l1, expected := range map[uint64]int{
+			0: 3, // before the first entry
+			5: 3,
+			6: 3,
+			7: 2,
+			8: 2,
+			9: 0,
+		} {
+			count, err := db.InvalidationsSince(l1)
+			require.NoError(t, err)
+			require.Equal(t, expected, count, "since L1 %d", l1)
+		}
+		_, err = db.InvalidationsSince(10)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestDerivedFromByHash(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l1Block2 := mockL1(2)
+	l1Block3 := mockL1(3)
+
+	l2Block0 := mockL2(0)
+	l2Block1 := mockL2(1)
+	l2Block2 := mockL2(2)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(toRef(l1Block0, common.Hash{}), toRef(l2Block0, common.Hash{})))
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(l2Block1, l2Block0.Hash)))
+		// L2 block 1 repeats across empty L1 blocks
+		require.NoError(t, db.AddDerived(toRef(l1Block2, l1Block1.Hash), toRef(l2Block1, l2Block0.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block3, l1Block2.Hash), toRef(l2Block1, l2Block0.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block3, l1Block2.Hash), toRef(l2Block2, l2Block1.Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		pair, err := db.DerivedFromByHash(l2Block2.Hash)
+		require.NoError(t, err)
+		require.Equal(t, l1Block3, pair.DerivedFrom)
+		require.Equal(t, l2Block2, pair.Derived)
+
+		// repeated: the first occurrence is returned
+		pair, err = db.DerivedFromByHash(l2Block1.Hash)
+		require.NoError(t, err)
+		require.Equal(t, l1Block1, pair.DerivedFrom)
+		require.Equal(t, l2Block1, pair.Derived)
+
+		_, err = db.DerivedFromByHash(common.Hash{0xba, 0xd})
+		require.ErrorIs(t, err, types.ErrNotFound)
+	})
+}
+
+func TestFirstDerivedAt(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l1Block2 := mockL1(2)
+
+	l2Block0 := mockL2(0)
+	l2Block1 := mockL2(1)
+	l2Block2 := mockL2(2)
+	l2Block3 := mockL2(3)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(toRef(l1Block0, common.Hash{}), toRef(l2Block0, common.Hash{})))
+		// multiple L2 blocks derived from L1 block 1
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(l2Block1, l2Block0.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(l2Block2, l2Block1.Hash)))
+		require.NoError(t, db.AddDerived(toRef(l1Block1, l1Block0.Hash), toRef(l2Block3, l2Block2.Hash)))
+		// empty L1 block 2
+		require.NoError(t, db.AddDerived(toRef(l1Block2, l1Block1.Hash), toRef(l2Block3, l2Block2.Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		// single-block derivation: first and last are the same
+		derived, err := db.FirstDerivedAt(l1Block0.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block0, derived)
+		derived, err = db.LastDerivedAt(l1Block0.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block0, derived)
+
+		// multi-block derivation
+		derived, err = db.FirstDerivedAt(l1Block1.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block1, derived)
+		derived, err = db.LastDerivedAt(l1Block1.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block3, derived)
+
+		derived, err = db.FirstDerivedAt(l1Block2.ID())
+		require.NoError(t, err)
+		require.Equal(t, l2Block3, derived)
+
+		_, err = db.FirstDerivedAt(eth.BlockID{Hash: common.Hash{0xba, 0xd}, Number: l1Block1.Number})
+		require.ErrorIs(t, err, types.ErrConflict)
+
+		_, err = db.FirstDerivedAt(mockL1(3).ID())
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestLastValidBeforeInvalidation(t *testing.T) {
+	l1Block0 := mockL1(0)
+	l1Block1 := mockL1(1)
+	l1Block2 := mockL1(2)
+
+	l1Ref0 := toRef(l1Block0, common.Hash{})
+	l1Ref1 := toRef(l1Block1, l1Block0.Hash)
+	l1Ref2 := toRef(l1Block2, l1Block1.Hash)
+
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		_, err := db.LastValidBeforeInvalidation()
+		require.ErrorIs(t, err, types.ErrFuture)
+
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+
+		_, err = db.LastValidBeforeInvalidation()
+		require.ErrorIs(t, err, types.ErrConflict, "tail is not invalidated")
+
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{
+			DerivedFrom: l1Ref2,
+			Derived:     l2Ref2,
+		}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		pair, err := db.LastValidBeforeInvalidation()
+		require.NoError(t, err)
+		require.Equal(t, l1Block1, pair.DerivedFrom)
+		require.Equal(t, mockL2(1), pair.Derived)
+	})
+}
+
+func TestVerifyLinksTo(t *testing.T) {
+	l1Block3 := mockL1(3)
+	l2Block3 := mockL2(3)
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		err := db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(2)})
+		require.ErrorIs(t, err, types.ErrFuture)
+
+		// the DB is a shard that continues from a prior shard, at L1 block 3
+		require.NoError(t, db.AddDerived(toRef(l1Block3, mockL1(2).Hash), toRef(l2Block3, mockL2(2).Hash)))
+		require.NoError(t, db.AddDerived(toRef(mockL1(4), l1Block3.Hash), toRef(mockL2(4), l2Block3.Hash)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		// both relations continue to the next block
+		require.NoError(t, db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(2)}))
+		// same L1 block, next L2 block
+		require.NoError(t, db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: l1Block3, Derived: mockL2(2)}))
+		// next L1 block, same L2 block
+		require.NoError(t, db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: l2Block3}))
+
+		// gap between the shards
+		err := db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(2)})
+		require.ErrorIs(t, err, types.ErrOutOfOrder)
+		err = db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(1)})
+		require.ErrorIs(t, err, types.ErrOutOfOrder)
+		// parent tail is ahead of the shard
+		err = db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(4), Derived: mockL2(2)})
+		require.ErrorIs(t, err, types.ErrOutOfOrder)
+
+		// same height, but a different block
+		otherL1 := l1Block3
+		otherL1.Hash = crypto.Keccak256Hash([]byte("other L1 block 3"))
+		err = db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: otherL1, Derived: mockL2(2)})
+		require.ErrorIs(t, err, types.ErrConflict)
+		otherL2 := l2Block3
+		otherL2.Hash = crypto.Keccak256Hash([]byte("other L2 block 3"))
+		err = db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: otherL2})
+		require.ErrorIs(t, err, types.ErrConflict)
+
+		// the shard overlaps with the parent tail
+		err = db.VerifyLinksTo(types.DerivedBlockSealPair{DerivedFrom: l1Block3, Derived: l2Block3})
+		require.ErrorIs(t, err, types.ErrConflict)
+	})
+}
+
+func TestStoreVersion(t *testing.T) {
+	newDB := func(t *testing.T, entries ...Entry) *DB {
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		for _, e := range entries {
+			require.NoError(t, store.Append(e))
+		}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+		return db
+	}
+	link := LinkEntry{derivedFrom: mockL1(1), derived: mockL2(2)}
+	invalidated := LinkEntry{derivedFrom: mockL1(2), derived: mockL2(3), invalidated: true}
+
+	t.Run("empty", func(t *testing.T) {
+		db := newDB(t)
+		version, err := db.StoreVersion()
+		require.NoError(t, err)
+		require.Equal(t, LatestVersion, version)
+		require.NoError(t, db.MigrateTo(LatestVersion))
+	})
+	t.Run("seeded", func(t *testing.T) {
+		db := newDB(t, link.encode(), invalidated.encode())
+		version, err := db.StoreVersion()
+		require.NoError(t, err)
+		require.Equal(t, uint8(0), version)
+		require.NoError(t, db.MigrateTo(0))
+		require.Error(t, db.MigrateTo(LatestVersion+1))
+	})
+	t.Run("unknown entry type", func(t *testing.T) {
+		e := link.encode()
+		e[0] = 0xff
+		db := newDB(t, e)
+		_, err := db.StoreVersion()
+		require.ErrorIs(t, err, types.ErrDataCorruption)
+		require.ErrorIs(t, db.MigrateTo(LatestVersion), types.ErrDataCorruption)
+	})
+}
+
+func TestMergeInto(t *testing.T) {
+	// newShard creates a DB with one L2 block derived from each L1 block in the inclusive range.
+	newShard := func(t *testing.T, first, last uint64) *DB {
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+		for i := first; i <= last; i++ {
+			var l1Parent, l2Parent common.Hash
+			if i > 0 {
+				l1Parent, l2Parent = mockL1(i-1).Hash, mockL2(i-1).Hash
+			}
+			require.NoError(t, db.AddDerived(toRef(mockL1(i), l1Parent), toRef(mockL2(i), l2Parent)))
+		}
+		return db
+	}
+	requireTail := func(t *testing.T, db *DB, size int64, num uint64) {
+		require.Equal(t, size, db.store.Size())
+		pair, err := db.Latest()
+		require.NoError(t, err)
+		require.Equal(t, mockL1(num), pair.DerivedFrom)
+		require.Equal(t, mockL2(num), pair.Derived)
+	}
+
+	t.Run("clean merge", func(t *testing.T) {
+		dst := newShard(t, 0, 2)
+		src := newShard(t, 3, 5)
+		require.NoError(t, src.MergeInto(dst))
+		requireTail(t, dst, 6, 5)
+		derived, err := dst.LastDerivedAt(mockL1(4).ID())
+		require.NoError(t, err)
+		require.Equal(t, mockL2(4), derived)
+		// the source is left untouched
+		requireTail(t, src, 3, 5)
+	})
+	t.Run("into empty", func(t *testing.T) {
+		dst := newShard(t, 1, 0)
+		require.NoError(t, newShard(t, 3, 5).MergeInto(dst))
+		requireTail(t, dst, 3, 5)
+	})
+	t.Run("overlapping merge", func(t *testing.T) {
+		dst := newShard(t, 0, 3)
+		err := newShard(t, 2, 5).MergeInto(dst)
+		require.ErrorIs(t, err, types.ErrOutOfOrder)
+		requireTail(t, dst, 4, 3)
+		err = newShard(t, 3, 5).MergeInto(dst)
+		require.ErrorIs(t, err, types.ErrConflict)
+		requireTail(t, dst, 4, 3)
+	})
+	t.Run("non-linking merge", func(t *testing.T) {
+		dst := newShard(t, 0, 2)
+		err := newShard(t, 4, 5).MergeInto(dst)
+		require.ErrorIs(t, err, types.ErrOutOfOrder)
+		requireTail(t, dst, 3, 2)
+	})
+	t.Run("replaced block", func(t *testing.T) {
+		dst := newShard(t, 0, 2)
+		src := newShard(t, 3, 4)
+		l1Ref5 := toRef(mockL1(5), mockL1(4).Hash)
+		l2Ref4 := toRef(mockL2(4), mockL2(3).Hash)
+		require.NoError(t, src.AddDerived(l1Ref5, l2Ref4))
+		require.NoError(t, src.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref5, Derived: l2Ref4}))
+		replacement := l2Ref4
+		replacement.Hash = common.Hash{0xaa}
+		_, err := src.ReplaceInvalidatedBlock(replacement, l2Ref4.Hash)
+		require.NoError(t, err)
+		require.NoError(t, src.AddDerived(toRef(mockL1(6), mockL1(5).Hash), toRef(mockL2(5), replacement.Hash)))
+
+		require.NoError(t, src.MergeInto(dst))
+		require.Equal(t, int64(7), dst.store.Size())
+		latest, err := dst.Latest()
+		require.NoError(t, err)
+		require.Equal(t, types.DerivedBlockSealPair{DerivedFrom: mockL1(6), Derived: mockL2(5)}, latest)
+		derived, err := dst.LastDerivedAt(mockL1(5).ID())
+		require.NoError(t, err)
+		require.Equal(t, replacement.Hash, derived.Hash)
+		require.NoError(t, dst.Verify())
+		expected, err := src.ReplacementHistory()
+		require.NoError(t, err)
+		got, err := dst.ReplacementHistory()
+		require.NoError(t, err)
+		require.Equal(t, expected, got)
+	})
+	t.Run("self", func(t *testing.T) {
+		db := newShard(t, 0, 2)
+		require.ErrorIs(t, db.MergeInto(db), types.ErrConflict)
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		// Merges in opposite directions between the same DBs must not deadlock:
+		// only one of them can succeed, since the other DB does not link.
+		for i := 0; i < 100; i++ {
+			dst := newShard(t, 0, 2)
+			src := newShard(t, 3, 5)
+			var wg sync.WaitGroup
+			errs := make([]error, 2)
+			for j, dbs := range [][2]*DB{{src, dst}, {dst, src}} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[j] = dbs[0].MergeInto(dbs[1])
+				}()
+			}
+			wg.Wait()
+			require.NoError(t, errs[0])
+			require.Error(t, errs[1])
+			requireTail(t, dst, 6, 5)
+		}
+	})
+}
+
+func TestLatestDerivedRef(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+
+	l2Ref4 := toRef(mockL2(4), common.Hash{0x42})
+	l2Ref5 := toRef(mockL2(5), mockL2(4).Hash)
+	l2Ref6 := toRef(mockL2(6), mockL2(5).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		_, err := db.LatestDerivedRef()
+		require.ErrorIs(t, err, types.ErrFuture)
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		// the first entry has no known parent
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref4))
+		ref, err := db.LatestDerivedRef()
+		require.NoError(t, err)
+		require.Equal(t, toRef(mockL2(4), common.Hash{}), ref)
+
+		// the same L2 block, repeated with a new L1 block, still has no known parent
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref4))
+		ref, err = db.LatestDerivedRef()
+		require.NoError(t, err)
+		require.Equal(t, toRef(mockL2(4), common.Hash{}), ref)
+
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref5))
+		ref, err = db.LatestDerivedRef()
+		require.NoError(t, err)
+		require.Equal(t, l2Ref5, ref)
+
+		// repeated with a new L1 block, the parent is still found
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref5))
+		ref, err = db.LatestDerivedRef()
+		require.NoError(t, err)
+		require.Equal(t, l2Ref5, ref)
+
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref6))
+		ref, err = db.LatestDerivedRef()
+		require.NoError(t, err)
+		require.Equal(t, l2Ref6, ref)
+
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref3, Derived: l2Ref6}))
+		_, err = db.LatestDerivedRef()
+		require.ErrorIs(t, err, types.ErrAwaitReplacementBlock)
+	})
+}
+
+func TestReplacementHistory(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				records, err := db.ReplacementHistory()
+				require.NoError(t, err)
+				require.Empty(t, records)
+			})
+	})
+
+	t.Run("history", func(t *testing.T) {
+		// Replaced invalidation entries are normally removed, so write a history that retains them directly.
+		replacement := mockL2(11)
+		replacement.Hash = crypto.Keccak256Hash([]byte("replacement 11"))
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		links := []LinkEntry{
+			{derivedFrom: mockL1(5), derived: mockL2(10)},
+			{derivedFrom: mockL1(6), derived: mockL2(11), invalidated: true},
+			{derivedFrom: mockL1(6), derived: replacement},
+			{derivedFrom: mockL1(7), derived: mockL2(12)},
+			{derivedFrom: mockL1(8), derived: mockL2(13), invalidated: true},
+		}
+		for _, link := range links {
+			require.NoError(t, store.Append(link.encode()))
+		}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+
+		records, err := db.ReplacementHistory()
+		require.NoError(t, err)
+		require.Equal(t, []ReplacementRecord{
+			{
+				Invalidated: types.DerivedBlockSealPair{DerivedFrom: mockL1(6), Derived: mockL2(11)},
+				Replacement: &types.DerivedBlockSealPair{DerivedFrom: mockL1(6), Derived: replacement},
+			},
+			{
+				// still pending at the tail
+				Invalidated: types.DerivedBlockSealPair{DerivedFrom: mockL1(8), Derived: mockL2(13)},
+			},
+		}, records)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		l1Ref0 := toRef(mockL1(0), common.Hash{})
+		l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+		l2Ref0 := toRef(mockL2(0), common.Hash{})
+		l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+			require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+			require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref1, Derived: l2Ref1}))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			records, err := db.ReplacementHistory()
+			require.NoError(t, err)
+			require.Equal(t, []ReplacementRecord{
+				{Invalidated: types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(1)}},
+			}, records)
+		})
+	})
+}
+
+func TestAt(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.Equal(t, 3, db.Len())
+		for i, want := range []types.DerivedBlockSealPair{
+			{DerivedFrom: mockL1(0), Derived: mockL2(0)},
+			{DerivedFrom: mockL1(1), Derived: mockL2(1)},
+			{DerivedFrom: mockL1(2), Derived: mockL2(2)},
+		} {
+			pair, invalidated, err := db.At(i)
+			require.NoError(t, err)
+			require.Equal(t, want, pair, "entry %d", i)
+			require.Equal(t, i == 2, invalidated, "entry %d", i)
+		}
+
+		_, _, err := db.At(-1)
+		require.ErrorIs(t, err, types.ErrSkipped)
+		_, _, err = db.At(3)
+		require.ErrorIs(t, err, types.ErrFuture)
+		_, _, err = db.At(1000)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				_, _, err := db.At(0)
+				require.ErrorIs(t, err, types.ErrFuture)
+			})
+	})
+}
+
+func TestFindDivergence(t *testing.T) {
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+	l2Ref4 := toRef(mockL2(4), mockL2(3).Hash)
+
+	setup := func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref3))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref4))
+	}
+	forked := mockL2(3)
+	forked.Hash = crypto.Keccak256Hash([]byte("forked 3"))
+
+	t.Run("agreement", func(t *testing.T) {
+		runDBTest(t, setup, func(t *testing.T, db *DB, m *stubMetrics) {
+			idx, seal, err := db.FindDivergence([]eth.BlockID{mockL2(2).ID(), mockL2(3).ID(), mockL2(4).ID()})
+			require.NoError(t, err)
+			require.Equal(t, -1, idx)
+			require.Equal(t, types.BlockSeal{}, seal)
+
+			idx, _, err = db.FindDivergence(nil)
+			require.NoError(t, err)
+			require.Equal(t, -1, idx)
+		})
+	})
+	t.Run("divergence", func(t *testing.T) {
+		runDBTest(t, setup, func(t *testing.T, db *DB, m *stubMetrics) {
+			idx, seal, err := db.FindDivergence([]eth.BlockID{mockL2(2).ID(), forked.ID(), mockL2(4).ID()})
+			require.NoError(t, err)
+			require.Equal(t, 1, idx)
+			require.Equal(t, mockL2(3), seal)
+		})
+	})
+	t.Run("past tip", func(t *testing.T) {
+		runDBTest(t, setup, func(t *testing.T, db *DB, m *stubMetrics) {
+			idx, _, err := db.FindDivergence([]eth.BlockID{mockL2(3).ID(), mockL2(4).ID(), mockL2(5).ID(), forked.ID()})
+			require.NoError(t, err)
+			require.Equal(t, -1, idx)
+		})
+	})
+	t.Run("invalidated", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			setup(t, db, m)
+			require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref3, Derived: l2Ref4}))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			idx, seal, err := db.FindDivergence([]eth.BlockID{mockL2(3).ID(), mockL2(4).ID()})
+			require.NoError(t, err)
+			require.Equal(t, 1, idx)
+			require.Equal(t, mockL2(4), seal)
+		})
+	})
+	t.Run("before first", func(t *testing.T) {
+		runDBTest(t, setup, func(t *testing.T, db *DB, m *stubMetrics) {
+			_, _, err := db.FindDivergence([]eth.BlockID{mockL2(1).ID()})
+			require.ErrorIs(t, err, types.ErrSkipped)
+		})
+	})
+	t.Run("empty", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {}, func(t *testing.T, db *DB, m *stubMetrics) {
+			idx, _, err := db.FindDivergence([]eth.BlockID{mockL2(1).ID()})
+			require.NoError(t, err)
+			require.Equal(t, -1, idx)
+		})
+	})
+}
+
+func TestCommonAncestor(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		return db
+	}
+	// forkL2 is an alternative L2 block, that forks off from the regular L2 chain at the given block.
+	forkL2 := func(i, forkAt uint64) types.BlockSeal {
+		if i < forkAt {
+			return mockL2(i)
+		}
+		seal := mockL2(i)
+		seal.Hash = crypto.Keccak256Hash([]byte(fmt.Sprintf("fork at %d: L2 block %d", forkAt, i)))
+		return seal
+	}
+	// fill adds one L2 block per L1 block, from L1 block 0 up to and including the given L1 block.
+	fill := func(t *testing.T, db *DB, last uint64, l2 func(i uint64) types.BlockSeal) {
+		for i := uint64(0); i <= last; i++ {
+			var l1Parent, l2Parent common.Hash
+			if i > 0 {
+				l1Parent, l2Parent = mockL1(i-1).Hash, l2(i-1).Hash
+			}
+			require.NoError(t, db.AddDerived(toRef(mockL1(i), l1Parent), toRef(l2(i), l2Parent)))
+		}
+	}
+	pairAt := func(i uint64, l2 func(i uint64) types.BlockSeal) types.DerivedBlockSealPair {
+		return types.DerivedBlockSealPair{DerivedFrom: mockL1(i), Derived: l2(i)}
+	}
+	requireAncestor := func(t *testing.T, a, b *DB, want types.DerivedBlockSealPair) {
+		for _, dbs := range [][2]*DB{{a, b}, {b, a}} {
+			pair, ok, err := CommonAncestor(dbs[0], dbs[1])
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, want, pair)
+		}
+	}
+	requireNoAncestor := func(t *testing.T, a, b *DB) {
+		for _, dbs := range [][2]*DB{{a, b}, {b, a}} {
+			_, ok, err := CommonAncestor(dbs[0], dbs[1])
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+	}
+
+	t.Run("agreeing", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 20, mockL2)
+		fill(t, b, 20, mockL2)
+		requireAncestor(t, a, b, pairAt(20, mockL2))
+		// a DB agrees with itself
+		requireAncestor(t, a, a, pairAt(20, mockL2))
+	})
+
+	t.Run("one ahead", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 20, mockL2)
+		fill(t, b, 13, mockL2)
+		requireAncestor(t, a, b, pairAt(13, mockL2))
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		for _, forkAt := range []uint64{1, 7, 20} {
+			a, b := newDB(t), newDB(t)
+			fork := func(i uint64) types.BlockSeal { return forkL2(i, forkAt) }
+			fill(t, a, 20, mockL2)
+			fill(t, b, 25, fork)
+			requireAncestor(t, a, b, pairAt(forkAt-1, mockL2))
+		}
+	})
+
+	t.Run("diverged by invalidation", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 10, mockL2)
+		fill(t, b, 10, mockL2)
+		require.NoError(t, b.RewindAndInvalidate(types.DerivedBlockRefPair{
+			DerivedFrom: toRef(mockL1(10), mockL1(9).Hash),
+			Derived:     toRef(mockL2(10), mockL2(9).Hash),
+		}))
+		requireAncestor(t, a, b, pairAt(9, mockL2))
+	})
+
+	t.Run("pruned", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 20, mockL2)
+		fill(t, b, 20, func(i uint64) types.BlockSeal { return forkL2(i, 15) })
+		_, err := b.PruneBefore(5)
+		require.NoError(t, err)
+		requireAncestor(t, a, b, pairAt(14, mockL2))
+	})
+
+	t.Run("disjoint", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 10, mockL2)
+		fill(t, b, 10, func(i uint64) types.BlockSeal { return forkL2(i, 0) })
+		requireNoAncestor(t, a, b)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		requireNoAncestor(t, a, b)
+		fill(t, a, 3, mockL2)
+		requireNoAncestor(t, a, b)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		a, b := newDB(t), newDB(t)
+		fill(t, a, 20, mockL2)
+		fill(t, b, 20, mockL2)
+		// Comparisons in both directions, while writers lock the DBs, must not deadlock.
+		var wg sync.WaitGroup
+		for _, dbs := range [][2]*DB{{a, b}, {b, a}} {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					_, _, err := CommonAncestor(dbs[0], dbs[1])
+					require.NoError(t, err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					// repeating the last link is a no-op, but takes the write-lock
+					require.NoError(t, dbs[0].AddDerived(toRef(mockL1(20), mockL1(19).Hash), toRef(mockL2(20), mockL2(19).Hash)))
+				}
+			}()
+		}
+		wg.Wait()
+		requireAncestor(t, a, b, pairAt(20, mockL2))
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	newMemDB := func(t *testing.T) *DB {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		return db
+	}
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		fp, err := db.Fingerprint()
+		require.NoError(t, err)
+
+		empty, err := newMemDB(t).Fingerprint()
+		require.NoError(t, err)
+		require.NotEqual(t, empty, fp)
+
+		cp := newMemDB(t)
+		require.NoError(t, db.CopyTo(cp))
+		cpFp, err := cp.Fingerprint()
+		require.NoError(t, err)
+		require.Equal(t, fp, cpFp, "copies have the same fingerprint")
+
+		// the same history, written independently
+		other := newMemDB(t)
+		require.NoError(t, other.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, other.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, other.AddDerived(l1Ref2, l2Ref2))
+		otherFp, err := other.Fingerprint()
+		require.NoError(t, err)
+		require.Equal(t, fp, otherFp)
+
+		// only the invalidation bit of the last entry differs
+		require.NoError(t, cp.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}))
+		cpFp, err = cp.Fingerprint()
+		require.NoError(t, err)
+		require.NotEqual(t, fp, cpFp)
+
+		// the last entry is derived from a different L1 block
+		diff := newMemDB(t)
+		require.NoError(t, diff.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, diff.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, diff.AddDerived(l1Ref1, l2Ref2))
+		diffFp, err := diff.Fingerprint()
+		require.NoError(t, err)
+		require.NotEqual(t, fp, diffFp)
+	})
+}
+
+func TestEntriesSince(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+
+	pairs := []types.DerivedBlockRefPair{
+		{DerivedFrom: l1Ref0, Derived: l2Ref0},
+		{DerivedFrom: l1Ref1, Derived: l2Ref1},
+		{DerivedFrom: l1Ref1, Derived: l2Ref2},
+		{DerivedFrom: l1Ref2, Derived: l2Ref2},
+		{DerivedFrom: l1Ref3, Derived: l2Ref3},
+	}
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		for _, p := range pairs {
+			require.NoError(t, db.AddDerived(p.DerivedFrom, p.Derived))
+		}
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		got, invalidated, err := db.EntriesSince(-1)
+		require.NoError(t, err)
+		require.Equal(t, pairs, got)
+		require.Equal(t, []bool{false, false, false, false, false}, invalidated)
+
+		got, invalidated, err = db.EntriesSince(4)
+		require.NoError(t, err)
+		require.Empty(t, got, "at tail")
+		require.Empty(t, invalidated)
+
+		got, invalidated, err = db.EntriesSince(2)
+		require.NoError(t, err)
+		require.Equal(t, pairs[3:], got, "mid-history, with parents from before the index")
+		require.Equal(t, []bool{false, false}, invalidated)
+
+		_, _, err = db.EntriesSince(5)
+		require.ErrorIs(t, err, types.ErrFuture, "beyond tip")
+
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref3, Derived: l2Ref3}))
+		got, invalidated, err = db.EntriesSince(3)
+		require.NoError(t, err)
+		require.Equal(t, pairs[4:], got)
+		require.Equal(t, []bool{true}, invalidated)
+	})
+}
+
+func TestForEachDerivedFrom(t *testing.T) {
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+	l2Ref3 := toRef(mockL2(3), mockL2(2).Hash)
+
+	collect := func(db *DB, derivedFrom uint64) ([]types.BlockSeal, error) {
+		var out []types.BlockSeal
+		err := db.ForEachDerivedFrom(derivedFrom, func(derived types.BlockSeal) error {
+			out = append(out, derived)
+			return nil
+		})
+		return out, err
+	}
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		// the DB starts at L1 block 1
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref0))
+		// multiple L2 blocks derived from L1 block 2
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		// L1 block 3 is empty, and repeats the last L2 block
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref2))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		got, err := collect(db, 2)
+		require.NoError(t, err)
+		require.Equal(t, []types.BlockSeal{mockL2(1), mockL2(2)}, got)
+
+		got, err = collect(db, 3)
+		require.NoError(t, err)
+		require.Equal(t, []types.BlockSeal{mockL2(2)}, got)
+
+		_, err = collect(db, 4)
+		require.ErrorIs(t, err, types.ErrFuture)
+		_, err = collect(db, 0)
+		require.ErrorIs(t, err, types.ErrSkipped)
+
+		// stops at the first error
+		stop := errors.New("stop")
+		calls := 0
+		err = db.ForEachDerivedFrom(2, func(derived types.BlockSeal) error {
+			calls++
+			return stop
+		})
+		require.ErrorIs(t, err, stop)
+		require.Equal(t, 1, calls)
+
+		// L2 block 3 was derived from L1 block 3, but then invalidated
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref3))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref3, Derived: l2Ref3}))
+		got, err = collect(db, 3)
+		require.ErrorIs(t, err, types.ErrAwaitReplacementBlock)
+		require.Equal(t, []types.BlockSeal{mockL2(2)}, got, "valid entries are visited first")
+	})
+}
+
+func TestStats(t *testing.T) {
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref5 := toRef(mockL2(5), mockL2(4).Hash)
+	l2Ref6 := toRef(mockL2(6), mockL2(5).Hash)
+	l2Ref7 := toRef(mockL2(7), mockL2(6).Hash)
+
+	t.Run("empty", func(t *testing.T) {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		require.Zero(t, db.Len())
+		_, err = db.Stats()
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref5))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref6))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref7))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref7))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.Equal(t, 4, db.Len())
+		stats, err := db.Stats()
+		require.NoError(t, err)
+		require.Equal(t, DerivedStats{
+			Entries:          4,
+			FirstDerivedFrom: 1,
+			LastDerivedFrom:  3,
+			FirstDerived:     5,
+			LastDerived:      7,
+		}, stats)
+
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref7}))
+		require.Equal(t, 3, db.Len())
+		stats, err = db.Stats()
+		require.NoError(t, err)
+		require.Equal(t, DerivedStats{
+			Entries:          3,
+			FirstDerivedFrom: 1,
+			LastDerivedFrom:  2,
+			FirstDerived:     5,
+			LastDerived:      7,
+			TailInvalidated:  true,
+			Invalidated:      1,
+		}, stats)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	link := func(l1, l2 uint64) LinkEntry {
+		return LinkEntry{derivedFrom: mockL1(l1), derived: mockL2(l2)}
+	}
+	invalid := func(l LinkEntry) LinkEntry {
+		l.invalidated = true
+		return l
+	}
+	fromLinks := func(t *testing.T, links ...LinkEntry) *DB {
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		for _, l := range links {
+			require.NoError(t, store.Append(l.encode()))
+		}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+		return db
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+		require.NoError(t, fromLinks(t).Verify(), "empty")
+		require.NoError(t, fromLinks(t, link(0, 0), link(1, 1), link(1, 2), link(2, 2), link(3, 3)).Verify())
+		require.NoError(t, fromLinks(t, link(0, 0), link(1, 1), invalid(link(2, 2))).Verify(), "invalidated tail")
+
+		// a block invalidated in an empty L1 block, and then replaced
+		db := fromLinks(t, link(0, 0), link(1, 1), link(2, 1))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{
+			DerivedFrom: toRef(mockL1(2), mockL1(1).Hash),
+			Derived:     toRef(mockL2(1), mockL2(0).Hash),
+		}))
+		replacement := toRef(types.BlockSeal{Hash: common.Hash{0xaa}, Number: 1, Timestamp: mockL2(1).Timestamp}, mockL2(0).Hash)
+		_, err := db.ReplaceInvalidatedBlock(replacement, mockL2(1).Hash)
+		require.NoError(t, err)
+		require.NoError(t, db.Verify())
+	})
+
+	testCases := []struct {
+		name  string
+		links []LinkEntry
+		index int
+		err   error
+	}{
+		{name: "derived gap", links: []LinkEntry{link(0, 0), link(1, 1), link(2, 3)}, index: 2, err: types.ErrOutOfOrder},
+		{name: "derived conflict", links: []LinkEntry{link(0, 0), link(1, 1), link(1, 2), {derivedFrom: mockL1(1), derived: types.BlockSeal{Hash: common.Hash{0xaa}, Number: 2}}}, index: 3, err: types.ErrConflict},
+		{name: "derived-from gap", links: []LinkEntry{link(0, 0), link(2, 1)}, index: 1, err: types.ErrOutOfOrder},
+		{name: "derived-from reversed", links: []LinkEntry{link(0, 0), link(1, 1), link(0, 2)}, index: 2, err: types.ErrOutOfOrder},
+		{name: "derived-from conflict", links: []LinkEntry{link(0, 0), link(1, 1), {derivedFrom: types.BlockSeal{Hash: common.Hash{0xaa}, Number: 1}, derived: mockL2(2)}}, index: 2, err: types.ErrConflict},
+		{name: "repeated entry", links: []LinkEntry{link(0, 0), link(1, 1), link(1, 1)}, index: 2, err: types.ErrConflict},
+		{name: "invalidated before tail", links: []LinkEntry{link(0, 0), invalid(link(1, 1)), link(2, 2)}, index: 1, err: types.ErrDataCorruption},
+		{name: "invalidated first", links: []LinkEntry{invalid(link(0, 0))}, index: 0, err: types.ErrDataCorruption},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fromLinks(t, tc.links...).Verify()
+			require.ErrorIs(t, err, tc.err)
+			require.ErrorContains(t, err, fmt.Sprintf("entry %d ", tc.index))
+		})
+	}
+
+	t.Run("corrupt entry", func(t *testing.T) {
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		first, second := link(0, 0), link(1, 1)
+		e := second.encode()
+		e[0] = 0xff
+		require.NoError(t, store.Append(first.encode(), e))
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+		err = db.Verify()
+		require.ErrorIs(t, err, types.ErrDataCorruption)
+		require.ErrorContains(t, err, "entry 1:")
+	})
+}
+
+func TestContainsDerivedPair(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l1Ref3 := toRef(mockL1(3), mockL1(2).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		// L1 block 2 is empty, and repeats L2 block 1
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref3, l2Ref2))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		for _, pair := range []types.DerivedBlockRefPair{
+			{DerivedFrom: l1Ref0, Derived: l2Ref0},
+			{DerivedFrom: l1Ref1, Derived: l2Ref1},
+			{DerivedFrom: l1Ref2, Derived: l2Ref1},
+			{DerivedFrom: l1Ref3, Derived: l2Ref2},
+		} {
+			ok, err := db.ContainsDerivedPair(pair.DerivedFrom.ID(), pair.Derived.ID())
+			require.NoError(t, err)
+			require.True(t, ok, "contains %s", pair)
+		}
+
+		// not derived from that L1 block
+		ok, err := db.ContainsDerivedPair(l1Ref1.ID(), l2Ref2.ID())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// hash mismatches
+		ok, err = db.ContainsDerivedPair(l1Ref2.ID(), eth.BlockID{Hash: common.Hash{0xaa}, Number: 1})
+		require.NoError(t, err)
+		require.False(t, ok)
+		ok, err = db.ContainsDerivedPair(eth.BlockID{Hash: common.Hash{0xaa}, Number: 2}, l2Ref1.ID())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, err = db.ContainsDerivedPair(mockL1(4).ID(), l2Ref2.ID())
+		require.ErrorIs(t, err, types.ErrFuture)
+		_, err = db.ContainsDerivedPair(mockL1(4).ID(), mockL2(3).ID())
+		require.ErrorIs(t, err, types.ErrFuture)
+
+		// an invalidated entry is not contained
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref3, Derived: l2Ref2}))
+		ok, err = db.ContainsDerivedPair(l1Ref3.ID(), l2Ref2.ID())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
+
+func TestInvalidationHook(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	var db *DB
+	var calls []types.DerivedBlockRefPair
+	hook := func(invalidated types.DerivedBlockRefPair) {
+		// the DB is not locked anymore
+		_, err := db.Invalidated()
+		require.NoError(t, err)
+		calls = append(calls, invalidated)
+	}
+	db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{},
+		&entrydb.MemEntryStore[EntryType, Entry]{}, WithInvalidationHook(hook))
+	require.NoError(t, err)
+	require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+	require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+	require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+	require.Empty(t, calls)
+
+	// an invalidation of an unknown block fails, and is not reported
+	unknown := types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: toRef(mockL2(3), mockL2(2).Hash)}
+	require.Error(t, db.RewindAndInvalidate(unknown))
+	require.Empty(t, calls)
+
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}
+	require.NoError(t, db.RewindAndInvalidate(invalidated))
+	require.Equal(t, []types.DerivedBlockRefPair{invalidated}, calls)
+
+	// replacing the block is not an invalidation
+	replacement := toRef(types.BlockSeal{Hash: common.Hash{0xaa}, Number: 2, Timestamp: mockL2(2).Timestamp}, mockL2(1).Hash)
+	_, err = db.ReplaceInvalidatedBlock(replacement, l2Ref2.Hash)
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestInvalidationHookMergeImport(t *testing.T) {
+	l1Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL1(i), mockL1(i-1).Hash)
+	}
+	l2Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL2(i), mockL2(i-1).Hash)
+	}
+	newDB := func(t *testing.T, opts ...Option) *DB {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{},
+			&entrydb.MemEntryStore[EntryType, Entry]{}, opts...)
+		require.NoError(t, err)
+		return db
+	}
+	// src ends with an invalidated block, that was not reported to the hook of the DBs it is copied into
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: l1Ref(4), Derived: l2Ref(4)}
+	newSrc := func(t *testing.T) *DB {
+		src := newDB(t)
+		for i := uint64(3); i <= 4; i++ {
+			require.NoError(t, src.AddDerived(l1Ref(i), l2Ref(i)))
+		}
+		require.NoError(t, src.RewindAndInvalidate(invalidated))
+		return src
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		var calls []types.DerivedBlockRefPair
+		dst := newDB(t, WithInvalidationHook(func(invalidated types.DerivedBlockRefPair) {
+			calls = append(calls, invalidated)
+		}))
+		require.NoError(t, dst.AddDerived(toRef(mockL1(2), mockL1(1).Hash), toRef(mockL2(2), mockL2(1).Hash)))
+		require.NoError(t, newSrc(t).MergeInto(dst))
+		require.Equal(t, []types.DerivedBlockRefPair{invalidated}, calls)
+		_, err := dst.Invalidated()
+		require.NoError(t, err)
+	})
+	t.Run("failed merge", func(t *testing.T) {
+		var calls []types.DerivedBlockRefPair
+		dst := newDB(t, WithInvalidationHook(func(invalidated types.DerivedBlockRefPair) {
+			calls = append(calls, invalidated)
+		}))
+		// dst does not link to src, so nothing is merged
+		require.NoError(t, dst.AddDerived(toRef(mockL1(1), mockL1(0).Hash), toRef(mockL2(1), mockL2(0).Hash)))
+		require.Error(t, newSrc(t).MergeInto(dst))
+		require.Empty(t, calls)
+	})
+	t.Run("import", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, newSrc(t).Export(&buf))
+		var calls []types.DerivedBlockRefPair
+		dst := newDB(t, WithInvalidationHook(func(invalidated types.DerivedBlockRefPair) {
+			calls = append(calls, invalidated)
+		}))
+		require.NoError(t, dst.Import(&buf, false))
+		require.Equal(t, []types.DerivedBlockRefPair{invalidated}, calls)
+	})
+}
+
+func TestFirstDerivedWhere(t *testing.T) {
+	l1Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL1(i), mockL1(i-1).Hash)
+	}
+	l2Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL2(i), mockL2(i-1).Hash)
+	}
+	atLeast := func(num uint64) func(derived types.BlockSeal) bool {
+		return func(derived types.BlockSeal) bool {
+			return derived.Number >= num
+		}
+	}
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref(1), l2Ref(1)))
+		require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(2)))
+		// L1 block 3 is empty, and repeats L2 block 2
+		require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(2)))
+		require.NoError(t, db.AddDerived(l1Ref(4), l2Ref(3)))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		pair, err := db.FirstDerivedWhere(atLeast(0))
+		require.NoError(t, err)
+		require.Equal(t, types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(1)}, pair)
+
+		// the first entry of a repeated L2 block
+		pair, err = db.FirstDerivedWhere(atLeast(2))
+		require.NoError(t, err)
+		require.Equal(t, types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(2)}, pair)
+
+		_, err = db.FirstDerivedWhere(atLeast(4))
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestFirstDerivedFromInRange(t *testing.T) {
+	l2Ref := func(i uint64) eth.BlockRef {
+		if i == 0 {
+			return toRef(mockL2(0), common.Hash{})
+		}
+		return toRef(mockL2(i), mockL2(i-1).Hash)
+	}
+	l1Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL1(i), mockL1(i-1).Hash)
+	}
+	pair := func(l1, l2 uint64) types.DerivedBlockSealPair {
+		return types.DerivedBlockSealPair{DerivedFrom: mockL1(l1), Derived: mockL2(l2)}
+	}
+
+	t.Run("dense", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddDerived(l1Ref(1), l2Ref(0)))
+			require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(1)))
+			require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(2)))
+			// L1 block 3 is empty, and repeats L2 block 2
+			require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(2)))
+			require.NoError(t, db.AddDerived(l1Ref(4), l2Ref(3)))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			got, err := db.FirstDerivedFromInRange(1, 4)
+			require.NoError(t, err)
+			require.Equal(t, []types.DerivedBlockSealPair{pair(1, 0), pair(2, 1), pair(3, 2), pair(4, 3)}, got)
+
+			got, err = db.FirstDerivedFromInRange(3, 3)
+			require.NoError(t, err)
+			require.Equal(t, []types.DerivedBlockSealPair{pair(3, 2)}, got)
+
+			// L1 block 0 is before the first entry
+			got, err = db.FirstDerivedFromInRange(0, 2)
+			require.NoError(t, err)
+			require.Equal(t, []types.DerivedBlockSealPair{pair(1, 0), pair(2, 1)}, got)
+
+			_, err = db.FirstDerivedFromInRange(2, 5)
+			require.ErrorIs(t, err, types.ErrFuture)
+			_, err = db.FirstDerivedFromInRange(3, 2)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("sparse", func(t *testing.T) {
+		// Entries do not link their derived-from blocks, so a sparse history can only be copied in.
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		for _, l := range []LinkEntry{
+			{derivedFrom: mockL1(10), derived: mockL2(0)},
+			{derivedFrom: mockL1(12), derived: mockL2(1)},
+			{derivedFrom: mockL1(15), derived: mockL2(2)},
+			{derivedFrom: mockL1(15), derived: mockL2(3)},
+		} {
+			require.NoError(t, store.Append(l.encode()))
+		}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+
+		got, err := db.FirstDerivedFromInRange(10, 15)
+		require.NoError(t, err)
+		require.Equal(t, []types.DerivedBlockSealPair{pair(10, 0), pair(12, 1), pair(15, 2)}, got)
+
+		got, err = db.FirstDerivedFromInRange(13, 14)
+		require.NoError(t, err)
+		require.Empty(t, got, "gap")
+	})
+}
@@ -34,6 +34,19 @@ func (s EntryType) String() string {
 	}
 }
 
+// LatestVersion is the layout version of entries that are written by the DB.
+const LatestVersion uint8 = 0
+
+// Version returns the layout version of entries of this type.
+func (s EntryType) Version() (uint8, error) {
+	switch s {
+	case DerivedFromV0, InvalidatedFromV0:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("%w: unexpected entry type: %s", types.ErrDataCorruption, s)
+	}
+}
+
 type EntryBinary struct{}
 
 func (EntryBinary) Append(dest []byte, e *Entry) []byte {
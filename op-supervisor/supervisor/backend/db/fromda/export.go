@@ -0,0 +1,152 @@
+package fromda
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// exportMagic identifies an export of a derived-from DB.
+var exportMagic = [4]byte{'F', 'D', 'A', 'X'}
+
+// exportVersion is the version of the export format.
+// The layout of the entries themselves is identified by their entry types.
+const exportVersion uint8 = 0
+
+// exportHeaderSize is the size of the magic, the export version, and the entry count.
+const exportHeaderSize = 4 + 1 + 8
+
+// Export writes a snapshot of the DB, that can be loaded with Import:
+// a header with the export version and entry count, the raw entries, and a keccak256 checksum of the entries.
+// The DB is read-locked while exporting, so the snapshot is consistent.
+func (db *DB) Export(w io.Writer) error {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	var header [exportHeaderSize]byte
+	copy(header[:4], exportMagic[:])
+	header[4] = exportVersion
+	binary.BigEndian.PutUint64(header[5:], uint64(db.store.Size()))
+	if _, err := w.Write(header[:]); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	hasher := crypto.NewKeccakState()
+	lastIndex := db.store.LastEntryIdx()
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		e, err := db.store.Read(i)
+		if err != nil {
+			return fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		hasher.Write(e[:])
+		if _, err := w.Write(e[:]); err != nil {
+			return fmt.Errorf("failed to write entry %d: %w", i, err)
+		}
+	}
+	var checksum common.Hash
+	hasher.Read(checksum[:])
+	if _, err := w.Write(checksum[:]); err != nil {
+		return fmt.Errorf("failed to write checksum: %w", err)
+	}
+	return nil
+}
+
+// Import loads a snapshot that was written by Export.
+// The snapshot is fully read and checked before the DB is modified:
+// besides the checksum, the entries have to be consistent, see Verify.
+// Importing into a non-empty DB is refused, unless replace is set, in which case the existing entries are dropped.
+// The entries are written with a single rewrite of the store: if writing fails, the existing entries are kept.
+func (db *DB) Import(r io.Reader, replace bool) error {
+	var header [exportHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if [4]byte(header[:4]) != exportMagic {
+		return fmt.Errorf("%w: not a derived-from DB export: %x", types.ErrDataCorruption, header[:4])
+	}
+	if v := header[4]; v != exportVersion {
+		return fmt.Errorf("unsupported export version %d, expected %d", v, exportVersion)
+	}
+	count := binary.BigEndian.Uint64(header[5:])
+
+	hasher := crypto.NewKeccakState()
+	var entries []Entry
+	for i := uint64(0); i < count; i++ {
+		var e Entry
+		if _, err := io.ReadFull(r, e[:]); err != nil {
+			return fmt.Errorf("failed to read entry %d of %d: %w", i, count, err)
+		}
+		hasher.Write(e[:])
+		entries = append(entries, e)
+	}
+	var expected, checksum common.Hash
+	if _, err := io.ReadFull(r, expected[:]); err != nil {
+		return fmt.Errorf("failed to read checksum: %w", err)
+	}
+	hasher.Read(checksum[:])
+	if checksum != expected {
+		return fmt.Errorf("%w: checksum %s does not match expected %s", types.ErrDataCorruption, checksum, expected)
+	}
+	// Verify the entries in a temporary DB, before they are written.
+	tmpStore := &entrydb.MemEntryStore[EntryType, Entry]{}
+	if err := tmpStore.Append(entries...); err != nil {
+		return err
+	}
+	tmp := &DB{id: dbIDs.Add(1), log: db.log, store: tmpStore}
+	if err := tmp.Verify(); err != nil {
+		return fmt.Errorf("inconsistent export: %w", err)
+	}
+	// Only the last entry can be invalidated, see Verify.
+	var invalidated []types.DerivedBlockRefPair
+	if db.onInvalidated != nil && len(entries) > 1 {
+		pair, ok, err := tmp.invalidatedTail()
+		if err != nil {
+			return fmt.Errorf("failed to read invalidated entry: %w", err)
+		}
+		if ok {
+			invalidated = append(invalidated, pair)
+		}
+	}
+
+	defer db.notifyInvalidated()
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	if size := db.store.Size(); size != 0 && !replace {
+		return fmt.Errorf("cannot import into DB with %d entries: %w", size, types.ErrConflict)
+	}
+	if err := db.rewriteLocked(entries); err != nil {
+		return fmt.Errorf("failed to write %d entries: %w", len(entries), err)
+	}
+	db.pendingInvalidated = append(db.pendingInvalidated, invalidated...)
+	return nil
+}
+
+// invalidatedTail returns the last entry, if it is invalidated, with the parent-hashes of the entries before it.
+// The derived-from parent is left empty if it is not in the DB.
+func (db *DB) invalidatedTail() (pair types.DerivedBlockRefPair, ok bool, err error) {
+	lastIndex := db.store.LastEntryIdx()
+	last, err := db.readAt(lastIndex)
+	if err != nil {
+		return types.DerivedBlockRefPair{}, false, err
+	}
+	if !last.invalidated {
+		return types.DerivedBlockRefPair{}, false, nil
+	}
+	prev, err := db.readAt(lastIndex - 1)
+	if err != nil {
+		return types.DerivedBlockRefPair{}, false, err
+	}
+	prevDerivedFrom, err := db.previousDerivedFrom(last.derivedFrom.ID())
+	if err != nil && !errors.Is(err, types.ErrPreviousToFirst) {
+		return types.DerivedBlockRefPair{}, false, err
+	}
+	return types.DerivedBlockRefPair{
+		DerivedFrom: last.derivedFrom.ForceWithParent(prevDerivedFrom.ID()),
+		Derived:     last.derived.ForceWithParent(prev.derived.ID()),
+	}, true, nil
+}
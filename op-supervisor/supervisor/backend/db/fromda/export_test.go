@@ -0,0 +1,116 @@
+package fromda
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestExportImport(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	newMemDB := func(t *testing.T) (*DB, *stubMetrics) {
+		m := &stubMetrics{}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), m, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		return db, m
+	}
+	export := func(t *testing.T, db *DB) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, db.Export(&buf))
+		return buf.Bytes()
+	}
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref2))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		data := export(t, db)
+		expected, err := db.Fingerprint()
+		require.NoError(t, err)
+
+		t.Run("round trip", func(t *testing.T) {
+			imported, im := newMemDB(t)
+			require.NoError(t, imported.Import(bytes.NewReader(data), false))
+			got, err := imported.Fingerprint()
+			require.NoError(t, err)
+			require.Equal(t, expected, got)
+			require.Equal(t, int64(4), im.DBDerivedEntryCount)
+			invalidated, err := imported.Invalidated()
+			require.NoError(t, err)
+			require.Equal(t, mockL2(2), invalidated.Derived)
+			require.Equal(t, data, export(t, imported))
+		})
+
+		t.Run("non-empty", func(t *testing.T) {
+			imported, _ := newMemDB(t)
+			require.NoError(t, imported.AddDerived(l1Ref0, l2Ref0))
+			require.ErrorIs(t, imported.Import(bytes.NewReader(data), false), types.ErrConflict)
+			require.Equal(t, 1, imported.Len())
+			require.NoError(t, imported.Import(bytes.NewReader(data), true))
+			got, err := imported.Fingerprint()
+			require.NoError(t, err)
+			require.Equal(t, expected, got)
+		})
+
+		t.Run("failed replace", func(t *testing.T) {
+			store := &failingStore{}
+			imported, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+			require.NoError(t, err)
+			require.NoError(t, imported.AddDerived(l1Ref0, l2Ref0))
+			require.NoError(t, imported.AddDerived(l1Ref1, l2Ref1))
+			expectedErr := errors.New("write failed")
+			store.writeErr = expectedErr
+			require.ErrorIs(t, imported.Import(bytes.NewReader(data), true), expectedErr)
+			require.Equal(t, 2, imported.Len(), "existing entries are kept")
+			latest, err := imported.Latest()
+			require.NoError(t, err)
+			require.Equal(t, mockL2(1), latest.Derived)
+		})
+
+		t.Run("corrupt", func(t *testing.T) {
+			imported, _ := newMemDB(t)
+			flipped := bytes.Clone(data)
+			flipped[exportHeaderSize+10] ^= 0xff
+			require.ErrorIs(t, imported.Import(bytes.NewReader(flipped), false), types.ErrDataCorruption)
+
+			badMagic := bytes.Clone(data)
+			badMagic[0] = 'x'
+			require.ErrorIs(t, imported.Import(bytes.NewReader(badMagic), false), types.ErrDataCorruption)
+
+			require.ErrorIs(t, imported.Import(bytes.NewReader(data[:len(data)-1]), false), io.ErrUnexpectedEOF)
+			require.Zero(t, imported.Len(), "nothing is imported")
+		})
+
+		t.Run("inconsistent", func(t *testing.T) {
+			// drop the second entry, and fix up the count and checksum, so only the consistency checks can catch it
+			entries := bytes.Clone(data[exportHeaderSize : len(data)-32])
+			entries = append(entries[:EntrySize], entries[2*EntrySize:]...)
+			header := bytes.Clone(data[:exportHeaderSize])
+			header[exportHeaderSize-1]--
+			edited := append(append(header, entries...), crypto.Keccak256(entries)...)
+			imported, _ := newMemDB(t)
+			require.ErrorIs(t, imported.Import(bytes.NewReader(edited), false), types.ErrOutOfOrder)
+			require.Zero(t, imported.Len())
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package fromda
+
+import (
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// LinkIterator iterates over the links of the DB, from first to last.
+// Next must be called before reading the first link.
+type LinkIterator interface {
+	// Next advances to the next link, and returns false when there are no more links, or when reading failed.
+	Next() bool
+	// Link returns the current link, and whether it is an invalidation entry.
+	Link() (pair types.DerivedBlockSealPair, invalidated bool)
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+type linkIterator struct {
+	readAt func(i entrydb.EntryIdx) (LinkEntry, error)
+	// end is the number of entries at the time the iterator was created.
+	end     entrydb.EntryIdx
+	next    entrydb.EntryIdx
+	current LinkEntry
+	err     error
+}
+
+// Iterator returns an iterator over all links in the DB, in order.
+// The entries are read lazily, from a snapshot of the DB: the iterator covers the entries that existed
+// when it was created, and does not see later appends. If the DB drops entries during iteration,
+// e.g. with a rewind, the iterator stops with ErrStaleSnapshot, see DBSnapshot.
+func (db *DB) Iterator() (LinkIterator, error) {
+	s, err := db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return s.Iterator(), nil
+}
+
+func (it *linkIterator) Next() bool {
+	if it.err != nil || it.next >= it.end {
+		return false
+	}
+	link, err := it.readAt(it.next)
+	if err != nil {
+		it.err = fmt.Errorf("failed to read entry %d: %w", it.next, err)
+		return false
+	}
+	it.current = link
+	it.next++
+	return true
+}
+
+func (it *linkIterator) Link() (pair types.DerivedBlockSealPair, invalidated bool) {
+	return types.DerivedBlockSealPair{
+		DerivedFrom: it.current.derivedFrom,
+		Derived:     it.current.derived,
+	}, it.current.invalidated
+}
+
+func (it *linkIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,87 @@
+package fromda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestIterator(t *testing.T) {
+	l1Ref0 := toRef(mockL1(0), common.Hash{})
+	l1Ref1 := toRef(mockL1(1), mockL1(0).Hash)
+	l1Ref2 := toRef(mockL1(2), mockL1(1).Hash)
+	l2Ref0 := toRef(mockL2(0), common.Hash{})
+	l2Ref1 := toRef(mockL2(1), mockL2(0).Hash)
+	l2Ref2 := toRef(mockL2(2), mockL2(1).Hash)
+
+	t.Run("empty", func(t *testing.T) {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		it, err := db.Iterator()
+		require.NoError(t, err)
+		require.False(t, it.Next())
+		require.NoError(t, it.Err())
+	})
+
+	runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref1))
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref2, Derived: l2Ref2}))
+	}, func(t *testing.T, db *DB, m *stubMetrics) {
+		type link struct {
+			pair        types.DerivedBlockSealPair
+			invalidated bool
+		}
+		expected := []link{
+			{pair: types.DerivedBlockSealPair{DerivedFrom: mockL1(0), Derived: mockL2(0)}},
+			{pair: types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(1)}},
+			{pair: types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(1)}},
+			{pair: types.DerivedBlockSealPair{DerivedFrom: mockL1(2), Derived: mockL2(2)}, invalidated: true},
+		}
+		it, err := db.Iterator()
+		require.NoError(t, err)
+		var got []link
+		for it.Next() {
+			pair, invalidated := it.Link()
+			got = append(got, link{pair: pair, invalidated: invalidated})
+		}
+		require.NoError(t, it.Err())
+		require.Equal(t, expected, got)
+		require.False(t, it.Next(), "remains exhausted")
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+		require.NoError(t, err)
+		require.NoError(t, db.AddDerived(l1Ref0, l2Ref0))
+		require.NoError(t, db.AddDerived(l1Ref1, l2Ref1))
+		it, err := db.Iterator()
+		require.NoError(t, err)
+		require.True(t, it.Next())
+
+		// later appends are not seen
+		require.NoError(t, db.AddDerived(l1Ref2, l2Ref2))
+		require.True(t, it.Next())
+		pair, _ := it.Link()
+		require.Equal(t, mockL2(1), pair.Derived)
+		require.False(t, it.Next())
+		require.NoError(t, it.Err())
+
+		// a rewind stops the iteration
+		it, err = db.Iterator()
+		require.NoError(t, err)
+		require.True(t, it.Next())
+		require.NoError(t, db.Rewind(types.DerivedBlockSealPair{DerivedFrom: mockL1(1), Derived: mockL2(1)}, false))
+		require.False(t, it.Next())
+		require.ErrorIs(t, it.Err(), ErrStaleSnapshot)
+	})
+}
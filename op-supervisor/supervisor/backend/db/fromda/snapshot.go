@@ -0,0 +1,118 @@
+package fromda
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// ErrStaleSnapshot is returned by reads of a snapshot, after the DB was rewound past the snapshot.
+var ErrStaleSnapshot = errors.New("stale snapshot")
+
+// DBSnapshot is a read-only view of the DB, as it was when the snapshot was captured.
+// The DB is append-only, so the entries of the snapshot do not change when the DB grows,
+// and the snapshot does not see any later appends.
+// The DB lock is only held while reading a single entry, so long-running queries on a snapshot
+// do not block writers of the DB. If the DB drops entries, e.g. with a rewind,
+// the entries of the snapshot may be rewritten, and reads of the snapshot fail with ErrStaleSnapshot.
+type DBSnapshot struct {
+	db      *DB
+	size    int64
+	rewinds uint64
+}
+
+// Snapshot captures a read-only view of the current entries of the DB.
+func (db *DB) Snapshot() (*DBSnapshot, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	return &DBSnapshot{db: db, size: db.store.Size(), rewinds: db.rewinds}, nil
+}
+
+// Len returns the number of entries in the snapshot.
+func (s *DBSnapshot) Len() int {
+	return int(s.size)
+}
+
+func (s *DBSnapshot) readAt(i entrydb.EntryIdx) (LinkEntry, error) {
+	if int64(i) >= s.size {
+		return LinkEntry{}, types.ErrFuture
+	}
+	s.db.rwLock.RLock()
+	defer s.db.rwLock.RUnlock()
+	if s.db.rewinds != s.rewinds {
+		return LinkEntry{}, ErrStaleSnapshot
+	}
+	return s.db.readAt(i)
+}
+
+// Latest returns the last entry of the snapshot, see DB.Latest.
+func (s *DBSnapshot) Latest() (pair types.DerivedBlockSealPair, err error) {
+	if s.size == 0 {
+		return types.DerivedBlockSealPair{}, types.ErrFuture
+	}
+	link, err := s.readAt(entrydb.EntryIdx(s.size - 1))
+	if err != nil {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("failed to read last derivation data: %w", err)
+	}
+	return link.sealOrErr()
+}
+
+// IsDerived checks if the given block is the canonical block of the snapshot, see DB.IsDerived.
+func (s *DBSnapshot) IsDerived(derived eth.BlockID) error {
+	_, link, err := findIn(s.size, s.readAt, true, func(link LinkEntry) int {
+		return cmp.Compare(derived.Number, link.derived.Number)
+	})
+	if err != nil {
+		return err
+	}
+	if link.derived.ID() != derived {
+		return fmt.Errorf("searched if derived %s but found %s: %w",
+			derived, link.derived, types.ErrConflict)
+	}
+	if link.invalidated {
+		return fmt.Errorf("derived %s, but invalidated it: %w", derived, types.ErrAwaitReplacementBlock)
+	}
+	return nil
+}
+
+// DerivedFrom determines where a L2 block was first derived from, see DB.DerivedFrom.
+func (s *DBSnapshot) DerivedFrom(derived eth.BlockID) (derivedFrom types.BlockSeal, err error) {
+	_, link, err := findIn(s.size, s.readAt, false, func(link LinkEntry) int {
+		return cmp.Compare(link.derived.Number, derived.Number)
+	})
+	if err != nil {
+		return types.BlockSeal{}, err
+	}
+	if link.derived.ID() != derived {
+		return types.BlockSeal{}, fmt.Errorf("searched for first derived %s but found %s: %w",
+			derived, link.derived, types.ErrConflict)
+	}
+	return link.derivedFrom, nil
+}
+
+// LastDerivedAt returns the last L2 block derived from the given L1 block, see DB.LastDerivedAt.
+func (s *DBSnapshot) LastDerivedAt(derivedFrom eth.BlockID) (derived types.BlockSeal, err error) {
+	_, link, err := findIn(s.size, s.readAt, true, func(link LinkEntry) int {
+		return cmp.Compare(derivedFrom.Number, link.derivedFrom.Number)
+	})
+	if err != nil {
+		return types.BlockSeal{}, err
+	}
+	if link.derivedFrom.ID() != derivedFrom {
+		return types.BlockSeal{}, fmt.Errorf("searched for last derived-from %s but found %s: %w",
+			derivedFrom, link.derivedFrom, types.ErrConflict)
+	}
+	if link.invalidated {
+		return types.BlockSeal{}, types.ErrAwaitReplacementBlock
+	}
+	return link.derived, nil
+}
+
+// Iterator returns an iterator over all links in the snapshot, in order.
+func (s *DBSnapshot) Iterator() LinkIterator {
+	return &linkIterator{readAt: s.readAt, end: entrydb.EntryIdx(s.size)}
+}
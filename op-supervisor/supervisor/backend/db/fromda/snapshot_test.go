@@ -0,0 +1,87 @@
+package fromda
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestSnapshot(t *testing.T) {
+	ref := func(seal func(uint64) types.BlockSeal, i uint64) eth.BlockRef {
+		if i == 0 {
+			return toRef(seal(0), common.Hash{})
+		}
+		return toRef(seal(i), seal(i-1).Hash)
+	}
+	db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{})
+	require.NoError(t, err)
+	for i := uint64(0); i < 3; i++ {
+		require.NoError(t, db.AddDerived(ref(mockL1, i), ref(mockL2, i)))
+	}
+
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+
+	const appends = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := uint64(3); i < 3+appends; i++ {
+			if err := db.AddDerived(ref(mockL1, i), ref(mockL2, i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	requireStable := func() {
+		require.Equal(t, 3, snap.Len())
+		pair, err := snap.Latest()
+		require.NoError(t, err)
+		require.Equal(t, mockL1(2), pair.DerivedFrom)
+		require.Equal(t, mockL2(2), pair.Derived)
+		require.NoError(t, snap.IsDerived(mockL2(1).ID()))
+		require.ErrorIs(t, snap.IsDerived(mockL2(3).ID()), types.ErrFuture)
+		derivedFrom, err := snap.DerivedFrom(mockL2(2).ID())
+		require.NoError(t, err)
+		require.Equal(t, mockL1(2), derivedFrom)
+		derived, err := snap.LastDerivedAt(mockL1(1).ID())
+		require.NoError(t, err)
+		require.Equal(t, mockL2(1), derived)
+
+		it := snap.Iterator()
+		count := 0
+		for it.Next() {
+			count++
+		}
+		require.NoError(t, it.Err())
+		require.Equal(t, 3, count)
+	}
+	for i := 0; i < 20; i++ {
+		requireStable()
+	}
+	wg.Wait()
+	require.Equal(t, 3+appends, db.Len())
+	requireStable()
+
+	// a rewind may rewrite the entries of the snapshot
+	require.NoError(t, db.RewindToL2(1))
+	_, err = snap.Latest()
+	require.ErrorIs(t, err, ErrStaleSnapshot)
+	require.ErrorIs(t, snap.IsDerived(mockL2(1).ID()), ErrStaleSnapshot)
+
+	fresh, err := db.Snapshot()
+	require.NoError(t, err)
+	pair, err := fresh.Latest()
+	require.NoError(t, err)
+	require.Equal(t, mockL2(1), pair.Derived)
+}
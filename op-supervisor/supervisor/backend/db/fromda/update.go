@@ -1,11 +1,14 @@
 package fromda
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 )
 
@@ -15,6 +18,54 @@ func (db *DB) AddDerived(derivedFrom eth.BlockRef, derived eth.BlockRef) error {
 	return db.addLink(derivedFrom, derived, common.Hash{})
 }
 
+// AddDerivedBatch adds the derivation links in order, like repeated AddDerived calls,
+// but acquires the lock only once, to speed up the import of long histories.
+// The links before a failing link are committed: the error reports the index of the failing link,
+// so the import can resume from there.
+func (db *DB) AddDerivedBatch(pairs []types.DerivedBlockRefPair) error {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	for i, pair := range pairs {
+		if err := db.addLink(pair.DerivedFrom, pair.Derived, common.Hash{}); err != nil {
+			return fmt.Errorf("failed to add link %d (%s derived from %s): %w", i, pair.Derived, pair.DerivedFrom, err)
+		}
+	}
+	return nil
+}
+
+// CopyTo copies every entry of the DB into the empty dst DB.
+// Unlike re-adding the derivation links, this preserves the exact entries, including invalidation markers.
+// Each entry is decoded, to check it is well-formed, and re-encoded into dst, which is then checked with Verify.
+// If the copy is inconsistent, dst is emptied again.
+// The DBs are locked in a stable order, see lockPair, so concurrent copies between the same DBs cannot deadlock.
+func (db *DB) CopyTo(dst *DB) error {
+	if db == dst {
+		return fmt.Errorf("cannot copy DB into itself: %w", types.ErrConflict)
+	}
+	defer lockPair(db, dst, true)()
+
+	if size := dst.store.Size(); size != 0 {
+		return fmt.Errorf("cannot copy into non-empty DB with %d entries: %w", size, types.ErrConflict)
+	}
+	lastIndex := db.store.LastEntryIdx()
+	entries := make([]Entry, 0, lastIndex+1)
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		entries = append(entries, link.encode())
+	}
+	if err := dst.store.Append(entries...); err != nil {
+		return fmt.Errorf("failed to write %d entries: %w", len(entries), err)
+	}
+	dst.m.RecordDBDerivedEntryCount(dst.store.Size())
+	if err := dst.verifyLocked(); err != nil {
+		return errors.Join(fmt.Errorf("copied entries are inconsistent: %w", err), dst.truncateLocked(-1))
+	}
+	return nil
+}
+
 // ReplaceInvalidatedBlock replaces the current Invalidated block with the given replacement.
 // The to-be invalidated hash must be provided for consistency checks.
 func (db *DB) ReplaceInvalidatedBlock(replacementDerived eth.BlockRef, invalidated common.Hash) (types.DerivedBlockSealPair, error) {
@@ -39,6 +90,10 @@ func (db *DB) ReplaceInvalidatedBlock(replacementDerived eth.BlockRef, invalidat
 	if last.derived.Hash != invalidated {
 		return types.DerivedBlockSealPair{}, fmt.Errorf("cannot replace invalidated %s, DB contains %s: %w", invalidated, last.derived, types.ErrConflict)
 	}
+	// The replacement must take the place of the invalidated block, at the same height.
+	if replacementDerived.Number != last.derived.Number {
+		return types.DerivedBlockSealPair{}, fmt.Errorf("cannot replace invalidated %s with block %s at different height: %w", last.derived, replacementDerived, types.ErrConflict)
+	}
 	// Find the parent-block of derived-from.
 	// We need this to build a block-ref, so the DB can be consistency-checked when the next entry is added.
 	// There is always one, since the first entry in the DB should never be an invalidated one.
@@ -47,8 +102,7 @@ func (db *DB) ReplaceInvalidatedBlock(replacementDerived eth.BlockRef, invalidat
 		return types.DerivedBlockSealPair{}, err
 	}
 	// Remove the invalidated placeholder and everything after
-	err = db.store.Truncate(lastIndex - 1)
-	if err != nil {
+	if err := db.truncateLocked(lastIndex - 1); err != nil {
 		return types.DerivedBlockSealPair{}, err
 	}
 	replacement := types.DerivedBlockRefPair{
@@ -65,7 +119,28 @@ func (db *DB) ReplaceInvalidatedBlock(replacementDerived eth.BlockRef, invalidat
 // RewindAndInvalidate rolls back the database to just before the invalidated block,
 // and then marks the block as invalidated, so that no new data can be added to the DB
 // until a Rewind or ReplaceInvalidatedBlock.
+// The invalidation hook of the DB, if any, is called after the DB is unlocked.
 func (db *DB) RewindAndInvalidate(invalidated types.DerivedBlockRefPair) error {
+	defer db.notifyInvalidated()
+	return db.rewindAndInvalidate(invalidated)
+}
+
+// notifyInvalidated passes the pending invalidations to the invalidation hook, if any.
+// Note: This function must be called without the rwLock held.
+func (db *DB) notifyInvalidated() {
+	if db.onInvalidated == nil {
+		return
+	}
+	db.rwLock.Lock()
+	pending := db.pendingInvalidated
+	db.pendingInvalidated = nil
+	db.rwLock.Unlock()
+	for _, invalidated := range pending {
+		db.onInvalidated(invalidated)
+	}
+}
+
+func (db *DB) rewindAndInvalidate(invalidated types.DerivedBlockRefPair) error {
 	db.rwLock.Lock()
 	defer db.rwLock.Unlock()
 
@@ -85,20 +160,35 @@ func (db *DB) RewindAndInvalidate(invalidated types.DerivedBlockRefPair) error {
 // Rewind rolls back the database to the target, including the target if the including flag is set.
 // it locks the DB and calls rewindLocked.
 func (db *DB) Rewind(target types.DerivedBlockSealPair, including bool) error {
+	return db.RewindCtx(context.Background(), target, including)
+}
+
+// RewindCtx is like Rewind, but checks the context while searching for the target.
+// The context is checked for the last time right before truncating, and the truncation itself
+// is a single operation on the store: if the context is cancelled, the DB is left unchanged.
+func (db *DB) RewindCtx(ctx context.Context, target types.DerivedBlockSealPair, including bool) error {
 	db.rwLock.Lock()
 	defer db.rwLock.Unlock()
-	return db.rewindLocked(target, including)
+	return db.rewindLockedCtx(ctx, target, including)
 }
 
 // RewindToL2 rewinds to the first entry where the L2 block with the given number was derived.
 func (db *DB) RewindToL2(derived uint64) error {
+	return db.RewindToL2Ctx(context.Background(), derived)
+}
+
+// RewindToL2Ctx is like RewindToL2, but checks the context like RewindCtx.
+func (db *DB) RewindToL2Ctx(ctx context.Context, derived uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	db.rwLock.Lock()
 	defer db.rwLock.Unlock()
 	_, link, err := db.firstDerivedFrom(derived)
 	if err != nil {
 		return fmt.Errorf("failed to find first derived-from %d: %w", derived, err)
 	}
-	return db.rewindLocked(types.DerivedBlockSealPair{
+	return db.rewindLockedCtx(ctx, types.DerivedBlockSealPair{
 		DerivedFrom: link.derivedFrom,
 		Derived:     link.derived,
 	}, false)
@@ -106,25 +196,200 @@ func (db *DB) RewindToL2(derived uint64) error {
 
 // RewindToL1 rewinds to the last entry that was derived from a L1 block with the given block number.
 func (db *DB) RewindToL1(derivedFrom uint64) error {
+	return db.RewindToL1Ctx(context.Background(), derivedFrom)
+}
+
+// RewindToL1Ctx is like RewindToL1, but checks the context like RewindCtx.
+func (db *DB) RewindToL1Ctx(ctx context.Context, derivedFrom uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	db.rwLock.Lock()
 	defer db.rwLock.Unlock()
 	_, link, err := db.lastDerivedAt(derivedFrom)
 	if err != nil {
 		return fmt.Errorf("failed to find last derived %d: %w", derivedFrom, err)
 	}
-	return db.rewindLocked(types.DerivedBlockSealPair{
+	return db.rewindLockedCtx(ctx, types.DerivedBlockSealPair{
+		DerivedFrom: link.derivedFrom,
+		Derived:     link.derived,
+	}, false)
+}
+
+// PruneBefore removes all entries that were derived from L1 blocks older than the given L1 block number,
+// and returns the number of removed entries.
+// The first remaining entry becomes the anchor that later entries build on:
+// if it is an invalidated entry, the entry before it is kept as anchor instead.
+// Returns types.ErrFuture if the L1 block number is past the latest derived-from block,
+// so at least one entry always remains.
+// The store is rewritten to drop the entries, and entry indices shift accordingly:
+// if the rewrite fails, all entries are kept.
+func (db *DB) PruneBefore(derivedFrom uint64) (removed int, err error) {
+	return db.PruneBeforeCtx(context.Background(), derivedFrom)
+}
+
+// PruneBeforeCtx is like PruneBefore, but checks the context while reading the entries to keep.
+// The context is checked for the last time right before the rewrite: if it is cancelled, the DB is left unchanged.
+func (db *DB) PruneBeforeCtx(ctx context.Context, derivedFrom uint64) (removed int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	last, err := db.latest()
+	if err != nil {
+		return 0, err
+	}
+	if derivedFrom > last.derivedFrom.Number {
+		return 0, fmt.Errorf("cannot prune before %d, past latest derived-from %s: %w", derivedFrom, last.derivedFrom, types.ErrFuture)
+	}
+	start, first, err := db.find(false, func(link LinkEntry) int {
+		if link.derivedFrom.Number < derivedFrom {
+			return -1
+		}
+		return 0
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find first entry derived from %d: %w", derivedFrom, err)
+	}
+	if first.invalidated && start > 0 {
+		start--
+	}
+	if start == 0 {
+		return 0, nil
+	}
+	lastIndex := db.store.LastEntryIdx()
+	kept := make([]Entry, 0, lastIndex-start+1)
+	for i := start; i <= lastIndex; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		e, err := db.store.Read(i)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		kept = append(kept, e)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := db.rewriteLocked(kept); err != nil {
+		return 0, fmt.Errorf("failed to rewrite %d entries after pruning: %w", len(kept), err)
+	}
+	return int(start), nil
+}
+
+// RewindToL2Timestamp rewinds to the first entry of the first L2 block with a timestamp at or after ts.
+// Like RewindToL2, the first entry of the L2 block is kept, and its repeats in later L1 blocks are dropped.
+// A timestamp in between L2 blocks thus rewinds to the next L2 block.
+// Returns types.ErrFuture if no L2 block has reached the timestamp.
+func (db *DB) RewindToL2Timestamp(ts uint64) error {
+	return db.RewindToL2TimestampCtx(context.Background(), ts)
+}
+
+// RewindToL2TimestampCtx is like RewindToL2Timestamp, but checks the context like RewindCtx.
+func (db *DB) RewindToL2TimestampCtx(ctx context.Context, ts uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	_, link, err := db.find(false, func(link LinkEntry) int {
+		if link.derived.Timestamp < ts {
+			return -1
+		}
+		return 0
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find first derived at or after timestamp %d: %w", ts, err)
+	}
+	return db.rewindLockedCtx(ctx, types.DerivedBlockSealPair{
+		DerivedFrom: link.derivedFrom,
+		Derived:     link.derived,
+	}, false)
+}
+
+// RewindToL1Timestamp rewinds to the last entry that was derived from a L1 block with a timestamp at or before ts.
+// This is a no-op if all entries are that old.
+// Returns types.ErrSkipped if all entries were derived from newer L1 blocks.
+func (db *DB) RewindToL1Timestamp(ts uint64) error {
+	return db.RewindToL1TimestampCtx(context.Background(), ts)
+}
+
+// RewindToL1TimestampCtx is like RewindToL1Timestamp, but checks the context like RewindCtx.
+func (db *DB) RewindToL1TimestampCtx(ctx context.Context, ts uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	// Reverse: prioritize the last entry, to not drop entries that share the L1 block, or timestamp.
+	_, link, err := db.find(true, func(link LinkEntry) int {
+		if link.derivedFrom.Timestamp <= ts {
+			return 0
+		}
+		return -1
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find last derived-from at or before timestamp %d: %w", ts, err)
+	}
+	return db.rewindLockedCtx(ctx, types.DerivedBlockSealPair{
 		DerivedFrom: link.derivedFrom,
 		Derived:     link.derived,
 	}, false)
 }
 
+// CrossRewind rolls back a cross-safe DB upon invalidation of a derived block:
+// all entries of the invalidated block, and everything after, are removed,
+// so that the DB ends at the last link that was cross-validated before the invalidated block.
+// Unlike RewindAndInvalidate, no invalidation marker is added:
+// the cross-safe DB simply follows the local-safe DB again once the replacement is cross-validated.
+// This is a no-op if the invalidated block was never cross-safe: rewound reports whether any entries were removed.
+// The first entry anchors the DB, and cannot be rewound: ErrPreviousToFirst is returned if it is invalidated.
+func (db *DB) CrossRewind(invalidated types.DerivedBlockRefPair) (rewound bool, err error) {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	i, link, err := db.firstDerivedFrom(invalidated.Derived.Number)
+	if errors.Is(err, types.ErrFuture) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to find first derived-from %d: %w", invalidated.Derived.Number, err)
+	}
+	if link.derived.Hash != invalidated.Derived.Hash {
+		return false, fmt.Errorf("found derived %s, but expected to invalidate %s: %w",
+			link.derived, invalidated.Derived, types.ErrConflict)
+	}
+	if i == 0 {
+		return false, fmt.Errorf("cannot rewind past first entry %s: %w", link.derived, types.ErrPreviousToFirst)
+	}
+	if err := db.truncateLocked(i - 1); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // rewindLocked performs the truncate operation to a specified block seal pair.
 // data beyond the specified block seal pair is truncated from the database.
 // if including is true, the block seal pair itself is removed as well.
 // Note: This function must be called with the rwLock held.
 // Callers are responsible for locking and unlocking the Database.
 func (db *DB) rewindLocked(t types.DerivedBlockSealPair, including bool) error {
-	i, link, err := db.lookup(t.DerivedFrom.Number, t.Derived.Number)
+	return db.rewindLockedCtx(context.Background(), t, including)
+}
+
+// rewindLockedCtx is like rewindLocked, but aborts when the context is cancelled before truncating.
+func (db *DB) rewindLockedCtx(ctx context.Context, t types.DerivedBlockSealPair, including bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Check the context on every entry read of the search, so a cancelled lookup stops early.
+	readAt := func(i entrydb.EntryIdx) (LinkEntry, error) {
+		if err := ctx.Err(); err != nil {
+			return LinkEntry{}, err
+		}
+		return db.readAt(i)
+	}
+	i, link, err := findIn(db.store.Size(), readAt, false, lookupCmp(t.DerivedFrom.Number, t.Derived.Number))
 	if err != nil {
 		return err
 	}
@@ -141,10 +406,13 @@ func (db *DB) rewindLocked(t types.DerivedBlockSealPair, including bool) error {
 	if including {
 		target = i - 1
 	}
-	if err := db.store.Truncate(target); err != nil {
+	// Last chance to abort: once truncating, the rewind is completed.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := db.truncateLocked(target); err != nil {
 		return fmt.Errorf("failed to rewind upon block invalidation of %s: %w", t, err)
 	}
-	db.m.RecordDBDerivedEntryCount(int64(target) + 1)
 	return nil
 }
 
@@ -262,5 +530,136 @@ func (db *DB) addLink(derivedFrom eth.BlockRef, derived eth.BlockRef, invalidate
 		return err
 	}
 	db.m.RecordDBDerivedEntryCount(db.store.Size())
+	if db.onInvalidated != nil && invalidated != (common.Hash{}) {
+		// The block is invalidated by the entry itself, or, when merging, by a replacement of the previous entry.
+		// The replacement of an invalidation entry, see ReplaceInvalidatedBlock, was reported when that entry was added.
+		if link.invalidated {
+			db.pendingInvalidated = append(db.pendingInvalidated, types.DerivedBlockRefPair{
+				DerivedFrom: derivedFrom,
+				Derived:     derived,
+			})
+		} else if lastDerived.Hash == invalidated {
+			db.pendingInvalidated = append(db.pendingInvalidated, types.DerivedBlockRefPair{
+				DerivedFrom: derivedFrom,
+				Derived:     lastDerived.ForceWithParent(derived.ParentID()),
+			})
+		}
+	}
+	return nil
+}
+
+// MergeInto appends every entry of the DB onto the tail of dst, to consolidate two parts of a history.
+// The first entry of the DB must link to the tail of dst, see VerifyLinksTo,
+// and the merge is refused if the two histories overlap or do not link.
+// Entries are appended through the regular consistency checks of dst,
+// and blocks that replaced an invalidated block are merged as replacements.
+// If any entry fails to append, dst is rolled back to its original tail.
+// The DBs are locked in a stable order, see lockPair, so concurrent merges between the same DBs cannot deadlock.
+func (db *DB) MergeInto(dst *DB) error {
+	if db == dst {
+		return fmt.Errorf("cannot merge DB into itself: %w", types.ErrConflict)
+	}
+	defer dst.notifyInvalidated()
+	defer lockPair(db, dst, true)()
+
+	lastIndex := db.store.LastEntryIdx()
+	if lastIndex < 0 {
+		return nil
+	}
+	dstLastIndex := dst.store.LastEntryIdx()
+	var prev LinkEntry
+	var prevDerivedParent eth.BlockID
+	if dstLastIndex >= 0 {
+		tail, err := dst.latest()
+		if err != nil {
+			return err
+		}
+		tailPair, err := tail.sealOrErr()
+		if err != nil {
+			return fmt.Errorf("cannot merge onto invalidated tail %s: %w", tail, err)
+		}
+		if err := db.verifyLinksTo(tailPair); err != nil {
+			return fmt.Errorf("cannot merge onto tail %s: %w", tail, err)
+		}
+		prev = tail
+	}
+	// rollback restores the original tail of dst, and drops the invalidations of the merged entries.
+	pending := len(dst.pendingInvalidated)
+	rollback := func(err error) error {
+		dst.pendingInvalidated = dst.pendingInvalidated[:pending]
+		return errors.Join(err, dst.truncateLocked(dstLastIndex))
+	}
+	for i := entrydb.EntryIdx(0); i <= lastIndex; i++ {
+		link, err := db.readAt(i)
+		if err != nil {
+			return rollback(fmt.Errorf("failed to read entry %d: %w", i, err))
+		}
+		// Entries do not retain parent-hashes, but the links within the DB were checked when they were added,
+		// and the link to dst was checked above, so the previous entry provides the parent-hash.
+		derivedFrom := link.derivedFrom.ForceWithParent(prev.derivedFrom.ID())
+		// A repeated or replacing block at the same height shares the parent of the previous entry.
+		derivedParent := prev.derived.ID()
+		if link.derived.Number == prev.derived.Number {
+			derivedParent = prevDerivedParent
+		}
+		derived := link.derived.ForceWithParent(derivedParent)
+		var invalidated common.Hash
+		if link.invalidated {
+			invalidated = link.derived.Hash
+		} else if link.derivedFrom.Number == prev.derivedFrom.Number+1 &&
+			link.derived.Number == prev.derived.Number && link.derived.Hash != prev.derived.Hash {
+			// A replacement of an invalidated block takes the height of the block it replaces, see Verify,
+			// and is added as the block that invalidates the previous entry.
+			invalidated = prev.derived.Hash
+		}
+		if err := dst.addLink(derivedFrom, derived, invalidated); err != nil {
+			return rollback(fmt.Errorf("failed to merge entry %d (%s): %w", i, link, err))
+		}
+		prev = link
+		prevDerivedParent = derivedParent
+	}
+	return nil
+}
+
+// truncateLocked removes all entries after the given index, and updates the metrics.
+// Note: This function must be called with the rwLock held.
+func (db *DB) truncateLocked(lastIndex entrydb.EntryIdx) error {
+	dropped := lastIndex < db.store.LastEntryIdx()
+	if err := db.store.Truncate(lastIndex); err != nil {
+		return fmt.Errorf("failed to truncate to entry %d: %w", lastIndex, err)
+	}
+	if dropped {
+		db.rewinds++
+	}
+	db.m.RecordDBDerivedEntryCount(db.store.Size())
+	return nil
+}
+
+// rewriteLocked replaces all entries of the store, and updates the metrics.
+// Like a truncation, this invalidates snapshots of the previous entries.
+// Note: This function must be called with the rwLock held.
+func (db *DB) rewriteLocked(entries []Entry) error {
+	if err := db.store.Rewrite(entries...); err != nil {
+		return err
+	}
+	db.rewinds++
+	db.m.RecordDBDerivedEntryCount(db.store.Size())
 	return nil
 }
+
+// MigrateTo upgrades the store to the given layout version.
+// There is only a single layout version so far, so there is nothing to migrate yet:
+// this is a no-op if the store is already on the requested version, and an error otherwise.
+func (db *DB) MigrateTo(version uint8) error {
+	current, err := db.StoreVersion()
+	if err != nil {
+		return fmt.Errorf("failed to determine store version: %w", err)
+	}
+	if current == version {
+		return nil
+	}
+	if version > LatestVersion {
+		return fmt.Errorf("cannot migrate to unknown version %d, latest version is %d", version, LatestVersion)
+	}
+	return fmt.Errorf("no migration path from version %d to version %d", current, version)
+}
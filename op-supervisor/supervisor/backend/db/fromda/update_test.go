@@ -1,12 +1,17 @@
 package fromda
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 )
 
@@ -178,3 +183,169 @@ func TestBadUpdates(t *testing.T) {
 		})
 	}
 }
+
+func TestAddDerivedBatch(t *testing.T) {
+	// mockChain builds a chain of n links, each L2 block derived from its own L1 block
+	mockChain := func(n uint64) []types.DerivedBlockRefPair {
+		pairs := make([]types.DerivedBlockRefPair, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var l1Parent, l2Parent common.Hash
+			if i > 0 {
+				l1Parent, l2Parent = mockL1(i-1).Hash, mockL2(i-1).Hash
+			}
+			pairs = append(pairs, types.DerivedBlockRefPair{
+				DerivedFrom: toRef(mockL1(i), l1Parent),
+				Derived:     toRef(mockL2(i), l2Parent),
+			})
+		}
+		return pairs
+	}
+
+	t.Run("long chain", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddDerivedBatch(mockChain(1000)))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			pair, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, mockL1(999), pair.DerivedFrom)
+			require.Equal(t, mockL2(999), pair.Derived)
+			derivedFrom, err := db.DerivedFrom(mockL2(500).ID())
+			require.NoError(t, err)
+			require.Equal(t, mockL1(500), derivedFrom)
+		})
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			pairs := mockChain(20)
+			// drop link 10, so link 11 does not build on the last link
+			pairs = append(pairs[:10], pairs[11:]...)
+			err := db.AddDerivedBatch(pairs)
+			require.ErrorIs(t, err, types.ErrOutOfOrder)
+			require.ErrorContains(t, err, "link 10 ")
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			// the links before the gap are committed
+			pair, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, mockL1(9), pair.DerivedFrom)
+			require.Equal(t, mockL2(9), pair.Derived)
+		})
+	})
+}
+
+func TestPruneBefore(t *testing.T) {
+	l1Ref := func(i uint64) eth.BlockRef {
+		return toRef(mockL1(i), mockL1(i-1).Hash)
+	}
+	l2Ref := func(i uint64) eth.BlockRef {
+		if i == 0 {
+			return toRef(mockL2(0), common.Hash{})
+		}
+		return toRef(mockL2(i), mockL2(i-1).Hash)
+	}
+
+	t.Run("prune", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddDerived(l1Ref(1), l2Ref(0)))
+			require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(1)))
+			require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(1)))
+			require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(2)))
+			require.NoError(t, db.AddDerived(l1Ref(4), l2Ref(3)))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			removed, err := db.PruneBefore(1)
+			require.NoError(t, err)
+			require.Zero(t, removed, "nothing to prune")
+			require.Equal(t, 5, db.Len())
+
+			_, err = db.PruneBefore(5)
+			require.ErrorIs(t, err, types.ErrFuture)
+			require.Equal(t, 5, db.Len())
+
+			removed, err = db.PruneBefore(3)
+			require.NoError(t, err)
+			require.Equal(t, 2, removed)
+			require.Equal(t, 3, db.Len())
+			require.Equal(t, int64(3), m.DBDerivedEntryCount)
+
+			first, err := db.First()
+			require.NoError(t, err)
+			require.Equal(t, types.DerivedBlockSealPair{DerivedFrom: mockL1(3), Derived: mockL2(1)}, first)
+			derivedFrom, err := db.DerivedFrom(mockL2(2).ID())
+			require.NoError(t, err)
+			require.Equal(t, mockL1(3), derivedFrom)
+			_, err = db.DerivedFrom(mockL2(0).ID())
+			require.ErrorIs(t, err, types.ErrSkipped)
+			require.NoError(t, db.IsDerived(mockL2(3).ID()))
+
+			// new entries build on the remaining entries
+			require.NoError(t, db.AddDerived(l1Ref(5), l2Ref(4)))
+			latest, err := db.Latest()
+			require.NoError(t, err)
+			require.Equal(t, mockL2(4), latest.Derived)
+			require.NoError(t, db.Verify())
+		})
+	})
+
+	t.Run("invalidated anchor", func(t *testing.T) {
+		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddDerived(l1Ref(1), l2Ref(0)))
+			require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(1)))
+			require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(2)))
+			require.NoError(t, db.RewindAndInvalidate(types.DerivedBlockRefPair{DerivedFrom: l1Ref(3), Derived: l2Ref(2)}))
+		}, func(t *testing.T, db *DB, m *stubMetrics) {
+			removed, err := db.PruneBefore(3)
+			require.NoError(t, err)
+			require.Equal(t, 1, removed, "the entry before the invalidated entry is kept")
+			first, err := db.First()
+			require.NoError(t, err)
+			require.Equal(t, mockL2(1), first.Derived)
+			invalidated, err := db.Invalidated()
+			require.NoError(t, err)
+			require.Equal(t, mockL2(2), invalidated.Derived)
+		})
+	})
+
+	t.Run("failed write", func(t *testing.T) {
+		store := &failingStore{}
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store)
+		require.NoError(t, err)
+		require.NoError(t, db.AddDerived(l1Ref(1), l2Ref(0)))
+		require.NoError(t, db.AddDerived(l1Ref(2), l2Ref(1)))
+		require.NoError(t, db.AddDerived(l1Ref(3), l2Ref(2)))
+
+		expectedErr := errors.New("write failed")
+		store.writeErr = expectedErr
+		_, err = db.PruneBefore(3)
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, 3, db.Len(), "all entries are kept")
+		first, err := db.First()
+		require.NoError(t, err)
+		require.Equal(t, mockL2(0), first.Derived)
+		require.NoError(t, db.Verify())
+
+		store.writeErr = nil
+		removed, err := db.PruneBefore(3)
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+	})
+}
+
+// failingStore is an in-memory store that fails to append or rewrite entries while writeErr is set.
+type failingStore struct {
+	entrydb.MemEntryStore[EntryType, Entry]
+	writeErr error
+}
+
+func (s *failingStore) Append(entries ...Entry) error {
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	return s.MemEntryStore.Append(entries...)
+}
+
+func (s *failingStore) Rewrite(entries ...Entry) error {
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	return s.MemEntryStore.Rewrite(entries...)
+}
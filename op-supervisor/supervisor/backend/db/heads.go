@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// ChainHeads are the heads of a chain, at each safety level.
+// A head that is not known yet is zeroed.
+// A safe head whose tail was invalidated, and awaits a replacement block during a reorg, is zeroed too,
+// and flagged as invalidated.
+type ChainHeads struct {
+	Unsafe      types.BlockSeal
+	CrossUnsafe types.BlockSeal
+	LocalSafe   types.DerivedBlockSealPair
+	CrossSafe   types.DerivedBlockSealPair
+
+	LocalSafeInvalidated bool
+	CrossSafeInvalidated bool
+}
+
+// AllChainHeads is a snapshot of the heads of every chain in the dependency set.
+type AllChainHeads struct {
+	chains []eth.ChainID
+	heads  map[eth.ChainID]ChainHeads
+}
+
+// Chains returns the chains in the snapshot, in dependency-set order.
+func (a AllChainHeads) Chains() []eth.ChainID {
+	return append([]eth.ChainID(nil), a.chains...)
+}
+
+// Heads returns the heads of the given chain, or false if the chain is not part of the snapshot.
+func (a AllChainHeads) Heads(chainID eth.ChainID) (ChainHeads, bool) {
+	h, ok := a.heads[chainID]
+	return h, ok
+}
+
+// HeadsSnapshot captures the heads of every chain in the dependency set.
+// Each head is read under the lock of the DB that holds it, and the heads of a chain are read
+// from the safest to the least safe, such that, while heads only move forward,
+// the heads of a chain are consistent with each other: e.g. cross-safe never exceeds local-safe.
+// This is a best-effort point-in-time view, for reporting: it is not a transaction over all DBs,
+// and a chain may progress while other chains are being read.
+func (db *ChainsDB) HeadsSnapshot() (AllChainHeads, error) {
+	chains := db.depSet.Chains()
+	out := AllChainHeads{
+		chains: chains,
+		heads:  make(map[eth.ChainID]ChainHeads, len(chains)),
+	}
+	for _, chain := range chains {
+		heads, err := db.chainHeads(chain)
+		if err != nil {
+			return AllChainHeads{}, fmt.Errorf("failed to snapshot heads of chain %s: %w", chain, err)
+		}
+		out.heads[chain] = heads
+	}
+	return out, nil
+}
+
+func (db *ChainsDB) chainHeads(chain eth.ChainID) (heads ChainHeads, err error) {
+	// ignoreFuture treats a head that is not known yet as zeroed
+	ignoreFuture := func(err error) error {
+		if errors.Is(err, types.ErrFuture) {
+			return nil
+		}
+		return err
+	}
+	if heads.CrossSafe, err = db.CrossSafe(chain); errors.Is(err, types.ErrAwaitReplacementBlock) {
+		heads.CrossSafe, heads.CrossSafeInvalidated = types.DerivedBlockSealPair{}, true
+	} else if ignoreFuture(err) != nil {
+		return ChainHeads{}, fmt.Errorf("failed to read cross-safe: %w", err)
+	}
+	if heads.LocalSafe, err = db.LocalSafe(chain); errors.Is(err, types.ErrAwaitReplacementBlock) {
+		heads.LocalSafe, heads.LocalSafeInvalidated = types.DerivedBlockSealPair{}, true
+	} else if ignoreFuture(err) != nil {
+		return ChainHeads{}, fmt.Errorf("failed to read local-safe: %w", err)
+	}
+	// Without cross-unsafe data, cross-unsafe falls back to cross-safe, which may be awaiting a replacement.
+	if heads.CrossUnsafe, err = db.CrossUnsafe(chain); errors.Is(err, types.ErrAwaitReplacementBlock) {
+		heads.CrossUnsafe = types.BlockSeal{}
+	} else if ignoreFuture(err) != nil {
+		return ChainHeads{}, fmt.Errorf("failed to read cross-unsafe: %w", err)
+	}
+	if heads.Unsafe, err = db.LocalUnsafe(chain); ignoreFuture(err) != nil {
+		return ChainHeads{}, fmt.Errorf("failed to read unsafe: %w", err)
+	}
+	return heads, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestHeadsSnapshot(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelInfo)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chains := []eth.ChainID{eth.ChainIDFromUInt64(900), eth.ChainIDFromUInt64(901), eth.ChainIDFromUInt64(902)}
+	for _, chain := range chains {
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+		chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossUnsafeTracker(chain)
+	}
+
+	// nothing is known yet
+	snapshot, err := chainDB.HeadsSnapshot()
+	require.NoError(t, err)
+	require.Equal(t, chains, snapshot.Chains())
+	heads, ok := snapshot.Heads(chains[0])
+	require.True(t, ok)
+	require.Equal(t, ChainHeads{}, heads)
+	_, ok = snapshot.Heads(eth.ChainIDFromUInt64(123))
+	require.False(t, ok)
+
+	// advance the chains concurrently, each head after the less safe heads
+	const blocks = 100
+	var wg sync.WaitGroup
+	for _, chain := range chains[:2] {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", 0)))
+		wg.Add(1)
+		go func(chain eth.ChainID) {
+			defer wg.Done()
+			for i := uint64(1); i <= blocks; i++ {
+				if err := chainDB.SealBlock(chain, testRef("L2", i)); err != nil {
+					t.Error(err)
+					return
+				}
+				chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+				if err := chainDB.UpdateCrossUnsafe(chain, types.BlockSealFromRef(testRef("L2", i))); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := chainDB.UpdateCrossSafe(chain, testRef("L1", i), testRef("L2", i)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(chain)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	requireConsistent := func(snapshot AllChainHeads) {
+		for _, chain := range chains {
+			heads, ok := snapshot.Heads(chain)
+			require.True(t, ok)
+			require.LessOrEqual(t, heads.CrossSafe.Derived.Number, heads.LocalSafe.Derived.Number)
+			require.LessOrEqual(t, heads.LocalSafe.Derived.Number, heads.Unsafe.Number)
+			require.LessOrEqual(t, heads.CrossSafe.Derived.Number, heads.CrossUnsafe.Number)
+			require.LessOrEqual(t, heads.CrossUnsafe.Number, heads.Unsafe.Number)
+		}
+	}
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		snapshot, err := chainDB.HeadsSnapshot()
+		require.NoError(t, err)
+		requireConsistent(snapshot)
+	}
+
+	snapshot, err = chainDB.HeadsSnapshot()
+	require.NoError(t, err)
+	requireConsistent(snapshot)
+	for _, chain := range chains[:2] {
+		heads, _ := snapshot.Heads(chain)
+		expected := types.BlockSealFromRef(testRef("L2", blocks))
+		require.Equal(t, expected, heads.Unsafe)
+		require.Equal(t, expected, heads.CrossUnsafe)
+		require.Equal(t, expected, heads.LocalSafe.Derived)
+		require.Equal(t, expected, heads.CrossSafe.Derived)
+	}
+	heads, _ = snapshot.Heads(chains[2])
+	require.Equal(t, ChainHeads{}, heads)
+}
+
+func TestHeadsSnapshotInvalidated(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelInfo)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	crossDBs := make(map[eth.ChainID]CrossDerivedFromStorage)
+	for _, chain := range []eth.ChainID{chainA, chainB, eth.ChainIDFromUInt64(902)} {
+		crossDB := newTestDerivedFromDB(t)
+		crossDBs[chain] = crossDB
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+		chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossDerivedFromDB(chain, crossDB)
+		chainDB.AddCrossUnsafeTracker(chain)
+		for i := uint64(0); i <= 3; i++ {
+			require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+			chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+			require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", i), testRef("L2", i)))
+		}
+	}
+	// a reorg invalidates the tail of chain A, in both the local-safe and the cross-safe DB
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 3), Derived: testRef("L2", 3)}
+	require.NoError(t, chainDB.InvalidateLocalSafe(chainA, invalidated))
+	require.NoError(t, crossDBs[chainA].RewindAndInvalidate(invalidated))
+
+	snapshot, err := chainDB.HeadsSnapshot()
+	require.NoError(t, err)
+	heads, ok := snapshot.Heads(chainA)
+	require.True(t, ok)
+	require.True(t, heads.LocalSafeInvalidated)
+	require.True(t, heads.CrossSafeInvalidated)
+	require.Equal(t, types.DerivedBlockSealPair{}, heads.LocalSafe)
+	require.Equal(t, types.DerivedBlockSealPair{}, heads.CrossSafe)
+
+	// the other chains are still snapshotted
+	heads, ok = snapshot.Heads(chainB)
+	require.True(t, ok)
+	require.False(t, heads.LocalSafeInvalidated)
+	require.False(t, heads.CrossSafeInvalidated)
+	require.Equal(t, types.BlockSealFromRef(testRef("L2", 3)), heads.LocalSafe.Derived)
+	require.Equal(t, types.BlockSealFromRef(testRef("L2", 3)), heads.CrossSafe.Derived)
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// InvalidateWithDependents invalidates the local-safe block of the given chain, like InvalidateLocalSafe,
+// and plans the invalidation of the cross-safe blocks of other chains that depend on it:
+// blocks that execute a message initiated at or after the invalidated block,
+// or that execute a message of another dependent block, transitively.
+//
+// The plan maps each dependent chain to its first dependent block, and the L1 block it was derived from.
+// The plan is returned for review, and can be applied with ApplyInvalidationPlan.
+// The given chain is only invalidated if the plan could be computed.
+func (db *ChainsDB) InvalidateWithDependents(chainID eth.ChainID, invalidated types.DerivedBlockRefPair) (map[eth.ChainID]types.DerivedBlockSealPair, error) {
+	plan, err := db.planDependentInvalidations(chainID, types.BlockSealFromRef(invalidated.Derived))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan invalidation of dependents of %s on chain %s: %w", invalidated.Derived, chainID, err)
+	}
+	if err := db.invalidateSafe(chainID, invalidated); err != nil {
+		return nil, fmt.Errorf("failed to invalidate %s on chain %s: %w", invalidated.Derived, chainID, err)
+	}
+	return plan, nil
+}
+
+// ApplyInvalidationPlan invalidates the planned blocks, see InvalidateWithDependents.
+// Chains are invalidated in order of chain ID, and this stops at the first failure.
+func (db *ChainsDB) ApplyInvalidationPlan(plan map[eth.ChainID]types.DerivedBlockSealPair) error {
+	chains := make([]eth.ChainID, 0, len(plan))
+	for chain := range plan {
+		chains = append(chains, chain)
+	}
+	slices.SortFunc(chains, eth.ChainID.Cmp)
+	for _, chain := range chains {
+		candidate, err := db.derivedRefPair(chain, plan[chain])
+		if err != nil {
+			return fmt.Errorf("failed to prepare invalidation of chain %s: %w", chain, err)
+		}
+		if err := db.invalidateSafe(chain, candidate); err != nil {
+			return fmt.Errorf("failed to invalidate %s on chain %s: %w", candidate.Derived, chain, err)
+		}
+	}
+	return nil
+}
+
+// planDependentInvalidations finds the first dependent block of each chain,
+// by propagating the invalidation from chain to chain, until no earlier dependent blocks are found.
+func (db *ChainsDB) planDependentInvalidations(chainID eth.ChainID, invalidated types.BlockSeal) (map[eth.ChainID]types.DerivedBlockSealPair, error) {
+	targets := map[eth.ChainID]types.BlockSeal{chainID: invalidated}
+	queue := []eth.ChainID{chainID}
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+		for _, chain := range db.depSet.Chains() {
+			if chain == src || chain == chainID {
+				continue
+			}
+			dep, ok, err := db.firstDependentBlock(chain, src, targets[src])
+			if err != nil {
+				return nil, fmt.Errorf("failed to find blocks of chain %s that depend on chain %s: %w", chain, src, err)
+			}
+			if !ok {
+				continue
+			}
+			// Only propagate if this moves the invalidation of the chain back.
+			if prev, exists := targets[chain]; exists && prev.Number <= dep.Number {
+				continue
+			}
+			targets[chain] = dep
+			queue = append(queue, chain)
+		}
+	}
+	delete(targets, chainID)
+
+	plan := make(map[eth.ChainID]types.DerivedBlockSealPair, len(targets))
+	for chain, target := range targets {
+		localDB, ok := db.localDBs.Get(chain)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
+		}
+		derivedFrom, err := localDB.DerivedFrom(target.ID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to find local-safe source of %s on chain %s: %w", target, chain, err)
+		}
+		plan[chain] = types.DerivedBlockSealPair{DerivedFrom: derivedFrom, Derived: target}
+	}
+	return plan, nil
+}
+
+// firstDependentBlock returns the first cross-safe block of the chain that executes a message
+// initiated by the src chain at or after the given block.
+// Blocks older than the given block cannot execute its messages, and are not searched:
+// the search starts at the first cross-safe block at or after the timestamp of the given block,
+// and stops at the first dependent block.
+func (db *ChainsDB) firstDependentBlock(chain eth.ChainID, src eth.ChainID, since types.BlockSeal) (types.BlockSeal, bool, error) {
+	crossDB, ok := db.crossDBs.Get(chain)
+	if !ok {
+		return types.BlockSeal{}, false, nil // nothing is cross-safe yet
+	}
+	logDB, ok := db.logDBs.Get(chain)
+	if !ok {
+		return types.BlockSeal{}, false, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
+	}
+	srcIndex, err := db.depSet.ChainIndexFromID(src)
+	if err != nil {
+		return types.BlockSeal{}, false, err
+	}
+	start, err := crossDB.FirstDerivedWhere(func(derived types.BlockSeal) bool {
+		return derived.Timestamp >= since.Timestamp
+	})
+	if errors.Is(err, types.ErrFuture) {
+		return types.BlockSeal{}, false, nil // nothing is cross-safe at or after the given block
+	} else if err != nil {
+		return types.BlockSeal{}, false, fmt.Errorf("failed to find first cross-safe block at or after %s: %w", since, err)
+	}
+	for block := start.Derived; ; {
+		_, _, execMsgs, err := logDB.OpenBlock(block.Number)
+		if err != nil {
+			return types.BlockSeal{}, false, fmt.Errorf("failed to open block %s: %w", block, err)
+		}
+		for _, msg := range execMsgs {
+			if msg.Chain == srcIndex && msg.BlockNum >= since.Number {
+				return block, true, nil
+			}
+		}
+		next, err := crossDB.NextDerived(block.ID())
+		if errors.Is(err, types.ErrFuture) {
+			return types.BlockSeal{}, false, nil // reached the cross-safe head
+		} else if err != nil {
+			return types.BlockSeal{}, false, fmt.Errorf("failed to find cross-safe block after %s: %w", block, err)
+		}
+		block = next.Derived
+	}
+}
+
+// derivedRefPair attaches the parents of the derived and derived-from blocks, as known in the local-safe DB.
+func (db *ChainsDB) derivedRefPair(chain eth.ChainID, pair types.DerivedBlockSealPair) (types.DerivedBlockRefPair, error) {
+	localDB, ok := db.localDBs.Get(chain)
+	if !ok {
+		return types.DerivedBlockRefPair{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
+	}
+	prevDerived, err := localDB.PreviousDerived(pair.Derived.ID())
+	if err != nil {
+		return types.DerivedBlockRefPair{}, fmt.Errorf("failed to find parent of %s: %w", pair.Derived, err)
+	}
+	prevDerivedFrom, err := localDB.PreviousDerivedFrom(pair.DerivedFrom.ID())
+	if err != nil && !errors.Is(err, types.ErrPreviousToFirst) {
+		return types.DerivedBlockRefPair{}, fmt.Errorf("failed to find parent of %s: %w", pair.DerivedFrom, err)
+	}
+	return types.DerivedBlockRefPair{
+		DerivedFrom: pair.DerivedFrom.ForceWithParent(prevDerivedFrom.ID()),
+		Derived:     pair.Derived.ForceWithParent(prevDerived.ID()),
+	}, nil
+}
+
+// invalidateSafe rewinds the cross-safe DB to before the candidate, if the candidate was cross-safe,
+// and then invalidates the candidate in the local-safe DB.
+func (db *ChainsDB) invalidateSafe(chain eth.ChainID, candidate types.DerivedBlockRefPair) error {
+	if _, ok := db.crossDBs.Get(chain); ok {
+		if err := db.CrossRewind(chain, candidate); err != nil {
+			return err
+		}
+	}
+	return db.InvalidateLocalSafe(chain, candidate)
+}
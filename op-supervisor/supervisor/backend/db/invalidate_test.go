@@ -0,0 +1,144 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/fromda"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// openCountingLogDB records the blocks that are opened.
+type openCountingLogDB struct {
+	LogStorage
+	opened []uint64
+}
+
+func (db *openCountingLogDB) OpenBlock(blockNum uint64) (ref eth.BlockRef, logCount uint32, execMsgs map[uint32]*types.ExecutingMessage, err error) {
+	db.opened = append(db.opened, blockNum)
+	return db.LogStorage.OpenBlock(blockNum)
+}
+
+func TestInvalidateWithDependents(t *testing.T) {
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	kinds := map[eth.ChainID]string{chainA: "A", chainB: "B", chainC: "C"}
+
+	// execMsgs maps chain and block number to the message executed in that block
+	type blockKey struct {
+		chain eth.ChainID
+		num   uint64
+	}
+	setup := func(t *testing.T, execMsgs map[blockKey]*types.ExecutingMessage) (*ChainsDB, map[eth.ChainID]*fromda.DB, map[eth.ChainID]*fromda.DB, map[eth.ChainID]*openCountingLogDB) {
+		chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+		chainsDB.AttachEmitter(event.NoopEmitter{})
+		localDBs := make(map[eth.ChainID]*fromda.DB)
+		crossDBs := make(map[eth.ChainID]*fromda.DB)
+		logDBs := make(map[eth.ChainID]*openCountingLogDB)
+		for chain, kind := range kinds {
+			logDBs[chain] = &openCountingLogDB{LogStorage: newTestLogDB(t)}
+			chainsDB.AddLogDB(chain, logDBs[chain])
+			localDBs[chain] = newTestDerivedFromDB(t)
+			crossDBs[chain] = newTestDerivedFromDB(t)
+			chainsDB.AddLocalDerivedFromDB(chain, localDBs[chain])
+			chainsDB.AddCrossDerivedFromDB(chain, crossDBs[chain])
+			for i := uint64(0); i <= 4; i++ {
+				block := testRef(kind, i)
+				if msg, ok := execMsgs[blockKey{chain, i}]; ok {
+					logHash := crypto.Keccak256Hash([]byte(kind), block.Hash[:])
+					require.NoError(t, chainsDB.AddLog(chain, logHash, block.ParentID(), 0, msg))
+				}
+				require.NoError(t, chainsDB.SealBlock(chain, block))
+				require.NoError(t, localDBs[chain].AddDerived(testRef("L1", i), block))
+				require.NoError(t, crossDBs[chain].AddDerived(testRef("L1", i), block))
+			}
+		}
+		return chainsDB, localDBs, crossDBs, logDBs
+	}
+	execMsg := func(src eth.ChainID, num uint64) *types.ExecutingMessage {
+		ref := testRef(kinds[src], num)
+		srcIndex, err := sampleDepSet(t).ChainIndexFromID(src)
+		require.NoError(t, err)
+		return &types.ExecutingMessage{
+			Chain:     srcIndex,
+			BlockNum:  num,
+			Timestamp: ref.Time,
+			Hash:      crypto.Keccak256Hash([]byte(kinds[src]), ref.Hash[:]),
+		}
+	}
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 2), Derived: testRef("A", 2)}
+
+	t.Run("no dependents", func(t *testing.T) {
+		chainsDB, localDBs, crossDBs, _ := setup(t, map[blockKey]*types.ExecutingMessage{
+			// executing an older message is not affected
+			{chainB, 3}: execMsg(chainA, 1),
+		})
+		plan, err := chainsDB.InvalidateWithDependents(chainA, invalidated)
+		require.NoError(t, err)
+		require.Empty(t, plan)
+
+		_, err = localDBs[chainA].Latest()
+		require.ErrorIs(t, err, types.ErrAwaitReplacementBlock)
+		crossSafe, err := crossDBs[chainA].Latest()
+		require.NoError(t, err)
+		require.Equal(t, testRef("A", 1).ID(), crossSafe.Derived.ID(), "cross-safe rewound to before the invalidated block")
+	})
+
+	t.Run("dependents", func(t *testing.T) {
+		chainsDB, localDBs, crossDBs, _ := setup(t, map[blockKey]*types.ExecutingMessage{
+			// B depends on the invalidated block of A
+			{chainB, 3}: execMsg(chainA, 2),
+			{chainB, 4}: execMsg(chainA, 3),
+			// C depends on the dependent block of B
+			{chainC, 4}: execMsg(chainB, 3),
+		})
+		plan, err := chainsDB.InvalidateWithDependents(chainA, invalidated)
+		require.NoError(t, err)
+		require.Equal(t, map[eth.ChainID]types.DerivedBlockSealPair{
+			chainB: {DerivedFrom: types.BlockSealFromRef(testRef("L1", 3)), Derived: types.BlockSealFromRef(testRef("B", 3))},
+			chainC: {DerivedFrom: types.BlockSealFromRef(testRef("L1", 4)), Derived: types.BlockSealFromRef(testRef("C", 4))},
+		}, plan)
+
+		// the plan is not applied yet
+		crossSafe, err := crossDBs[chainB].Latest()
+		require.NoError(t, err)
+		require.Equal(t, testRef("B", 4).ID(), crossSafe.Derived.ID())
+
+		require.NoError(t, chainsDB.ApplyInvalidationPlan(plan))
+		for chain, pair := range plan {
+			invalid, err := localDBs[chain].Invalidated()
+			require.NoError(t, err)
+			require.Equal(t, pair, invalid)
+			crossSafe, err := crossDBs[chain].Latest()
+			require.NoError(t, err)
+			require.Equal(t, pair.Derived.Number-1, crossSafe.Derived.Number)
+		}
+	})
+	t.Run("bounded search", func(t *testing.T) {
+		chainsDB, _, _, logDBs := setup(t, map[blockKey]*types.ExecutingMessage{
+			{chainB, 3}: execMsg(chainA, 2),
+			{chainB, 4}: execMsg(chainA, 3),
+		})
+		plan, err := chainsDB.InvalidateWithDependents(chainA, invalidated)
+		require.NoError(t, err)
+		require.Equal(t, map[eth.ChainID]types.DerivedBlockSealPair{
+			chainB: {DerivedFrom: types.BlockSealFromRef(testRef("L1", 3)), Derived: types.BlockSealFromRef(testRef("B", 3))},
+		}, plan)
+		// the search of B starts at the timestamp of the invalidated block, and stops at the first dependent block
+		require.Equal(t, []uint64{2, 3}, logDBs[chainB].opened)
+		// older blocks are never opened
+		for chain, logDB := range logDBs {
+			for _, num := range logDB.opened {
+				require.GreaterOrEqual(t, num, invalidated.Derived.Number, "chain %s", chain)
+			}
+		}
+	})
+}
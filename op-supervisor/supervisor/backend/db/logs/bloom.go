@@ -0,0 +1,162 @@
+package logs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+const (
+	// bloomBitsPerLog is the number of filter bits per log in a block.
+	// With bloomHashes bits set per log, this results in a false-positive rate of about 0.25%.
+	bloomBitsPerLog = 16
+	// bloomHashes is the number of bits that is set per log.
+	bloomHashes = 4
+	// bloomMinBytes is the minimum size of a bloom filter.
+	bloomMinBytes = 8
+)
+
+// logBloom is a bloom filter of the log hashes of a block.
+// Log hashes are already uniformly distributed, so the filter bits are taken directly from the hash.
+type logBloom []byte
+
+func newLogBloom(logHashes []common.Hash) logBloom {
+	size := bloomMinBytes
+	for size*8 < len(logHashes)*bloomBitsPerLog {
+		size *= 2
+	}
+	b := make(logBloom, size)
+	for _, h := range logHashes {
+		for i := 0; i < bloomHashes; i++ {
+			bit := b.bit(h, i)
+			b[bit/8] |= 1 << (bit % 8)
+		}
+	}
+	return b
+}
+
+// bit returns the i-th filter bit of the given log hash
+func (b logBloom) bit(h common.Hash, i int) uint32 {
+	return binary.BigEndian.Uint32(h[i*4:i*4+4]) % uint32(len(b)*8)
+}
+
+// test returns false if the log hash is definitely not in the filter.
+func (b logBloom) test(h common.Hash) bool {
+	for i := 0; i < bloomHashes; i++ {
+		bit := b.bit(h, i)
+		if b[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// blockBlooms maintains in-memory bloom filters of the log hashes of recently sealed blocks.
+type blockBlooms struct {
+	// pending holds the log hashes of the block that is being built.
+	pending []common.Hash
+	// pendingComplete is false if the pending block had logs before blooms were enabled,
+	// in which case its bloom is incomplete, and not retained when the block is sealed.
+	pendingComplete bool
+	// sealed holds the blooms of the most recently sealed blocks, by block number.
+	sealed lru.BasicLRU[uint64, logBloom]
+}
+
+func (b *blockBlooms) addLog(logHash common.Hash) {
+	b.pending = append(b.pending, logHash)
+}
+
+func (b *blockBlooms) sealBlock(blockNum uint64) {
+	if b.pendingComplete {
+		b.sealed.Add(blockNum, newLogBloom(b.pending))
+	}
+	b.pending = b.pending[:0]
+	b.pendingComplete = true
+}
+
+// rewind drops the blooms of any blocks after the given block number.
+func (b *blockBlooms) rewind(blockNum uint64) {
+	for _, n := range b.sealed.Keys() {
+		if n > blockNum {
+			b.sealed.Remove(n)
+		}
+	}
+	b.pending = b.pending[:0]
+	b.pendingComplete = true
+}
+
+// truncatePending drops the log hashes of the block that is being built, after the first n logs.
+func (b *blockBlooms) truncatePending(n int) {
+	if n < len(b.pending) {
+		b.pending = b.pending[:n]
+	}
+}
+
+// EnableBlooms starts maintaining a bloom filter of the log hashes of each block that is sealed from now on,
+// retaining the blooms of up to capacity most recently sealed blocks. See MayContain.
+// Blooms are kept in memory only: blocks that were sealed before blooms were enabled do not have a bloom.
+func (db *DB) EnableBlooms(capacity int) {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	db.blooms = &blockBlooms{
+		// If the block in progress already has logs, we cannot tell what they are without reading them back.
+		pendingComplete: db.lastEntryContext.logsSince == 0,
+		sealed:          lru.NewBasicLRU[uint64, logBloom](capacity),
+	}
+}
+
+// MayContain returns whether the sealed block with the given number contains a log with the given hash, at any index.
+// If the block has a bloom filter, see EnableBlooms, a hash that is definitely absent is rejected without reading the DB.
+// A bloom filter may have false positives, in which case, and for blocks without a bloom filter,
+// this falls back to reading the logs of the block. The result is thus always exact,
+// but only the negative path is fast: there are no false negatives, and no false positives are returned.
+// If the block is not sealed yet, then ErrFuture is returned.
+func (db *DB) MayContain(blockNum uint64, logHash common.Hash) (bool, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if db.blooms != nil {
+		// Peek, as Get would update the recency of the entry, which is not safe under a read-lock.
+		if bloom, ok := db.blooms.sealed.Peek(blockNum); ok && !bloom.test(logHash) {
+			return false, nil
+		}
+	}
+	if blockNum == 0 {
+		return false, nil // no logs in block 0
+	}
+	iter, err := db.newIteratorAt(blockNum-1, 0)
+	if errors.Is(err, types.ErrFuture) {
+		return false, fmt.Errorf("block %d is not known yet: %w", blockNum, types.ErrFuture)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to find parent of block %d: %w", blockNum, err)
+	}
+	// The logs of a block are only known to be part of it once the block is sealed,
+	// so continue past a match, until the seal of the block is found.
+	found := false
+	for {
+		err := iter.NextInitMsg()
+		if errors.Is(err, types.ErrFuture) {
+			// Ran out of data. The block is only complete if we saw it get sealed.
+			if _, n, ok := iter.SealedBlock(); !ok || n < blockNum {
+				return false, fmt.Errorf("block %d is not sealed yet: %w", blockNum, types.ErrFuture)
+			}
+			return found, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to read logs of block %d: %w", blockNum, err)
+		}
+		if _, n, ok := iter.SealedBlock(); !ok {
+			panic("expected block")
+		} else if n > blockNum-1 {
+			return found, nil // the block was sealed, and we moved on to the logs of the next block
+		}
+		h, _, ok := iter.InitMessage()
+		if !ok {
+			panic("expected init message")
+		}
+		found = found || h == logHash
+	}
+}
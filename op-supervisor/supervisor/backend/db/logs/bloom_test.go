@@ -0,0 +1,130 @@
+package logs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func newMemDB(t testing.TB) *DB {
+	store := &entrydb.MemEntryStore[EntryType, Entry]{}
+	db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store, false)
+	require.NoError(t, err)
+	return db
+}
+
+// blockLogHash is the hash of log i in block n
+func blockLogHash(n uint64, i int) common.Hash {
+	return createHash(int(n)*1000 + i)
+}
+
+func TestMayContain(t *testing.T) {
+	db := newMemDB(t)
+	logCounts := map[uint64]int{1: 2, 2: 2, 3: 50, 4: 0, 5: 300}
+
+	require.NoError(t, db.SealBlock(common.Hash{}, createID(0), 5000))
+	addLogs := func(n uint64, from, to int) {
+		for i := from; i < to; i++ {
+			require.NoError(t, db.AddLog(blockLogHash(n, i), createID(int(n-1)), uint32(i), nil))
+		}
+	}
+	seal := func(n uint64) {
+		require.NoError(t, db.SealBlock(createHash(int(n-1)), createID(int(n)), 5000+n))
+	}
+	// block 1 is sealed before blooms are enabled
+	addLogs(1, 0, logCounts[1])
+	seal(1)
+	// blooms are enabled while block 2 is in progress
+	addLogs(2, 0, 1)
+	db.EnableBlooms(10)
+	addLogs(2, 1, logCounts[2])
+	seal(2)
+	for n := uint64(3); n <= 5; n++ {
+		addLogs(n, 0, logCounts[n])
+		seal(n)
+	}
+
+	require.False(t, db.blooms.sealed.Contains(1), "sealed before blooms were enabled")
+	require.False(t, db.blooms.sealed.Contains(2), "incomplete bloom")
+	for n := uint64(3); n <= 5; n++ {
+		require.True(t, db.blooms.sealed.Contains(n))
+	}
+
+	requireMayContain := func(t *testing.T) {
+		for n, count := range logCounts {
+			for i := 0; i < count; i++ {
+				ok, err := db.MayContain(n, blockLogHash(n, i))
+				require.NoError(t, err)
+				require.True(t, ok, "no false negative for log %d of block %d", i, n)
+			}
+			// a log of another block
+			ok, err := db.MayContain(n, blockLogHash(n+1, 0))
+			require.NoError(t, err)
+			require.False(t, ok)
+			// a log that was never added
+			ok, err = db.MayContain(n, blockLogHash(n, count))
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+		ok, err := db.MayContain(0, blockLogHash(1, 0))
+		require.NoError(t, err)
+		require.False(t, ok, "no logs in block 0")
+	}
+	requireMayContain(t)
+
+	_, err := db.MayContain(6, blockLogHash(6, 0))
+	require.ErrorIs(t, err, types.ErrFuture)
+
+	// logs of a block in progress are not included
+	addLogs(6, 0, 1)
+	_, err = db.MayContain(6, blockLogHash(6, 0))
+	require.ErrorIs(t, err, types.ErrFuture)
+
+	require.NoError(t, db.Rewind(createID(4)))
+	require.False(t, db.blooms.sealed.Contains(5))
+	_, err = db.MayContain(5, blockLogHash(5, 0))
+	require.ErrorIs(t, err, types.ErrFuture)
+	delete(logCounts, 5)
+	requireMayContain(t)
+
+	// blooms are maintained for blocks sealed after the rewind
+	logCounts[5] = 3
+	addLogs(5, 0, logCounts[5])
+	seal(5)
+	require.True(t, db.blooms.sealed.Contains(5))
+	requireMayContain(t)
+}
+
+func BenchmarkMayContain(b *testing.B) {
+	const logCount = 1000
+	for _, blooms := range []bool{false, true} {
+		b.Run(fmt.Sprintf("blooms=%v", blooms), func(b *testing.B) {
+			db := newMemDB(b)
+			if blooms {
+				db.EnableBlooms(16)
+			}
+			require.NoError(b, db.SealBlock(common.Hash{}, createID(0), 5000))
+			for i := 0; i < logCount; i++ {
+				require.NoError(b, db.AddLog(blockLogHash(1, i), createID(0), uint32(i), nil))
+			}
+			require.NoError(b, db.SealBlock(createHash(0), createID(1), 5001))
+			absent := blockLogHash(2, 0)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ok, err := db.MayContain(1, absent)
+				if err != nil || ok {
+					b.Fatal("expected absent log", err)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -39,6 +40,9 @@ type DB struct {
 	rwLock sync.RWMutex
 
 	lastEntryContext logContext
+
+	// blooms, if not nil, tracks bloom filters of the logs of recently sealed blocks
+	blooms *blockBlooms
 }
 
 func NewFromFile(logger log.Logger, m Metrics, path string, trimToLastSealed bool) (*DB, error) {
@@ -248,6 +252,65 @@ func (db *DB) OpenBlock(blockNum uint64) (ref eth.BlockRef, logCount uint32, exe
 	return
 }
 
+// CountLogs returns the number of logs of the given sealed block.
+// Unlike OpenBlock, this does not collect the executing messages, and only reads the entries
+// from the last search checkpoint before the block was sealed, rather than from the start of the block.
+// Returns ErrFuture if the block is not sealed yet, or beyond the block that is being built.
+func (db *DB) CountLogs(blockNum uint64) (uint32, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if db.lastEntryContext.nextEntryIndex == 0 {
+		return 0, fmt.Errorf("cannot count logs of block %d in empty DB: %w", blockNum, types.ErrFuture)
+	}
+	head := db.lastEntryContext.blockNum
+	if blockNum == head+1 {
+		return 0, fmt.Errorf("block %d is not sealed yet, logs may still be added: %w", blockNum, types.ErrFuture)
+	}
+	if blockNum > head {
+		return 0, fmt.Errorf("block %d is beyond head %d: %w", blockNum, head, types.ErrFuture)
+	}
+	if blockNum == 0 {
+		// The logs of the first block are not recorded, see OpenBlock.
+		return 0, nil
+	}
+	// The logs of the block are recorded after the seal of the parent block.
+	// Find the latest checkpoint before the block is sealed, and count the remaining logs from there.
+	checkpointIdx, err := db.searchCheckpoint(blockNum-1, math.MaxUint32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find checkpoint before block %d: %w", blockNum, err)
+	}
+	iter := db.newIterator(checkpointIdx)
+	iter.current.need.Add(FlagCanonicalHash)
+	defer func() {
+		db.m.RecordDBSearchEntriesRead(iter.entriesRead)
+	}()
+	var logCount uint32
+	err = iter.TraverseConditional(func(state IteratorState) error {
+		_, n, ok := state.SealedBlock()
+		if !ok {
+			return nil
+		}
+		switch {
+		case n+1 == blockNum:
+			logCount = iter.current.logsSince
+			return nil
+		case n == blockNum:
+			return types.ErrStop
+		case n > blockNum:
+			return fmt.Errorf("expected to run into block %d, but did not find it, found %d: %w", blockNum, n, types.ErrDataCorruption)
+		default:
+			return nil
+		}
+	})
+	if !errors.Is(err, types.ErrStop) {
+		if err == nil {
+			err = types.ErrFuture
+		}
+		return 0, fmt.Errorf("failed to count logs of block %d: %w", blockNum, err)
+	}
+	return logCount, nil
+}
+
 // LatestSealedBlock returns the block ID of the block that was last sealed,
 // or ok=false if there is no sealed block (i.e. empty DB)
 func (db *DB) LatestSealedBlock() (id eth.BlockID, ok bool) {
@@ -275,6 +338,29 @@ func (db *DB) Get(blockNum uint64, logIdx uint32) (common.Hash, error) {
 	return hash, err
 }
 
+// GetLog returns the hash and executing message (if any) of the log at the specified blockNum
+// (the block that includes the log) and logIdx. Unlike OpenBlock, this only reads the entries of the one log.
+// Returns ErrFuture if the block is not sealed yet, like Contains does,
+// and ErrConflict if the block is known but does not have a log at logIdx.
+func (db *DB) GetLog(blockNum uint64, logIdx uint32) (logHash common.Hash, execMsg *types.ExecutingMessage, err error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if db.lastEntryContext.nextEntryIndex == 0 {
+		return common.Hash{}, nil, fmt.Errorf("cannot get log of block %d in empty DB: %w", blockNum, types.ErrFuture)
+	}
+	if head := db.lastEntryContext.blockNum; blockNum > head {
+		return common.Hash{}, nil, fmt.Errorf("block %d is beyond head %d: %w", blockNum, head, types.ErrFuture)
+	}
+	if blockNum == 0 {
+		return common.Hash{}, nil, fmt.Errorf("log %d not found, no logs in block 0: %w", logIdx, types.ErrConflict)
+	}
+	logHash, iter, err := db.findLogInfo(blockNum, logIdx)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return logHash, iter.ExecMessage(), nil
+}
+
 // Contains returns no error iff the specified logHash is recorded in the specified blockNum and logIdx.
 // If the log is out of reach, then ErrFuture is returned.
 // If the log is determined to conflict with the canonical chain, then ErrConflict is returned.
@@ -333,6 +419,75 @@ func (db *DB) Contains(blockNum uint64, logIdx uint32, logHash common.Hash) (typ
 	return types.BlockSeal{}, err
 }
 
+// FindLogByHash returns every position of a sealed block where a log with the given hash was recorded,
+// in order, or an empty slice if there is none. Logs of the block that is being built are not included.
+// The logs are not indexed by hash, so this scans the full DB: O(n) entry reads.
+// See FindLogByHashInRange to limit the scan to a range of blocks.
+func (db *DB) FindLogByHash(logHash common.Hash) ([]types.LogPosition, error) {
+	return db.FindLogByHashInRange(logHash, 0, math.MaxUint64)
+}
+
+// FindLogByHashInRange is like FindLogByHash, but only returns the logs of the blocks from fromBlock
+// up to and including toBlock, and only scans the entries of those blocks.
+func (db *DB) FindLogByHashInRange(logHash common.Hash, fromBlock, toBlock uint64) ([]types.LogPosition, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	out := []types.LogPosition{}
+	if fromBlock > toBlock || db.lastEntryContext.nextEntryIndex == 0 {
+		return out, nil
+	}
+	// The logs of a block are recorded after the seal of the parent block.
+	var start entrydb.EntryIdx
+	if fromBlock > 0 {
+		idx, err := db.searchCheckpoint(fromBlock-1, 0)
+		if err == nil {
+			start = idx
+		} else if !errors.Is(err, types.ErrSkipped) {
+			return nil, fmt.Errorf("failed to find start of block %d: %w", fromBlock, err)
+		}
+	}
+	iter := db.newIterator(start)
+	iter.current.need.Add(FlagCanonicalHash)
+	defer func() {
+		db.m.RecordDBSearchEntriesRead(iter.entriesRead)
+	}()
+	// matches are the log indices of the block that is being scanned, that match the hash.
+	var matches []uint32
+	for {
+		typ, err := iter.next()
+		if errors.Is(err, types.ErrFuture) {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to scan for log %s: %w", logHash, err)
+		}
+		if iter.current.need != 0 {
+			continue
+		}
+		switch typ {
+		case TypeInitiatingEvent, TypeExecutingCheck:
+			if iter.current.logHash == logHash {
+				matches = append(matches, iter.current.logsSince-1)
+			}
+		case TypeCanonicalHash:
+			// A block was sealed, or a search-checkpoint was passed, within the logs of a block.
+			if iter.current.logsSince != 0 {
+				continue
+			}
+			num := iter.current.blockNum
+			if num >= fromBlock && num <= toBlock {
+				seal := types.BlockSeal{Hash: iter.current.blockHash, Number: num, Timestamp: iter.current.timestamp}
+				for _, logIdx := range matches {
+					out = append(out, types.LogPosition{Block: seal, LogIdx: logIdx})
+				}
+			}
+			matches = matches[:0]
+			if num >= toBlock {
+				return out, nil
+			}
+		}
+	}
+}
+
 func (db *DB) findLogInfo(blockNum uint64, logIdx uint32) (common.Hash, Iterator, error) {
 	if blockNum == 0 {
 		return common.Hash{}, nil, types.ErrConflict // no logs in block 0
@@ -366,6 +521,51 @@ func (db *DB) findLogInfo(blockNum uint64, logIdx uint32) (common.Hash, Iterator
 	return logHash, iter, nil
 }
 
+// VerifyBlockLogs re-reads the logs of the given sealed block,
+// and checks that the log indices are contiguous, starting from zero.
+// Contains relies on this ordering to locate logs.
+// The first anomaly is returned as ErrDataCorruption.
+// If the block is not sealed yet, then ErrFuture is returned.
+func (db *DB) VerifyBlockLogs(blockNum uint64) error {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	if blockNum == 0 {
+		return nil // no logs in block 0
+	}
+	iter, err := db.newIteratorAt(blockNum-1, 0)
+	if errors.Is(err, types.ErrFuture) {
+		return fmt.Errorf("block %d is not known yet: %w", blockNum, types.ErrFuture)
+	} else if err != nil {
+		return fmt.Errorf("failed to find parent of block %d: %w", blockNum, err)
+	}
+	expected := uint32(0)
+	for {
+		err := iter.NextInitMsg()
+		if errors.Is(err, types.ErrFuture) {
+			// Ran out of data. The block is only complete if we saw it get sealed.
+			if _, n, ok := iter.SealedBlock(); !ok || n < blockNum {
+				return fmt.Errorf("block %d is not sealed yet: %w", blockNum, types.ErrFuture)
+			}
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read log %d of block %d: %w", expected, blockNum, err)
+		}
+		if _, n, ok := iter.SealedBlock(); !ok {
+			panic("expected block")
+		} else if n > blockNum-1 {
+			return nil // the block was sealed, and we moved on to the logs of the next block
+		}
+		_, logIdx, ok := iter.InitMessage()
+		if !ok {
+			panic("expected init message")
+		}
+		if logIdx != expected {
+			return fmt.Errorf("expected log %d in block %d, but found log %d: %w", expected, blockNum, logIdx, types.ErrDataCorruption)
+		}
+		expected++
+	}
+}
+
 // newIteratorAt returns an iterator ready after the given sealed block number,
 // and positioned such that the next log-read on the iterator return the log with logIndex, if any.
 // It may return an ErrNotFound if the block number is unknown,
@@ -538,7 +738,13 @@ func (db *DB) SealBlock(parentHash common.Hash, block eth.BlockID, timestamp uin
 		return fmt.Errorf("failed to seal block: %w", err)
 	}
 	db.log.Trace("Sealed block", "parent", parentHash, "block", block, "timestamp", timestamp)
-	return db.flush()
+	if err := db.flush(); err != nil {
+		return err
+	}
+	if db.blooms != nil {
+		db.blooms.sealBlock(block.Number)
+	}
+	return nil
 }
 
 func (db *DB) AddLog(logHash common.Hash, parentBlock eth.BlockID, logIdx uint32, execMsg *types.ExecutingMessage) error {
@@ -549,7 +755,34 @@ func (db *DB) AddLog(logHash common.Hash, parentBlock eth.BlockID, logIdx uint32
 		return fmt.Errorf("failed to apply log: %w", err)
 	}
 	db.log.Trace("Applied log", "parentBlock", parentBlock, "logIndex", logIdx, "logHash", logHash, "executing", execMsg != nil)
-	return db.flush()
+	if err := db.flush(); err != nil {
+		return err
+	}
+	if db.blooms != nil {
+		db.blooms.addLog(logHash)
+	}
+	return nil
+}
+
+// AppendLog is like AddLog, but assigns the next log index within the block that builds on parentBlock,
+// and returns the assigned log index.
+func (db *DB) AppendLog(logHash common.Hash, parentBlock eth.BlockID, execMsg *types.ExecutingMessage) (uint32, error) {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+
+	// The number of logs since the last sealed block is the index of the next log.
+	logIdx := db.lastEntryContext.logsSince
+	if err := db.lastEntryContext.ApplyLog(parentBlock, logIdx, logHash, execMsg); err != nil {
+		return 0, fmt.Errorf("failed to apply log: %w", err)
+	}
+	db.log.Trace("Appended log", "parentBlock", parentBlock, "logIndex", logIdx, "logHash", logHash, "executing", execMsg != nil)
+	if err := db.flush(); err != nil {
+		return 0, err
+	}
+	if db.blooms != nil {
+		db.blooms.addLog(logHash)
+	}
+	return logIdx, nil
 }
 
 // Rewind the database to remove any blocks after headBlockNum
@@ -577,6 +810,44 @@ func (db *DB) Rewind(newHead eth.BlockID) error {
 	if err := db.init(true); err != nil {
 		return fmt.Errorf("failed to find new last entry context: %w", err)
 	}
+	if db.blooms != nil {
+		db.blooms.rewind(newHead.Number)
+	}
+	return nil
+}
+
+// DeleteLogsAfter drops the logs after logIdx, of the block that is being built on top of the given block,
+// for when a partial reorg invalidates only the later logs of the block.
+// As with AddLog, the given block is the parent block of the logs: it must be the latest sealed block,
+// and its seal is kept intact. An ErrConflict error is returned if the block is not the latest sealed block.
+// If there are no logs after logIdx, this is a no-op.
+func (db *DB) DeleteLogsAfter(block eth.BlockID, logIdx uint32) error {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	hash, num, ok := db.lastEntryContext.SealedBlock()
+	if !ok || db.lastEntryContext.nextEntryIndex == 0 {
+		return fmt.Errorf("cannot delete logs after %s, no sealed block: %w", block, types.ErrFuture)
+	}
+	if head := (eth.BlockID{Hash: hash, Number: num}); head != block {
+		return fmt.Errorf("cannot delete logs after %s, latest sealed block is %s: %w", block, head, types.ErrConflict)
+	}
+	if logIdx+1 >= db.lastEntryContext.logsSince {
+		return nil
+	}
+	// Position the iterator right after the last log to keep, and drop everything after it.
+	iter, err := db.newIteratorAt(block.Number, logIdx+1)
+	if err != nil {
+		return fmt.Errorf("failed to find log %d after block %s: %w", logIdx, block, err)
+	}
+	if err := db.store.Truncate(iter.NextIndex() - 1); err != nil {
+		return fmt.Errorf("failed to delete logs after %d: %w", logIdx, err)
+	}
+	if err := db.init(false); err != nil {
+		return fmt.Errorf("failed to find new last entry context: %w", err)
+	}
+	if db.blooms != nil {
+		db.blooms.truncatePending(int(logIdx) + 1)
+	}
 	return nil
 }
 
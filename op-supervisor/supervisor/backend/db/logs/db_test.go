@@ -2,9 +2,13 @@ package logs
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -574,6 +578,78 @@ func TestAddLog(t *testing.T) {
 	})
 }
 
+func TestAppendLog(t *testing.T) {
+	t.Run("SequentialIndices", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				bl15 := createID(15)
+				require.NoError(t, db.SealBlock(common.Hash{}, bl15, 5000))
+				for i := uint32(0); i < 3; i++ {
+					logIdx, err := db.AppendLog(createHash(int(i)), bl15, nil)
+					require.NoError(t, err)
+					require.Equal(t, i, logIdx)
+				}
+				// explicit-index logs and appended logs can be mixed
+				require.NoError(t, db.AddLog(createHash(3), bl15, 3, nil))
+				execMsg := types.ExecutingMessage{
+					Chain:     2,
+					BlockNum:  10,
+					LogIdx:    1,
+					Timestamp: 1234,
+					Hash:      createHash(10),
+				}
+				logIdx, err := db.AppendLog(createHash(4), bl15, &execMsg)
+				require.NoError(t, err)
+				require.Equal(t, uint32(4), logIdx)
+				require.NoError(t, db.SealBlock(bl15.Hash, createID(16), 5001))
+
+				// the index restarts in the next block
+				logIdx, err = db.AppendLog(createHash(5), createID(16), nil)
+				require.NoError(t, err)
+				require.Zero(t, logIdx)
+				require.NoError(t, db.SealBlock(createHash(16), createID(17), 5002))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				for i := 0; i < 4; i++ {
+					requireContains(t, db, 16, uint32(i), createHash(i))
+				}
+				requireContains(t, db, 16, 4, createHash(4), types.ExecutingMessage{
+					Chain:     2,
+					BlockNum:  10,
+					LogIdx:    1,
+					Timestamp: 1234,
+					Hash:      createHash(10),
+				})
+				requireContains(t, db, 17, 0, createHash(5))
+				_, logCount, _, err := db.OpenBlock(16)
+				require.NoError(t, err)
+				require.Equal(t, uint32(5), logCount)
+			})
+	})
+
+	t.Run("MismatchedParent", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				bl15 := createID(15)
+				require.NoError(t, db.SealBlock(common.Hash{}, bl15, 5000))
+				_, err := db.AppendLog(createHash(0), bl15, nil)
+				require.NoError(t, err)
+
+				_, err = db.AppendLog(createHash(1), createID(14), nil)
+				require.ErrorIs(t, err, types.ErrOutOfOrder)
+				_, err = db.AppendLog(createHash(1), eth.BlockID{Hash: createHash(100), Number: 15}, nil)
+				require.ErrorIs(t, err, types.ErrOutOfOrder)
+				require.NoError(t, db.SealBlock(bl15.Hash, createID(16), 5001))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				requireContains(t, db, 16, 0, createHash(0))
+				_, logCount, _, err := db.OpenBlock(16)
+				require.NoError(t, err)
+				require.Equal(t, uint32(1), logCount, "rejected logs must not be added")
+			})
+	})
+}
+
 func TestAddDependentLog(t *testing.T) {
 	execMsg := types.ExecutingMessage{
 		Chain:     3,
@@ -883,6 +959,71 @@ func requireExecutingMessage(t *testing.T, db *DB, blockNum uint64, logIdx uint3
 	require.NotZero(t, m.entriesReadForSearch, "Must read at least some entries to find the log")
 }
 
+func TestVerifyBlockLogs(t *testing.T) {
+	t.Run("Ordered", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				genesis := createID(0)
+				require.NoError(t, db.SealBlock(common.Hash{}, genesis, 5000))
+				// enough logs to cross a search checkpoint within the block
+				for i := uint32(0); i < 300; i++ {
+					require.NoError(t, db.AddLog(createHash(int(i)), genesis, i, nil))
+				}
+				require.NoError(t, db.SealBlock(genesis.Hash, createID(1), 5002))
+				require.NoError(t, db.SealBlock(createHash(1), createID(2), 5004))
+				require.NoError(t, db.AddLog(createHash(1000), createID(2), 0, nil))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.NoError(t, db.VerifyBlockLogs(0))
+				require.NoError(t, db.VerifyBlockLogs(1))
+				require.NoError(t, db.VerifyBlockLogs(2), "block without logs")
+				require.ErrorIs(t, db.VerifyBlockLogs(3), types.ErrFuture, "block with logs, not sealed yet")
+				require.ErrorIs(t, db.VerifyBlockLogs(4), types.ErrFuture)
+			})
+	})
+
+	// The log index is tracked by counting logs, and reset by any search checkpoint.
+	// A bad checkpoint in the middle of a block thus corrupts the log indices.
+	storeWithMidBlockCheckpoint := func(logsSince uint32) *entrydb.MemEntryStore[EntryType, Entry] {
+		store := &entrydb.MemEntryStore[EntryType, Entry]{}
+		_ = store.Append(
+			newSearchCheckpoint(0, 0, 100).encode(),
+			newCanonicalHash(createHash(300)).encode(),
+			newInitiatingEvent(createHash(1), false).encode(),
+			newSearchCheckpoint(0, logsSince, 100).encode(),
+			newCanonicalHash(createHash(300)).encode(),
+			newInitiatingEvent(createHash(2), false).encode(),
+			newSearchCheckpoint(1, 0, 101).encode(),
+			newCanonicalHash(createHash(301)).encode(),
+		)
+		return store
+	}
+	openStore := func(t *testing.T, store *entrydb.MemEntryStore[EntryType, Entry]) *DB {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, store, false)
+		require.NoError(t, err)
+		return db
+	}
+
+	t.Run("MidBlockCheckpoint", func(t *testing.T) {
+		db := openStore(t, storeWithMidBlockCheckpoint(1))
+		require.NoError(t, db.VerifyBlockLogs(1))
+	})
+
+	t.Run("MissingIndex", func(t *testing.T) {
+		db := openStore(t, storeWithMidBlockCheckpoint(2))
+		err := db.VerifyBlockLogs(1)
+		require.ErrorIs(t, err, types.ErrDataCorruption)
+		require.ErrorContains(t, err, "expected log 1 in block 1, but found log 2")
+	})
+
+	t.Run("DuplicateIndex", func(t *testing.T) {
+		db := openStore(t, storeWithMidBlockCheckpoint(0))
+		err := db.VerifyBlockLogs(1)
+		require.ErrorIs(t, err, types.ErrDataCorruption)
+		require.ErrorContains(t, err, "expected log 1 in block 1, but found log 0")
+	})
+}
+
 func TestRecoverOnCreate(t *testing.T) {
 	createDb := func(t *testing.T, store *entrydb.MemEntryStore[EntryType, Entry]) (*DB, *stubMetrics, error) {
 		logger := testlog.Logger(t, log.LvlInfo)
@@ -1167,6 +1308,10 @@ func TestRewind(t *testing.T) {
 				require.NoError(t, db.Rewind(createID(30)))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
+				// the last block is kept
+				head, ok := db.LatestSealedBlock()
+				require.True(t, ok)
+				require.Equal(t, createID(30), head)
 				requireContains(t, db, 20, 0, createHash(1))
 				requireContains(t, db, 20, 1, createHash(2))
 				// built on top of 29, these are in sealed block 30, still around
@@ -1175,6 +1320,20 @@ func TestRewind(t *testing.T) {
 			})
 	})
 
+	t.Run("AtHeadInMemory", func(t *testing.T) {
+		// Rewinding to the latest sealed block, with no entries after it, keeps the block.
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{}, false)
+		require.NoError(t, err)
+		for i := 0; i <= 3; i++ {
+			require.NoError(t, db.SealBlock(createHash(i-1), createID(i), 500+uint64(i)))
+		}
+		require.NoError(t, db.Rewind(createID(3)))
+		require.NoError(t, db.Rewind(createID(3)))
+		head, ok := db.LatestSealedBlock()
+		require.True(t, ok)
+		require.Equal(t, createID(3), head)
+	})
+
 	t.Run("ReadDeletedBlocks", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -1227,3 +1386,371 @@ func (s *stubMetrics) RecordDBSearchEntriesRead(count int64) {
 var _ Metrics = (*stubMetrics)(nil)
 
 var _ entrydb.EntryStore[EntryType, Entry] = (*entrydb.MemEntryStore[EntryType, Entry])(nil)
+
+func TestDeleteLogsAfter(t *testing.T) {
+	bl50 := createID(50)
+	bl51 := createID(51)
+	execMsg := func(i int) *types.ExecutingMessage {
+		return &types.ExecutingMessage{
+			Chain:     2,
+			BlockNum:  10,
+			LogIdx:    uint32(i),
+			Timestamp: 1234,
+			Hash:      createHash(1000 + i),
+		}
+	}
+	// every third log is an executing message, to cover deletion after multi-entry logs
+	logAt := func(i int) *types.ExecutingMessage {
+		if i%3 == 2 {
+			return execMsg(i)
+		}
+		return nil
+	}
+	// logHashesOf collects the log hashes of the block built on top of the given block, using an iterator
+	logHashesOf := func(t *testing.T, db *DB, parent eth.BlockID) []common.Hash {
+		iter, err := db.IteratorStartingAt(parent.Number, 0)
+		require.NoError(t, err)
+		var hashes []common.Hash
+		err = iter.TraverseConditional(func(state IteratorState) error {
+			if _, n, ok := state.SealedBlock(); ok && n > parent.Number {
+				return types.ErrStop
+			}
+			if h, idx, ok := state.InitMessage(); ok && int(idx) == len(hashes) {
+				hashes = append(hashes, h)
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, types.ErrStop)
+		return hashes
+	}
+
+	// the keep values cover deletions around the search-checkpoint at entry 256
+	const total = 200
+	for _, keep := range []uint32{0, 5, 148, 149, 150, 151, 152, 153, 154, 198} {
+		t.Run(fmt.Sprintf("keep %d", keep), func(t *testing.T) {
+			runDBTest(t,
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					require.NoError(t, db.SealBlock(createHash(49), bl50, 500))
+					for i := 0; i < total; i++ {
+						require.NoError(t, db.AddLog(createHash(i), bl50, uint32(i), logAt(i)))
+					}
+					require.NoError(t, db.DeleteLogsAfter(bl50, keep))
+					// replace the deleted logs with a different log
+					require.NoError(t, db.AddLog(createHash(500), bl50, keep+1, nil))
+					require.NoError(t, db.SealBlock(bl50.Hash, bl51, 502))
+				},
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					for i := 0; i <= int(keep); i++ {
+						if msg := logAt(i); msg != nil {
+							requireContains(t, db, 51, uint32(i), createHash(i), *msg)
+						} else {
+							requireContains(t, db, 51, uint32(i), createHash(i))
+						}
+					}
+					requireContains(t, db, 51, keep+1, createHash(500))
+					_, err := db.Contains(51, keep+2, createHash(int(keep)+2))
+					require.Error(t, err, "deleted log must not be found")
+
+					_, logCount, _, err := db.OpenBlock(51)
+					require.NoError(t, err)
+					require.Equal(t, keep+2, logCount)
+
+					hashes := logHashesOf(t, db, bl50)
+					require.Len(t, hashes, int(keep)+2)
+					require.Equal(t, createHash(500), hashes[keep+1])
+				})
+		})
+	}
+
+	t.Run("NotHead", func(t *testing.T) {
+		logger := testlog.Logger(t, log.LvlInfo)
+		db, err := NewFromEntryStore(logger, &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{}, false)
+		require.NoError(t, err)
+		require.ErrorIs(t, db.DeleteLogsAfter(bl50, 0), types.ErrFuture)
+		require.NoError(t, db.SealBlock(createHash(49), bl50, 500))
+		require.NoError(t, db.AddLog(createHash(0), bl50, 0, nil))
+		require.NoError(t, db.AddLog(createHash(1), bl50, 1, nil))
+		require.NoError(t, db.SealBlock(bl50.Hash, bl51, 502))
+		require.NoError(t, db.AddLog(createHash(2), bl51, 0, nil))
+
+		require.ErrorIs(t, db.DeleteLogsAfter(bl50, 0), types.ErrConflict)
+		require.ErrorIs(t, db.DeleteLogsAfter(eth.BlockID{Hash: createHash(100), Number: 51}, 0), types.ErrConflict)
+		require.ErrorIs(t, db.DeleteLogsAfter(createID(52), 0), types.ErrConflict)
+		requireContains(t, db, 51, 1, createHash(1))
+
+		// deleting after the last log is a no-op
+		size := db.store.Size()
+		require.NoError(t, db.DeleteLogsAfter(bl51, 0))
+		require.NoError(t, db.DeleteLogsAfter(bl51, 10))
+		require.Equal(t, size, db.store.Size())
+	})
+}
+
+func TestCountLogs(t *testing.T) {
+	// log counts of the blocks built on top of block 10, 11, ...
+	// The large blocks span search-checkpoints.
+	counts := []uint32{0, 3, 300, 0, 1, 600, 2}
+	runDBTest(t,
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.SealBlock(createHash(9), createID(10), 5000))
+			for i, count := range counts {
+				parent := createID(10 + i)
+				for j := uint32(0); j < count; j++ {
+					var execMsg *types.ExecutingMessage
+					if j%5 == 0 {
+						execMsg = &types.ExecutingMessage{Chain: 2, BlockNum: 1, LogIdx: j, Timestamp: 1234, Hash: createHash(int(j))}
+					}
+					require.NoError(t, db.AddLog(createHash(int(j)), parent, j, execMsg))
+				}
+				require.NoError(t, db.SealBlock(parent.Hash, createID(11+i), 5001+uint64(i)))
+			}
+			// logs of the next block are in progress
+			require.NoError(t, db.AddLog(createHash(0), createID(10+len(counts)), 0, nil))
+		},
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			for i, count := range counts {
+				num := uint64(11 + i)
+				n, err := db.CountLogs(num)
+				require.NoError(t, err)
+				require.Equal(t, count, n, "block %d", num)
+				_, logCount, _, err := db.OpenBlock(num)
+				require.NoError(t, err)
+				require.Equal(t, logCount, n, "block %d", num)
+			}
+			head := uint64(10 + len(counts))
+			_, err := db.CountLogs(head + 1)
+			require.ErrorIs(t, err, types.ErrFuture)
+			require.ErrorContains(t, err, "not sealed yet")
+			_, err = db.CountLogs(head + 2)
+			require.ErrorIs(t, err, types.ErrFuture)
+			// the logs of the first block are not known
+			_, err = db.CountLogs(10)
+			require.ErrorIs(t, err, types.ErrSkipped)
+		})
+
+	t.Run("Empty", func(t *testing.T) {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{}, false)
+		require.NoError(t, err)
+		_, err = db.CountLogs(0)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestGetLog(t *testing.T) {
+	// log counts of the blocks built on top of block 10, 11, ...
+	counts := []uint32{0, 3, 300, 1}
+	runDBTest(t,
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.SealBlock(createHash(9), createID(10), 5000))
+			for i, count := range counts {
+				parent := createID(10 + i)
+				for j := uint32(0); j < count; j++ {
+					var execMsg *types.ExecutingMessage
+					if j%3 == 1 {
+						execMsg = &types.ExecutingMessage{Chain: 2, BlockNum: uint64(i), LogIdx: j, Timestamp: 1234, Hash: createHash(int(j))}
+					}
+					require.NoError(t, db.AddLog(createHash(i*1000+int(j)), parent, j, execMsg))
+				}
+				require.NoError(t, db.SealBlock(parent.Hash, createID(11+i), 5001+uint64(i)))
+			}
+			// logs of the next block are in progress
+			require.NoError(t, db.AddLog(createHash(0), createID(10+len(counts)), 0, nil))
+		},
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			for i, count := range counts {
+				num := uint64(11 + i)
+				_, logCount, execMsgs, err := db.OpenBlock(num)
+				require.NoError(t, err)
+				require.Equal(t, count, logCount)
+				for j := uint32(0); j < count; j++ {
+					logHash, execMsg, err := db.GetLog(num, j)
+					require.NoError(t, err)
+					require.Equal(t, createHash(i*1000+int(j)), logHash, "block %d log %d", num, j)
+					require.Equal(t, execMsgs[j], execMsg, "block %d log %d", num, j)
+				}
+				_, _, err = db.GetLog(num, count)
+				require.ErrorIs(t, err, types.ErrConflict, "block %d", num)
+			}
+			head := uint64(10 + len(counts))
+			_, _, err := db.GetLog(head+1, 0)
+			require.ErrorIs(t, err, types.ErrFuture)
+			_, _, err = db.GetLog(head+2, 0)
+			require.ErrorIs(t, err, types.ErrFuture)
+			_, _, err = db.GetLog(0, 0)
+			require.ErrorIs(t, err, types.ErrConflict)
+		})
+
+	t.Run("Empty", func(t *testing.T) {
+		db, err := NewFromEntryStore(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, &entrydb.MemEntryStore[EntryType, Entry]{}, false)
+		require.NoError(t, err)
+		_, _, err = db.GetLog(1, 0)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestReverseIterator(t *testing.T) {
+	type logPos struct {
+		parent  uint64
+		logIdx  uint32
+		hash    common.Hash
+		execMsg *types.ExecutingMessage
+	}
+	read := func(state IteratorState) logPos {
+		_, parent, ok := state.SealedBlock()
+		require.True(t, ok)
+		hash, idx, ok := state.InitMessage()
+		require.True(t, ok)
+		return logPos{parent: parent, logIdx: idx, hash: hash, execMsg: state.ExecMessage()}
+	}
+	// log counts of the blocks built on top of block 10, 11, ...
+	counts := []uint32{2, 0, 300, 1, 0, 150, 3}
+	runDBTest(t,
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.SealBlock(createHash(9), createID(10), 5000))
+			for i, count := range counts {
+				parent := createID(10 + i)
+				for j := uint32(0); j < count; j++ {
+					var execMsg *types.ExecutingMessage
+					if j%4 == 1 {
+						execMsg = &types.ExecutingMessage{Chain: 2, BlockNum: 1, LogIdx: j, Timestamp: 1234, Hash: createHash(int(j))}
+					}
+					require.NoError(t, db.AddLog(createHash(1000*i+int(j)), parent, j, execMsg))
+				}
+				if i < len(counts)-1 { // the logs of the last block are still in progress
+					require.NoError(t, db.SealBlock(parent.Hash, createID(11+i), 5001+uint64(i)))
+				}
+			}
+		},
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			// collect all logs with the forward iterator
+			var forward []logPos
+			iter, err := db.IteratorStartingAt(10, 0)
+			require.NoError(t, err)
+			for {
+				err := iter.NextInitMsg()
+				if errors.Is(err, types.ErrFuture) {
+					break
+				}
+				require.NoError(t, err)
+				forward = append(forward, read(iter))
+			}
+			var total int
+			for _, c := range counts {
+				total += int(c)
+			}
+			require.Len(t, forward, total)
+
+			// reverse from the latest log
+			head := uint64(10 + len(counts) - 1)
+			rev, err := db.ReverseIteratorEndingAt(head, counts[len(counts)-1])
+			require.NoError(t, err)
+			var backward []logPos
+			for {
+				err := rev.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				backward = append(backward, read(rev))
+			}
+			slices.Reverse(backward)
+			require.Equal(t, forward, backward)
+			require.ErrorIs(t, rev.Next(), io.EOF, "stays at the end")
+
+			// reverse from the start of a block, in the middle of the DB
+			rev, err = db.ReverseIteratorEndingAt(13, 0)
+			require.NoError(t, err)
+			require.NoError(t, rev.Next())
+			require.Equal(t, logPos{parent: 12, logIdx: 299, hash: createHash(2000 + 299)}, read(rev))
+			require.NoError(t, rev.Next())
+			require.Equal(t, logPos{parent: 12, logIdx: 298, hash: createHash(2000 + 298)}, read(rev))
+			require.NoError(t, rev.Next())
+			pos := read(rev)
+			require.Equal(t, uint32(297), pos.logIdx)
+			require.NotNil(t, pos.execMsg, "log 297 is an executing message")
+
+			// reverse from within a block
+			rev, err = db.ReverseIteratorEndingAt(10, 1)
+			require.NoError(t, err)
+			require.NoError(t, rev.Next())
+			require.Equal(t, logPos{parent: 10, logIdx: 0, hash: createHash(0)}, read(rev))
+			require.ErrorIs(t, rev.Next(), io.EOF)
+
+			// out of range
+			_, err = db.ReverseIteratorEndingAt(head+1, 0)
+			require.ErrorIs(t, err, types.ErrFuture)
+			_, err = db.ReverseIteratorEndingAt(head, counts[len(counts)-1]+1)
+			require.ErrorIs(t, err, types.ErrFuture)
+			_, err = db.ReverseIteratorEndingAt(5, 0)
+			require.ErrorIs(t, err, types.ErrSkipped)
+		})
+}
+
+func TestFindLogByHash(t *testing.T) {
+	dup := createHash(7777)
+	seal := func(num int) types.BlockSeal {
+		return types.BlockSeal{Hash: createHash(num), Number: uint64(num), Timestamp: 5000 + uint64(num)}
+	}
+	runDBTest(t,
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.SealBlock(createHash(9), createID(10), 5010))
+			// block 11: the duplicate at log 1, as executing message
+			require.NoError(t, db.AddLog(createHash(1), createID(10), 0, nil))
+			require.NoError(t, db.AddLog(dup, createID(10), 1, &types.ExecutingMessage{Chain: 2, BlockNum: 1, LogIdx: 0, Timestamp: 1234, Hash: createHash(3)}))
+			require.NoError(t, db.SealBlock(createHash(10), createID(11), 5011))
+			// block 12: no logs
+			require.NoError(t, db.SealBlock(createHash(11), createID(12), 5012))
+			// block 13: many logs, spanning search-checkpoints, with the duplicate twice
+			for i := uint32(0); i < 600; i++ {
+				h := createHash(100 + int(i))
+				if i == 0 || i == 400 {
+					h = dup
+				}
+				require.NoError(t, db.AddLog(h, createID(12), i, nil))
+			}
+			require.NoError(t, db.SealBlock(createHash(12), createID(13), 5013))
+			// block 14: the duplicate at log 0
+			require.NoError(t, db.AddLog(dup, createID(13), 0, nil))
+			require.NoError(t, db.SealBlock(createHash(13), createID(14), 5014))
+			// the block in progress is not included
+			require.NoError(t, db.AddLog(dup, createID(14), 0, nil))
+		},
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			all := []types.LogPosition{
+				{Block: seal(11), LogIdx: 1},
+				{Block: seal(13), LogIdx: 0},
+				{Block: seal(13), LogIdx: 400},
+				{Block: seal(14), LogIdx: 0},
+			}
+			positions, err := db.FindLogByHash(dup)
+			require.NoError(t, err)
+			require.Equal(t, all, positions)
+			for _, pos := range positions {
+				includedIn, err := db.Contains(pos.Block.Number, pos.LogIdx, dup)
+				require.NoError(t, err)
+				require.Equal(t, pos.Block, includedIn)
+			}
+
+			positions, err = db.FindLogByHash(createHash(100 + 300))
+			require.NoError(t, err)
+			require.Equal(t, []types.LogPosition{{Block: seal(13), LogIdx: 300}}, positions)
+
+			positions, err = db.FindLogByHash(createHash(123456))
+			require.NoError(t, err)
+			require.NotNil(t, positions)
+			require.Empty(t, positions)
+
+			positions, err = db.FindLogByHashInRange(dup, 12, 13)
+			require.NoError(t, err)
+			require.Equal(t, all[1:3], positions)
+			positions, err = db.FindLogByHashInRange(dup, 14, 20)
+			require.NoError(t, err)
+			require.Equal(t, all[3:], positions)
+			positions, err = db.FindLogByHashInRange(dup, 11, 11)
+			require.NoError(t, err)
+			require.Equal(t, all[:1], positions)
+			positions, err = db.FindLogByHashInRange(dup, 15, 20)
+			require.NoError(t, err)
+			require.Empty(t, positions)
+		})
+}
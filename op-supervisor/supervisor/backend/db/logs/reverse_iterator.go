@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// ReverseIterator iterates over the logs of the DB, from later to earlier logs.
+type ReverseIterator interface {
+	// Next moves to the previous log. The state then describes that log,
+	// with the sealed block being the parent block of the block that contains the log.
+	// Next returns io.EOF when there are no earlier logs:
+	// iteration terminates at the first sealed block of the DB, since no logs are recorded before it.
+	Next() error
+	IteratorState
+}
+
+// reverseIterator walks the DB backwards, one search-checkpoint window at a time.
+// Entries can only be decoded in forward order, so each window is scanned forward from its checkpoint,
+// and the logs found in the window are then returned in reverse order.
+type reverseIterator struct {
+	db *DB
+	// end is the index of the first entry that is not part of the logs yet to be returned.
+	end entrydb.EntryIdx
+	// pending are the logs of the last scanned window that were not returned yet, in forward order.
+	pending []logContext
+	current logContext
+}
+
+// ReverseIteratorEndingAt creates an iterator that starts at the log before logsUntil,
+// of the block that is built on top of the given sealed block, and then moves towards earlier logs.
+// The start position is interpreted like the position of IteratorStartingAt:
+// if logsUntil is 0, the first log returned is the last log before the given block was sealed.
+// An error is returned if the start position is not in the DB.
+func (db *DB) ReverseIteratorEndingAt(sealedNum uint64, logsUntil uint32) (ReverseIterator, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	iter, err := db.newIteratorAt(sealedNum, logsUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &reverseIterator{db: db, end: iter.NextIndex()}, nil
+}
+
+func (i *reverseIterator) Next() error {
+	for len(i.pending) == 0 {
+		if i.end <= 0 {
+			return io.EOF
+		}
+		if err := i.scanWindow(); err != nil {
+			return err
+		}
+	}
+	last := len(i.pending) - 1
+	i.current = i.pending[last]
+	i.pending = i.pending[:last]
+	return nil
+}
+
+// scanWindow reads the logs from the search-checkpoint before the end, up to the end,
+// and moves the end back to that checkpoint.
+func (i *reverseIterator) scanWindow() error {
+	i.db.rwLock.RLock()
+	defer i.db.rwLock.RUnlock()
+	start := ((i.end - 1) / searchCheckpointFrequency) * searchCheckpointFrequency
+	iter := i.db.newIterator(start)
+	iter.current.need.Add(FlagCanonicalHash)
+	for iter.NextIndex() < i.end {
+		typ, err := iter.next()
+		if err != nil {
+			return fmt.Errorf("failed to scan logs from entry %d to %d: %w", start, i.end, err)
+		}
+		// A log is complete after its initiating event, or after the executing-check that follows it.
+		if (typ == TypeInitiatingEvent || typ == TypeExecutingCheck) && iter.current.need == 0 {
+			i.pending = append(i.pending, iter.current)
+		}
+	}
+	i.db.m.RecordDBSearchEntriesRead(iter.entriesRead)
+	i.end = start
+	return nil
+}
+
+func (i *reverseIterator) NextIndex() entrydb.EntryIdx {
+	return i.current.NextIndex()
+}
+
+func (i *reverseIterator) SealedBlock() (hash common.Hash, num uint64, ok bool) {
+	return i.current.SealedBlock()
+}
+
+func (i *reverseIterator) SealedTimestamp() (timestamp uint64, ok bool) {
+	return i.current.SealedTimestamp()
+}
+
+func (i *reverseIterator) InitMessage() (hash common.Hash, logIndex uint32, ok bool) {
+	return i.current.InitMessage()
+}
+
+func (i *reverseIterator) ExecMessage() *types.ExecutingMessage {
+	return i.current.ExecMessage()
+}
@@ -56,6 +56,106 @@ func (db *ChainsDB) LastCommonL1() (types.BlockSeal, error) {
 	return commonL1, nil
 }
 
+// SharedL1Range returns the range of L1 block numbers, inclusive, that both chains were derived from, per their local-safe DBs.
+// If the chains do not overlap, a zero range is returned, without error.
+func (db *ChainsDB) SharedL1Range(a, b eth.ChainID) (from, to uint64, err error) {
+	firstA, lastA, err := db.localL1Range(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	firstB, lastB, err := db.localL1Range(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to = max(firstA, firstB), min(lastA, lastB)
+	if from > to {
+		return 0, 0, nil
+	}
+	return from, to, nil
+}
+
+// localL1Range returns the first and last L1 block number that the local-safe data of the chain was derived from.
+func (db *ChainsDB) localL1Range(chain eth.ChainID) (first, last uint64, err error) {
+	localDB, ok := db.localDBs.Get(chain)
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
+	}
+	firstPair, err := localDB.First()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get first local-safe block of chain %s: %w", chain, err)
+	}
+	lastPair, err := localDB.Latest()
+	if errors.Is(err, types.ErrAwaitReplacementBlock) {
+		// the invalidated block is still derived from the L1 block
+		lastPair, err = localDB.Invalidated()
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get latest local-safe block of chain %s: %w", chain, err)
+	}
+	return firstPair.DerivedFrom.Number, lastPair.DerivedFrom.Number, nil
+}
+
+// AllCrossSafe returns whether all the given blocks are cross-safe,
+// and the blocks that are not cross-safe, in the order they were given.
+// Blocks newer than the cross-safe tip of their chain are rejected without further lookups.
+func (db *ChainsDB) AllCrossSafe(blocks []types.ChainBlock) (allSafe bool, notSafe []types.ChainBlock, err error) {
+	// the cross-safe tip of each chain, or nil if the chain has no cross-safe data yet
+	tips := make(map[eth.ChainID]*types.BlockSeal)
+	for _, b := range blocks {
+		crossDB, ok := db.crossDBs.Get(b.ChainID)
+		if !ok {
+			return false, nil, fmt.Errorf("%w: %v", types.ErrUnknownChain, b.ChainID)
+		}
+		tip, ok := tips[b.ChainID]
+		if !ok {
+			pair, err := crossDB.Latest()
+			if errors.Is(err, types.ErrAwaitReplacementBlock) {
+				// the invalidated block itself is rejected by the IsDerived check
+				pair, err = crossDB.Invalidated()
+			}
+			if err != nil && !errors.Is(err, types.ErrFuture) {
+				return false, nil, fmt.Errorf("failed to get cross-safe tip of chain %s: %w", b.ChainID, err)
+			}
+			if err == nil {
+				tip = &pair.Derived
+			}
+			tips[b.ChainID] = tip
+		}
+		if tip == nil || b.Block.Number > tip.Number {
+			notSafe = append(notSafe, b)
+			continue
+		}
+		if err := crossDB.IsDerived(b.Block); err != nil {
+			if errors.Is(err, types.ErrConflict) || errors.Is(err, types.ErrAwaitReplacementBlock) ||
+				errors.Is(err, types.ErrSkipped) || errors.Is(err, types.ErrFuture) {
+				notSafe = append(notSafe, b)
+				continue
+			}
+			return false, nil, fmt.Errorf("failed to check if %s is cross-safe: %w", b, err)
+		}
+	}
+	return len(notSafe) == 0, notSafe, nil
+}
+
+// SyncProgress returns the fraction, in the range [0, 1], of the L1 blocks from the start of the
+// local-safe data of the chain up to targetL1 that local-safe has been derived from.
+// ErrFuture is returned if the chain has no local-safe data yet,
+// and ErrSkipped if targetL1 is before the start of the local-safe data.
+func (db *ChainsDB) SyncProgress(chainID eth.ChainID, targetL1 uint64) (float64, error) {
+	first, last, err := db.localL1Range(chainID)
+	if err != nil {
+		return 0, err
+	}
+	if targetL1 < first {
+		return 0, fmt.Errorf("target L1 block %d is before first L1 block %d of chain %s: %w",
+			targetL1, first, chainID, types.ErrSkipped)
+	}
+	if last >= targetL1 {
+		return 1, nil
+	}
+	return float64(last-first) / float64(targetL1-first), nil
+}
+
 func (db *ChainsDB) IsCrossUnsafe(chainID eth.ChainID, block eth.BlockID) error {
 	v, ok := db.crossUnsafe.Get(chainID)
 	if !ok {
@@ -144,6 +244,69 @@ func (db *ChainsDB) CrossUnsafe(chainID eth.ChainID) (types.BlockSeal, error) {
 	return crossUnsafe, nil
 }
 
+// IsCrossSafeCaughtUp returns true if the latest cross-safe block of the chain is the latest local-safe block.
+// ErrFuture is returned if the chain has no local-safe data yet.
+func (db *ChainsDB) IsCrossSafeCaughtUp(chainID eth.ChainID) (bool, error) {
+	localDB, ok := db.localDBs.Get(chainID)
+	if !ok {
+		return false, fmt.Errorf("%w: %v", types.ErrUnknownChain, chainID)
+	}
+	crossDB, ok := db.crossDBs.Get(chainID)
+	if !ok {
+		return false, fmt.Errorf("%w: %v", types.ErrUnknownChain, chainID)
+	}
+	localSafe, err := localDB.Latest()
+	if err != nil {
+		return false, fmt.Errorf("failed to get local-safe head of chain %s: %w", chainID, err)
+	}
+	crossSafe, err := crossDB.Latest()
+	if errors.Is(err, types.ErrFuture) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get cross-safe head of chain %s: %w", chainID, err)
+	}
+	return crossSafe.Derived.ID() == localSafe.Derived.ID(), nil
+}
+
+// ChainActivation reports whether the latest local-safe block of the chain is past the activation point
+// that the dependency set governs, i.e. whether the chain may be executing interop messages.
+// If active, since is the first local-safe block that is allowed to execute messages.
+// If the activation happened before the first block in the local-safe DB, then that first block is returned.
+// ErrFuture is returned if the chain has no local-safe blocks yet.
+func (db *ChainsDB) ChainActivation(chainID eth.ChainID) (active bool, since types.BlockSeal, err error) {
+	if !db.depSet.HasChain(chainID) {
+		return false, types.BlockSeal{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chainID)
+	}
+	localDB, ok := db.localDBs.Get(chainID)
+	if !ok {
+		return false, types.BlockSeal{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chainID)
+	}
+	latest, err := localDB.Latest()
+	if err != nil {
+		return false, types.BlockSeal{}, fmt.Errorf("failed to get local-safe head of chain %s: %w", chainID, err)
+	}
+	active, err = db.depSet.CanExecuteAt(chainID, latest.Derived.Timestamp)
+	if err != nil {
+		return false, types.BlockSeal{}, fmt.Errorf("failed to check activation at block %s: %w", latest.Derived, err)
+	}
+	if !active {
+		return false, types.BlockSeal{}, nil
+	}
+	// Block timestamps only increase, so the activation check is monotonic, and can be binary-searched.
+	var checkErr error
+	first, err := localDB.FirstDerivedWhere(func(derived types.BlockSeal) bool {
+		ok, err := db.depSet.CanExecuteAt(chainID, derived.Timestamp)
+		if err != nil {
+			checkErr = errors.Join(checkErr, err)
+		}
+		return ok
+	})
+	if err := errors.Join(checkErr, err); err != nil {
+		return false, types.BlockSeal{}, fmt.Errorf("failed to find first active block of chain %s: %w", chainID, err)
+	}
+	return true, first.Derived, nil
+}
+
 func (db *ChainsDB) AcceptedBlock(chainID eth.ChainID, id eth.BlockID) error {
 	localDB, ok := db.localDBs.Get(chainID)
 	if !ok {
@@ -191,8 +354,55 @@ func (db *ChainsDB) CrossSafe(chainID eth.ChainID) (pair types.DerivedBlockSealP
 	return crossDB.Latest()
 }
 
-func (db *ChainsDB) FinalizedL1() eth.BlockRef {
-	return db.finalizedL1.Get()
+// CrossSafeLatest returns the latest cross-safe pair of the given chain,
+// and whether that pair is an invalidated entry, awaiting a replacement.
+// While the cross-safe tail is invalidated, cross-safe promotion of the chain halts.
+func (db *ChainsDB) CrossSafeLatest(chainID eth.ChainID) (pair types.DerivedBlockSealPair, invalidated bool, err error) {
+	crossDB, ok := db.crossDBs.Get(chainID)
+	if !ok {
+		return types.DerivedBlockSealPair{}, false, types.ErrUnknownChain
+	}
+	pair, err = crossDB.Latest()
+	if errors.Is(err, types.ErrAwaitReplacementBlock) {
+		pair, err = crossDB.Invalidated()
+		if err != nil {
+			return types.DerivedBlockSealPair{}, false, fmt.Errorf("failed to read invalidated cross-safe block of chain %s: %w", chainID, err)
+		}
+		return pair, true, nil
+	} else if err != nil {
+		return types.DerivedBlockSealPair{}, false, err
+	}
+	return pair, false, nil
+}
+
+// GlobalCrossSafeFloor returns the cross-safe head of each chain in the dependency set,
+// and the chain with the oldest cross-safe head by timestamp, which limits what is cross-safe everywhere.
+// If multiple chains share the lowest timestamp, the first one in the dependency set is returned.
+func (db *ChainsDB) GlobalCrossSafeFloor() (heads map[eth.ChainID]types.BlockSeal, limitingChain eth.ChainID, err error) {
+	heads = make(map[eth.ChainID]types.BlockSeal)
+	var floor types.BlockSeal
+	for _, chain := range db.depSet.Chains() {
+		crossDB, ok := db.crossDBs.Get(chain)
+		if !ok {
+			return nil, eth.ChainID{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
+		}
+		latest, err := crossDB.Latest()
+		if err != nil {
+			return nil, eth.ChainID{}, fmt.Errorf("failed to determine cross-safe head of chain %s: %w", chain, err)
+		}
+		heads[chain] = latest.Derived
+		if len(heads) == 1 || latest.Derived.Timestamp < floor.Timestamp {
+			floor = latest.Derived
+			limitingChain = chain
+		}
+	}
+	return heads, limitingChain, nil
+}
+
+// FinalizedL1 returns the finalized L1 block, or false if there is no finality signal yet.
+func (db *ChainsDB) FinalizedL1() (eth.L1BlockRef, bool) {
+	ref := db.finalizedL1.Get()
+	return ref, ref != (eth.L1BlockRef{})
 }
 
 func (db *ChainsDB) Finalized(chainID eth.ChainID) (types.BlockSeal, error) {
@@ -229,6 +439,41 @@ func (db *ChainsDB) Finalized(chainID eth.ChainID) (types.BlockSeal, error) {
 	return derived, nil
 }
 
+// LatestFinalizedL2 returns the last L2 block of the chain that was cross-safe derived
+// from an L1 block at or below the finalized L1 block.
+// Unlike Finalized, the finalized L1 block itself does not have to be in the cross-safe DB.
+// If the finalized L1 block is past the latest cross-safe data, the latest cross-safe block is final.
+// ErrFuture is returned if there is no finalized L1 signal yet,
+// or if no L2 block was derived from finalized L1 data yet.
+func (db *ChainsDB) LatestFinalizedL2(chainID eth.ChainID) (types.BlockSeal, error) {
+	finalizedL1, ok := db.FinalizedL1()
+	if !ok {
+		return types.BlockSeal{}, fmt.Errorf("no finalized L1 signal, cannot determine L2 finality of chain %s yet: %w",
+			chainID, types.ErrFuture)
+	}
+	crossDB, ok := db.crossDBs.Get(chainID)
+	if !ok {
+		return types.BlockSeal{}, types.ErrUnknownChain
+	}
+	pair, err := crossDB.NearestDerivedFrom(finalizedL1.Number)
+	if errors.Is(err, types.ErrNotFound) {
+		return types.BlockSeal{}, fmt.Errorf("no cross-safe data of chain %s yet: %w", chainID, types.ErrFuture)
+	}
+	if errors.Is(err, types.ErrFuture) {
+		return types.BlockSeal{}, fmt.Errorf("no L2 block of chain %s derived at or below finalized L1 %s: %w",
+			chainID, finalizedL1, types.ErrFuture)
+	}
+	if err != nil {
+		return types.BlockSeal{}, fmt.Errorf("could not find what was derived in L2 chain %s up to finalized L1 %s: %w",
+			chainID, finalizedL1, err)
+	}
+	if pair.DerivedFrom.Number == finalizedL1.Number && pair.DerivedFrom.Hash != finalizedL1.Hash {
+		return types.BlockSeal{}, fmt.Errorf("finalized L1 %s conflicts with cross-safe derived-from %s: %w",
+			finalizedL1, pair.DerivedFrom, types.ErrConflict)
+	}
+	return pair.Derived, nil
+}
+
 func (db *ChainsDB) LastDerivedFrom(chainID eth.ChainID, derivedFrom eth.BlockID) (derived types.BlockSeal, err error) {
 	crossDB, ok := db.crossDBs.Get(chainID)
 	if !ok {
@@ -259,14 +504,21 @@ func (db *ChainsDB) CrossDerivedFromBlockRef(chainID eth.ChainID, derived eth.Bl
 	return res.MustWithParent(parent.ID()), nil
 }
 
+// Contains checks if the log with the given hash is recorded in the given block and log index of the chain,
+// see LogStorage.Contains. The block-seal of the block that the log was included in is returned.
+// ErrUnknownChain is returned if the chain has no log DB.
+func (db *ChainsDB) Contains(chainID eth.ChainID, blockNum uint64, logIdx uint32, logHash common.Hash) (includedIn types.BlockSeal, err error) {
+	logDB, ok := db.logDBs.Get(chainID)
+	if !ok {
+		return types.BlockSeal{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chainID)
+	}
+	return logDB.Contains(blockNum, logIdx, logHash)
+}
+
 // Check calls the underlying logDB to determine if the given log entry exists at the given location.
 // If the block-seal of the block that includes the log is known, it is returned. It is fully zeroed otherwise, if the block is in-progress.
 func (db *ChainsDB) Check(chain eth.ChainID, blockNum uint64, timestamp uint64, logIdx uint32, logHash common.Hash) (includedIn types.BlockSeal, err error) {
-	logDB, ok := db.logDBs.Get(chain)
-	if !ok {
-		return types.BlockSeal{}, fmt.Errorf("%w: %v", types.ErrUnknownChain, chain)
-	}
-	includedIn, err = logDB.Contains(blockNum, logIdx, logHash)
+	includedIn, err = db.Contains(chain, blockNum, logIdx, logHash)
 	if err != nil {
 		return types.BlockSeal{}, err
 	}
@@ -291,6 +543,40 @@ func (db *ChainsDB) OpenBlock(chainID eth.ChainID, blockNum uint64) (seal eth.Bl
 	return logDB.OpenBlock(blockNum)
 }
 
+// BlockOpenResult is the result of opening a block of a single chain, see OpenBlockAll.
+type BlockOpenResult struct {
+	Ref      eth.BlockRef
+	LogCount uint32
+	ExecMsgs map[uint32]*types.ExecutingMessage
+	// Err is ErrFuture if the chain does not have the block yet. The other fields are zeroed then.
+	Err error
+}
+
+// OpenBlockAll opens the block at the given number on every chain with a log DB, see OpenBlock.
+// Chains that do not have the block yet are included in the result, with ErrFuture as error.
+// Any other error fails the whole call.
+func (db *ChainsDB) OpenBlockAll(blockNum uint64) (map[eth.ChainID]BlockOpenResult, error) {
+	out := make(map[eth.ChainID]BlockOpenResult)
+	var result error
+	db.logDBs.Range(func(chainID eth.ChainID, logDB LogStorage) bool {
+		ref, logCount, execMsgs, err := logDB.OpenBlock(blockNum)
+		if errors.Is(err, types.ErrFuture) {
+			out[chainID] = BlockOpenResult{Err: err}
+			return true
+		}
+		if err != nil {
+			result = fmt.Errorf("failed to open block %d of chain %s: %w", blockNum, chainID, err)
+			return false
+		}
+		out[chainID] = BlockOpenResult{Ref: ref, LogCount: logCount, ExecMsgs: execMsgs}
+		return true
+	})
+	if result != nil {
+		return nil, result
+	}
+	return out, nil
+}
+
 // LocalDerivedFrom returns the block that the given block was derived from, if it exists in the local derived-from storage.
 // it routes the request to the appropriate localDB.
 func (db *ChainsDB) LocalDerivedFrom(chain eth.ChainID, derived eth.BlockID) (derivedFrom types.BlockSeal, err error) {
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/fromda"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/depset"
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/stretchr/testify/require"
 )
@@ -26,6 +30,12 @@ func (m *mockDerivedFromStorage) Latest() (pair types.DerivedBlockSealPair, err
 	}
 	return types.DerivedBlockSealPair{}, nil
 }
+func (m *mockDerivedFromStorage) Close() error {
+	return nil
+}
+func (m *mockDerivedFromStorage) Len() int {
+	return 0
+}
 func (m *mockDerivedFromStorage) Invalidated() (pair types.DerivedBlockSealPair, err error) {
 	return types.DerivedBlockSealPair{}, nil
 }
@@ -65,6 +75,21 @@ func (m *mockDerivedFromStorage) PreviousDerived(derived eth.BlockID) (prevDeriv
 func (m *mockDerivedFromStorage) RewindToL2(derived uint64) error {
 	return nil
 }
+func (m *mockDerivedFromStorage) NearestDerivedFrom(l1Number uint64) (pair types.DerivedBlockSealPair, err error) {
+	return types.DerivedBlockSealPair{}, nil
+}
+func (m *mockDerivedFromStorage) FirstDerivedWhere(match func(derived types.BlockSeal) bool) (pair types.DerivedBlockSealPair, err error) {
+	return types.DerivedBlockSealPair{}, nil
+}
+func (m *mockDerivedFromStorage) RewindToL1(derivedFrom uint64) error {
+	return nil
+}
+func (m *mockDerivedFromStorage) Rewind(target types.DerivedBlockSealPair, including bool) error {
+	return nil
+}
+func (m *mockDerivedFromStorage) ContainsDerivedPair(derivedFrom, derived eth.BlockID) (bool, error) {
+	return false, nil
+}
 
 func sampleDepSet(t *testing.T) depset.DependencySet {
 	depSet, err := depset.NewStaticConfigDependencySet(
@@ -89,6 +114,32 @@ func sampleDepSet(t *testing.T) depset.DependencySet {
 	return depSet
 }
 
+type stubFromDAMetrics struct{}
+
+func (stubFromDAMetrics) RecordDBDerivedEntryCount(count int64) {}
+
+// newTestDerivedFromDB creates an in-memory derived-from DB, to back local-safe or cross-safe data in tests.
+func newTestDerivedFromDB(t *testing.T) *fromda.DB {
+	logger := testlog.Logger(t, log.LevelDebug)
+	store := &entrydb.MemEntryStore[fromda.EntryType, fromda.Entry]{}
+	dfDB, err := fromda.NewFromEntryStore(logger, stubFromDAMetrics{}, store)
+	require.NoError(t, err)
+	return dfDB
+}
+
+// testRef creates a mock block-ref of the given kind ("L1" or "L2"), with a parent-hash that links to the previous number.
+func testRef(kind string, num uint64) eth.BlockRef {
+	ref := eth.BlockRef{
+		Hash:   crypto.Keccak256Hash([]byte(fmt.Sprintf("%s block %d", kind, num))),
+		Number: num,
+		Time:   1000_000 + num*12,
+	}
+	if num > 0 {
+		ref.ParentHash = crypto.Keccak256Hash([]byte(fmt.Sprintf("%s block %d", kind, num-1)))
+	}
+	return ref
+}
+
 func TestCommonL1UnknownChain(t *testing.T) {
 	m1 := &mockDerivedFromStorage{}
 	m2 := &mockDerivedFromStorage{}
@@ -164,3 +215,437 @@ func TestCommonL1(t *testing.T) {
 		require.Equal(t, types.BlockSeal{}, latest)
 	})
 }
+
+func TestCrossSafeLatest(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainID := eth.ChainIDFromUInt64(900)
+	crossDB := newTestDerivedFromDB(t)
+	chainDB.AddCrossDerivedFromDB(chainID, crossDB)
+
+	_, _, err := chainDB.CrossSafeLatest(eth.ChainIDFromUInt64(901))
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+
+	_, _, err = chainDB.CrossSafeLatest(chainID)
+	require.ErrorIs(t, err, types.ErrFuture)
+
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 0), testRef("L2", 0)))
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 1), testRef("L2", 1)))
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 2), testRef("L2", 2)))
+
+	t.Run("normal tail", func(t *testing.T) {
+		pair, invalidated, err := chainDB.CrossSafeLatest(chainID)
+		require.NoError(t, err)
+		require.False(t, invalidated)
+		require.Equal(t, testRef("L1", 2).ID(), pair.DerivedFrom.ID())
+		require.Equal(t, testRef("L2", 2).ID(), pair.Derived.ID())
+	})
+
+	t.Run("invalidated tail", func(t *testing.T) {
+		require.NoError(t, crossDB.RewindAndInvalidate(types.DerivedBlockRefPair{
+			DerivedFrom: testRef("L1", 2),
+			Derived:     testRef("L2", 2),
+		}))
+		pair, invalidated, err := chainDB.CrossSafeLatest(chainID)
+		require.NoError(t, err)
+		require.True(t, invalidated)
+		require.Equal(t, testRef("L1", 2).ID(), pair.DerivedFrom.ID())
+		require.Equal(t, testRef("L2", 2).ID(), pair.Derived.ID())
+	})
+}
+
+func TestGlobalCrossSafeFloor(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+
+	// cross-safe L2 heads at different heights, and thus different timestamps
+	heights := map[eth.ChainID]uint64{chainA: 5, chainB: 2, chainC: 7}
+	crossDBs := make(map[eth.ChainID]*fromda.DB)
+	for _, chain := range []eth.ChainID{chainA, chainB} {
+		crossDB := newTestDerivedFromDB(t)
+		for i := uint64(0); i <= heights[chain]; i++ {
+			require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+		}
+		crossDBs[chain] = crossDB
+		chainDB.AddCrossDerivedFromDB(chain, crossDB)
+	}
+
+	_, _, err := chainDB.GlobalCrossSafeFloor()
+	require.ErrorIs(t, err, types.ErrUnknownChain, "chain C is not attached yet")
+
+	crossDBs[chainC] = newTestDerivedFromDB(t)
+	chainDB.AddCrossDerivedFromDB(chainC, crossDBs[chainC])
+	_, _, err = chainDB.GlobalCrossSafeFloor()
+	require.ErrorIs(t, err, types.ErrFuture, "chain C has no cross-safe data yet")
+
+	for i := uint64(0); i <= heights[chainC]; i++ {
+		require.NoError(t, crossDBs[chainC].AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+
+	heads, limiting, err := chainDB.GlobalCrossSafeFloor()
+	require.NoError(t, err)
+	require.Equal(t, chainB, limiting)
+	require.Len(t, heads, 3)
+	for chain, height := range heights {
+		require.Equal(t, testRef("L2", height).ID(), heads[chain].ID())
+		require.Equal(t, testRef("L2", height).Time, heads[chain].Timestamp)
+	}
+
+	// once chain B catches up past chain A, chain A becomes the limiting chain
+	for i := heights[chainB] + 1; i <= 6; i++ {
+		require.NoError(t, crossDBs[chainB].AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	heads, limiting, err = chainDB.GlobalCrossSafeFloor()
+	require.NoError(t, err)
+	require.Equal(t, chainA, limiting)
+	require.Equal(t, testRef("L2", 6).ID(), heads[chainB].ID())
+}
+
+func TestSharedL1Range(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+
+	// addLocalDB attaches a local DB, with one L2 block derived from each L1 block in the inclusive range.
+	addLocalDB := func(chain eth.ChainID, first, last uint64) {
+		localDB := newTestDerivedFromDB(t)
+		for i := first; i <= last; i++ {
+			require.NoError(t, localDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+		}
+		chainDB.AddLocalDerivedFromDB(chain, localDB)
+	}
+	addLocalDB(chainA, 10, 20)
+	addLocalDB(chainB, 15, 30)
+
+	_, _, err := chainDB.SharedL1Range(chainA, chainC)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+
+	t.Run("overlap", func(t *testing.T) {
+		from, to, err := chainDB.SharedL1Range(chainA, chainB)
+		require.NoError(t, err)
+		require.Equal(t, uint64(15), from)
+		require.Equal(t, uint64(20), to)
+
+		from, to, err = chainDB.SharedL1Range(chainB, chainA)
+		require.NoError(t, err)
+		require.Equal(t, uint64(15), from)
+		require.Equal(t, uint64(20), to)
+	})
+
+	t.Run("no overlap", func(t *testing.T) {
+		addLocalDB(chainC, 21, 25)
+		from, to, err := chainDB.SharedL1Range(chainA, chainC)
+		require.NoError(t, err)
+		require.Zero(t, from)
+		require.Zero(t, to)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		chainDB.AddLocalDerivedFromDB(chainC, newTestDerivedFromDB(t))
+		_, _, err := chainDB.SharedL1Range(chainA, chainC)
+		require.ErrorIs(t, err, types.ErrFuture)
+	})
+}
+
+func TestSyncProgress(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+
+	_, err := chainDB.SyncProgress(chainA, 100)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+
+	localDB := newTestDerivedFromDB(t)
+	chainDB.AddLocalDerivedFromDB(chainA, localDB)
+	chainDB.AddLocalDerivedFromDB(chainB, newTestDerivedFromDB(t))
+
+	_, err = chainDB.SyncProgress(chainB, 100)
+	require.ErrorIs(t, err, types.ErrFuture, "empty DB")
+
+	// local-safe starts at L1 block 10, and is derived up to L1 block 25
+	for i := uint64(10); i <= 25; i++ {
+		require.NoError(t, localDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+
+	progress, err := chainDB.SyncProgress(chainA, 50)
+	require.NoError(t, err)
+	require.InDelta(t, 0.375, progress, 1e-9)
+
+	progress, err = chainDB.SyncProgress(chainA, 25)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, progress)
+
+	progress, err = chainDB.SyncProgress(chainA, 20)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, progress, "clamped to 1 when past the target")
+
+	progress, err = chainDB.SyncProgress(chainA, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, progress)
+
+	_, err = chainDB.SyncProgress(chainA, 9)
+	require.ErrorIs(t, err, types.ErrSkipped)
+}
+
+func TestAllCrossSafe(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+
+	addCrossDB := func(chain eth.ChainID, last uint64) {
+		crossDB := newTestDerivedFromDB(t)
+		for i := uint64(0); i <= last; i++ {
+			require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+		}
+		chainDB.AddCrossDerivedFromDB(chain, crossDB)
+	}
+	addCrossDB(chainA, 5)
+	addCrossDB(chainB, 2)
+	// chain C has no cross-safe data yet
+	chainDB.AddCrossDerivedFromDB(chainC, newTestDerivedFromDB(t))
+
+	block := func(chain eth.ChainID, num uint64) types.ChainBlock {
+		return types.ChainBlock{ChainID: chain, Block: testRef("L2", num).ID()}
+	}
+
+	t.Run("all safe", func(t *testing.T) {
+		allSafe, notSafe, err := chainDB.AllCrossSafe([]types.ChainBlock{
+			block(chainA, 3), block(chainB, 2), block(chainA, 0), block(chainA, 5),
+		})
+		require.NoError(t, err)
+		require.True(t, allSafe)
+		require.Empty(t, notSafe)
+	})
+	t.Run("empty", func(t *testing.T) {
+		allSafe, notSafe, err := chainDB.AllCrossSafe(nil)
+		require.NoError(t, err)
+		require.True(t, allSafe)
+		require.Empty(t, notSafe)
+	})
+	t.Run("not yet safe", func(t *testing.T) {
+		conflicting := types.ChainBlock{ChainID: chainA, Block: eth.BlockID{Hash: common.Hash{0xaa}, Number: 3}}
+		allSafe, notSafe, err := chainDB.AllCrossSafe([]types.ChainBlock{
+			block(chainA, 3), block(chainB, 3), conflicting, block(chainC, 0), block(chainB, 1),
+		})
+		require.NoError(t, err)
+		require.False(t, allSafe)
+		require.Equal(t, []types.ChainBlock{block(chainB, 3), conflicting, block(chainC, 0)}, notSafe)
+	})
+	t.Run("unknown chain", func(t *testing.T) {
+		_, _, err := chainDB.AllCrossSafe([]types.ChainBlock{block(eth.ChainIDFromUInt64(123), 0)})
+		require.ErrorIs(t, err, types.ErrUnknownChain)
+	})
+}
+
+func TestIsCrossSafeCaughtUp(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chain := eth.ChainIDFromUInt64(900)
+
+	_, err := chainDB.IsCrossSafeCaughtUp(chain)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+
+	localDB := newTestDerivedFromDB(t)
+	chainDB.AddLocalDerivedFromDB(chain, localDB)
+	_, err = chainDB.IsCrossSafeCaughtUp(chain)
+	require.ErrorIs(t, err, types.ErrUnknownChain, "no cross DB")
+
+	crossDB := newTestDerivedFromDB(t)
+	chainDB.AddCrossDerivedFromDB(chain, crossDB)
+	_, err = chainDB.IsCrossSafeCaughtUp(chain)
+	require.ErrorIs(t, err, types.ErrFuture, "empty DBs")
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, localDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	caughtUp, err := chainDB.IsCrossSafeCaughtUp(chain)
+	require.NoError(t, err)
+	require.False(t, caughtUp, "empty cross DB")
+
+	for i := uint64(0); i <= 2; i++ {
+		require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	caughtUp, err = chainDB.IsCrossSafeCaughtUp(chain)
+	require.NoError(t, err)
+	require.False(t, caughtUp, "lagging")
+
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 3), testRef("L2", 3)))
+	caughtUp, err = chainDB.IsCrossSafeCaughtUp(chain)
+	require.NoError(t, err)
+	require.True(t, caughtUp)
+
+	// an empty L1 block does not derive a new L2 block
+	require.NoError(t, localDB.AddDerived(testRef("L1", 4), testRef("L2", 3)))
+	caughtUp, err = chainDB.IsCrossSafeCaughtUp(chain)
+	require.NoError(t, err)
+	require.True(t, caughtUp, "the same L2 block")
+}
+
+func TestChainActivation(t *testing.T) {
+	chain := eth.ChainIDFromUInt64(900)
+	depSet, err := depset.NewStaticConfigDependencySet(
+		map[eth.ChainID]*depset.StaticConfigDependency{
+			chain: {
+				ChainIndex:     900,
+				ActivationTime: testRef("L2", 3).Time,
+			},
+		})
+	require.NoError(t, err)
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, depSet)
+	chainDB.AttachEmitter(event.NoopEmitter{})
+
+	_, _, err = chainDB.ChainActivation(eth.ChainIDFromUInt64(901))
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+	_, _, err = chainDB.ChainActivation(chain)
+	require.ErrorIs(t, err, types.ErrUnknownChain, "no local-safe DB")
+
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	_, _, err = chainDB.ChainActivation(chain)
+	require.ErrorIs(t, err, types.ErrFuture, "no blocks")
+
+	for i := uint64(0); i <= 2; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		require.NoError(t, chainDB.updateLocalSafe(chain, testRef("L1", i), testRef("L2", i)))
+	}
+	// sealed blocks past activation do not activate the chain until they are local-safe
+	require.NoError(t, chainDB.SealBlock(chain, testRef("L2", 3)))
+	active, since, err := chainDB.ChainActivation(chain)
+	require.NoError(t, err)
+	require.False(t, active, "before activation block")
+	require.Equal(t, types.BlockSeal{}, since)
+
+	for i := uint64(3); i <= 7; i++ {
+		if i > 3 {
+			require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		}
+		require.NoError(t, chainDB.updateLocalSafe(chain, testRef("L1", i), testRef("L2", i)))
+		active, since, err = chainDB.ChainActivation(chain)
+		require.NoError(t, err)
+		require.True(t, active, "at or after activation block")
+		require.Equal(t, types.BlockSealFromRef(testRef("L2", 3)), since)
+	}
+}
+
+func TestLatestFinalizedL2(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+
+	crossDB := newTestDerivedFromDB(t)
+	chainDB.AddCrossDerivedFromDB(chain, crossDB)
+
+	_, err := chainDB.LatestFinalizedL2(chain)
+	require.ErrorIs(t, err, types.ErrFuture, "no finalized L1 yet")
+	require.ErrorContains(t, err, "no finalized L1 signal")
+
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 0), testRef("L2", 0)))
+	// L1 block 1 derives multiple L2 blocks
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 1), testRef("L2", 1)))
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 1), testRef("L2", 2)))
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 1), testRef("L2", 3)))
+	// L1 block 2 is empty: it does not derive a new L2 block
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 2), testRef("L2", 3)))
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 3), testRef("L2", 4)))
+
+	expect := func(finalizedL1, derived uint64) {
+		require.NoError(t, chainDB.SetFinalizedL1(testRef("L1", finalizedL1)))
+		seal, err := chainDB.LatestFinalizedL2(chain)
+		require.NoError(t, err)
+		require.Equal(t, types.BlockSealFromRef(testRef("L2", derived)), seal)
+	}
+	expect(0, 0)
+	expect(1, 3)
+	expect(2, 3)
+	expect(3, 4)
+	// finalized L1 past the cross-safe data finalizes the latest cross-safe block
+	expect(5, 4)
+
+	_, err = chainDB.LatestFinalizedL2(eth.ChainIDFromUInt64(901))
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+}
+
+func TestContains(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+
+	logHash := crypto.Keccak256Hash([]byte("log"))
+	require.NoError(t, chainDB.SealBlock(chain, testRef("L2", 0)))
+	require.NoError(t, chainDB.AddLog(chain, logHash, testRef("L2", 0).ID(), 0, nil))
+	require.NoError(t, chainDB.SealBlock(chain, testRef("L2", 1)))
+
+	includedIn, err := chainDB.Contains(chain, 1, 0, logHash)
+	require.NoError(t, err)
+	require.Equal(t, types.BlockSealFromRef(testRef("L2", 1)), includedIn)
+
+	_, err = chainDB.Contains(chain, 1, 0, crypto.Keccak256Hash([]byte("other")))
+	require.ErrorIs(t, err, types.ErrConflict)
+
+	_, err = chainDB.Contains(chain, 5, 0, logHash)
+	require.ErrorIs(t, err, types.ErrFuture)
+
+	_, err = chainDB.Contains(eth.ChainIDFromUInt64(901), 1, 0, logHash)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+}
+
+func TestOpenBlockAll(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	for _, chain := range []eth.ChainID{chainA, chainB, chainC} {
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+	}
+
+	execMsg := &types.ExecutingMessage{
+		Chain:     types.ChainIndex(901),
+		BlockNum:  0,
+		LogIdx:    0,
+		Timestamp: testRef("L2", 0).Time,
+		Hash:      crypto.Keccak256Hash([]byte("init")),
+	}
+	// chain A is at block 2, with an executing message in block 2
+	require.NoError(t, chainDB.SealBlock(chainA, testRef("L2", 0)))
+	require.NoError(t, chainDB.SealBlock(chainA, testRef("L2", 1)))
+	require.NoError(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte("a")), testRef("L2", 1).ID(), 0, nil))
+	require.NoError(t, chainDB.AddLog(chainA, crypto.Keccak256Hash([]byte("b")), testRef("L2", 1).ID(), 1, execMsg))
+	require.NoError(t, chainDB.SealBlock(chainA, testRef("L2", 2)))
+	// chain B is at block 1
+	require.NoError(t, chainDB.SealBlock(chainB, testRef("L2", 0)))
+	require.NoError(t, chainDB.SealBlock(chainB, testRef("L2", 1)))
+	// chain C is empty
+
+	results, err := chainDB.OpenBlockAll(2)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.NoError(t, results[chainA].Err)
+	require.Equal(t, testRef("L2", 2), results[chainA].Ref)
+	require.Equal(t, uint32(2), results[chainA].LogCount)
+	require.Equal(t, map[uint32]*types.ExecutingMessage{1: execMsg}, results[chainA].ExecMsgs)
+	require.ErrorIs(t, results[chainB].Err, types.ErrFuture)
+	require.ErrorIs(t, results[chainC].Err, types.ErrFuture)
+
+	results, err = chainDB.OpenBlockAll(1)
+	require.NoError(t, err)
+	require.NoError(t, results[chainA].Err)
+	require.NoError(t, results[chainB].Err)
+	require.Equal(t, testRef("L2", 1), results[chainB].Ref)
+	require.Zero(t, results[chainB].LogCount)
+	require.Empty(t, results[chainB].ExecMsgs)
+	require.ErrorIs(t, results[chainC].Err, types.ErrFuture)
+}
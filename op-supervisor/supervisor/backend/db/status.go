@@ -0,0 +1,214 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/locks"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+// ChainDBStatus reports which DBs of a chain are present, and their latest heads.
+// A head of a DB that is missing, or that has no data yet, is zeroed.
+type ChainDBStatus struct {
+	HasLogs        bool
+	HasLocalSafe   bool
+	HasCrossSafe   bool
+	HasCrossUnsafe bool
+
+	// LatestSealed is the latest sealed block of the log DB.
+	LatestSealed eth.BlockID
+	LocalSafe    types.DerivedBlockSealPair
+	CrossSafe    types.DerivedBlockSealPair
+	// CrossUnsafe is the raw cross-unsafe value, without falling back to cross-safe.
+	CrossUnsafe types.BlockSeal
+
+	// LocalSafeInvalidated and CrossSafeInvalidated are set if the tail of the DB was invalidated,
+	// and awaits a replacement block. The corresponding head is zeroed then.
+	LocalSafeInvalidated bool
+	CrossSafeInvalidated bool
+}
+
+// Chains returns the chains that have any DB attached, sorted by chain ID.
+// This may differ from the dependency set, e.g. while chains are still being initialized.
+func (db *ChainsDB) Chains() []eth.ChainID {
+	seen := make(map[eth.ChainID]struct{})
+	add := func(chain eth.ChainID) {
+		seen[chain] = struct{}{}
+	}
+	db.logDBs.Range(func(chain eth.ChainID, _ LogStorage) bool {
+		add(chain)
+		return true
+	})
+	db.localDBs.Range(func(chain eth.ChainID, _ LocalDerivedFromStorage) bool {
+		add(chain)
+		return true
+	})
+	db.crossDBs.Range(func(chain eth.ChainID, _ CrossDerivedFromStorage) bool {
+		add(chain)
+		return true
+	})
+	db.crossUnsafe.Range(func(chain eth.ChainID, _ *locks.RWValue[types.BlockSeal]) bool {
+		add(chain)
+		return true
+	})
+	out := make([]eth.ChainID, 0, len(seen))
+	for chain := range seen {
+		out = append(out, chain)
+	}
+	slices.SortFunc(out, eth.ChainID.Cmp)
+	return out
+}
+
+// ChainStatus returns which DBs of the chain are present, and their latest heads.
+// It returns ErrUnknownChain if the chain has no DB attached at all.
+func (db *ChainsDB) ChainStatus(chainID eth.ChainID) (ChainDBStatus, error) {
+	var status ChainDBStatus
+	if logDB, ok := db.logDBs.Get(chainID); ok {
+		status.HasLogs = true
+		status.LatestSealed, _ = logDB.LatestSealedBlock()
+	}
+	if localDB, ok := db.localDBs.Get(chainID); ok {
+		status.HasLocalSafe = true
+		pair, err := localDB.Latest()
+		switch {
+		case err == nil:
+			status.LocalSafe = pair
+		case errors.Is(err, types.ErrAwaitReplacementBlock):
+			status.LocalSafeInvalidated = true
+		case !errors.Is(err, types.ErrFuture):
+			return ChainDBStatus{}, fmt.Errorf("failed to read local-safe of chain %s: %w", chainID, err)
+		}
+	}
+	if crossDB, ok := db.crossDBs.Get(chainID); ok {
+		status.HasCrossSafe = true
+		pair, err := crossDB.Latest()
+		switch {
+		case err == nil:
+			status.CrossSafe = pair
+		case errors.Is(err, types.ErrAwaitReplacementBlock):
+			status.CrossSafeInvalidated = true
+		case !errors.Is(err, types.ErrFuture):
+			return ChainDBStatus{}, fmt.Errorf("failed to read cross-safe of chain %s: %w", chainID, err)
+		}
+	}
+	if v, ok := db.crossUnsafe.Get(chainID); ok {
+		status.HasCrossUnsafe = true
+		status.CrossUnsafe = v.Get()
+	}
+	if !status.HasLogs && !status.HasLocalSafe && !status.HasCrossSafe && !status.HasCrossUnsafe {
+		return ChainDBStatus{}, fmt.Errorf("no DBs for chain %s: %w", chainID, types.ErrUnknownChain)
+	}
+	return status, nil
+}
+
+// HealthCheck checks that the DBs of each chain are consistent with each other:
+// cross-safe must not exceed local-safe, and must be canonical in the local-safe DB,
+// the local-safe block must match the log DB where the log DB has sealed it,
+// and the cross-safe tail must not be invalidated: cross-safe is rewound, not invalidated.
+// An invalidated local-safe tail is expected while awaiting a replacement block, and is not a problem.
+// The problems of all chains are joined into a single error, identifying each chain.
+func (db *ChainsDB) HealthCheck() error {
+	var result error
+	for _, chain := range db.Chains() {
+		if err := db.checkChainHealth(chain); err != nil {
+			result = errors.Join(result, fmt.Errorf("chain %s: %w", chain, err))
+		}
+	}
+	return result
+}
+
+func (db *ChainsDB) checkChainHealth(chainID eth.ChainID) (result error) {
+	var localSafe, crossSafe types.DerivedBlockSealPair
+	localDB, hasLocal := db.localDBs.Get(chainID)
+	if hasLocal {
+		pair, err := localDB.Latest()
+		switch {
+		case err == nil:
+			localSafe = pair
+		case errors.Is(err, types.ErrFuture), errors.Is(err, types.ErrAwaitReplacementBlock):
+			// no local-safe data to check against
+		default:
+			result = errors.Join(result, fmt.Errorf("failed to read local-safe: %w", err))
+		}
+	}
+	if crossDB, ok := db.crossDBs.Get(chainID); ok {
+		pair, err := crossDB.Latest()
+		switch {
+		case err == nil:
+			crossSafe = pair
+		case errors.Is(err, types.ErrFuture):
+		case errors.Is(err, types.ErrAwaitReplacementBlock):
+			result = errors.Join(result, fmt.Errorf("cross-safe tail is invalidated: %w", types.ErrDataCorruption))
+		default:
+			result = errors.Join(result, fmt.Errorf("failed to read cross-safe: %w", err))
+		}
+	}
+	if crossSafe != (types.DerivedBlockSealPair{}) && localSafe != (types.DerivedBlockSealPair{}) {
+		if crossSafe.Derived.Number > localSafe.Derived.Number {
+			result = errors.Join(result, fmt.Errorf("cross-safe %s exceeds local-safe %s: %w",
+				crossSafe.Derived, localSafe.Derived, types.ErrDataCorruption))
+		} else if err := localDB.IsDerived(crossSafe.Derived.ID()); err != nil {
+			result = errors.Join(result, fmt.Errorf("cross-safe %s is not local-safe: %w", crossSafe.Derived, err))
+		}
+	}
+	if logDB, ok := db.logDBs.Get(chainID); ok && localSafe != (types.DerivedBlockSealPair{}) {
+		// The log DB may lag behind local-safe, while indexing. It can only be checked where it overlaps.
+		if sealed, ok := logDB.LatestSealedBlock(); ok && sealed.Number >= localSafe.Derived.Number {
+			seal, err := logDB.FindSealedBlock(localSafe.Derived.Number)
+			if err != nil {
+				result = errors.Join(result, fmt.Errorf("failed to find local-safe %s in log DB: %w", localSafe.Derived, err))
+			} else if seal.Hash != localSafe.Derived.Hash {
+				result = errors.Join(result, fmt.Errorf("local-safe %s does not match sealed block %s of log DB: %w",
+					localSafe.Derived, seal, types.ErrConflict))
+			}
+		}
+	}
+	return result
+}
+
+// ChainMetrics is a point-in-time summary of the DBs of a chain, for monitoring.
+// Values of missing DBs, or DBs without data, are zeroed.
+type ChainMetrics struct {
+	LocalDerivedEntries int
+	CrossDerivedEntries int
+	// LatestSealed is the number of the latest sealed block of the log DB.
+	LatestSealed uint64
+	LocalSafe    types.BlockSeal
+	CrossSafe    types.BlockSeal
+	// InvalidationPending is set if the local-safe tail was invalidated, and awaits a replacement block.
+	InvalidationPending bool
+}
+
+// MetricsSnapshot summarizes the DBs of each chain that has any DB attached.
+// Each DB is read under its own lock; the summary of a chain is not atomic across its DBs.
+// Read errors are tolerated: the affected values are left zeroed.
+func (db *ChainsDB) MetricsSnapshot() map[eth.ChainID]ChainMetrics {
+	out := make(map[eth.ChainID]ChainMetrics)
+	for _, chain := range db.Chains() {
+		var m ChainMetrics
+		if logDB, ok := db.logDBs.Get(chain); ok {
+			if sealed, ok := logDB.LatestSealedBlock(); ok {
+				m.LatestSealed = sealed.Number
+			}
+		}
+		if localDB, ok := db.localDBs.Get(chain); ok {
+			m.LocalDerivedEntries = localDB.Len()
+			pair, err := localDB.Latest()
+			if err == nil {
+				m.LocalSafe = pair.Derived
+			}
+			m.InvalidationPending = errors.Is(err, types.ErrAwaitReplacementBlock)
+		}
+		if crossDB, ok := db.crossDBs.Get(chain); ok {
+			m.CrossDerivedEntries = crossDB.Len()
+			if pair, err := crossDB.Latest(); err == nil {
+				m.CrossSafe = pair.Derived
+			}
+		}
+		out[chain] = m
+	}
+	return out
+}
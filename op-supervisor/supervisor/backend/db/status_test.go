@@ -0,0 +1,195 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestChainStatus(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainsDB.AttachEmitter(event.NoopEmitter{})
+	require.Empty(t, chainsDB.Chains())
+
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	chainD := eth.ChainIDFromUInt64(903)
+
+	// fully wired, with data
+	chainsDB.AddLogDB(chainA, newTestLogDB(t))
+	chainsDB.AddLocalDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	chainsDB.AddCrossDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	chainsDB.AddCrossUnsafeTracker(chainA)
+	require.NoError(t, chainsDB.SealBlock(chainA, testRef("L2", 0)))
+	require.NoError(t, chainsDB.SealBlock(chainA, testRef("L2", 1)))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 0), testRef("L2", 0))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 1), testRef("L2", 1))
+	require.NoError(t, chainsDB.UpdateCrossSafe(chainA, testRef("L1", 0), testRef("L2", 0)))
+	require.NoError(t, chainsDB.UpdateCrossUnsafe(chainA, types.BlockSealFromRef(testRef("L2", 1))))
+	// only a log DB, without data
+	chainsDB.AddLogDB(chainC, newTestLogDB(t))
+	// local and cross-unsafe, without data, outside of the dependency set
+	chainsDB.AddLocalDerivedFromDB(chainD, newTestDerivedFromDB(t))
+	chainsDB.AddCrossUnsafeTracker(chainD)
+
+	require.Equal(t, []eth.ChainID{chainA, chainC, chainD}, chainsDB.Chains())
+
+	status, err := chainsDB.ChainStatus(chainA)
+	require.NoError(t, err)
+	require.Equal(t, ChainDBStatus{
+		HasLogs:        true,
+		HasLocalSafe:   true,
+		HasCrossSafe:   true,
+		HasCrossUnsafe: true,
+		LatestSealed:   testRef("L2", 1).ID(),
+		LocalSafe: types.DerivedBlockSealPair{
+			DerivedFrom: types.BlockSealFromRef(testRef("L1", 1)),
+			Derived:     types.BlockSealFromRef(testRef("L2", 1)),
+		},
+		CrossSafe: types.DerivedBlockSealPair{
+			DerivedFrom: types.BlockSealFromRef(testRef("L1", 0)),
+			Derived:     types.BlockSealFromRef(testRef("L2", 0)),
+		},
+		CrossUnsafe: types.BlockSealFromRef(testRef("L2", 1)),
+	}, status)
+
+	status, err = chainsDB.ChainStatus(chainC)
+	require.NoError(t, err)
+	require.Equal(t, ChainDBStatus{HasLogs: true}, status)
+
+	status, err = chainsDB.ChainStatus(chainD)
+	require.NoError(t, err)
+	require.Equal(t, ChainDBStatus{HasLocalSafe: true, HasCrossUnsafe: true}, status)
+
+	// in the dependency set, but without any DB
+	_, err = chainsDB.ChainStatus(chainB)
+	require.ErrorIs(t, err, types.ErrUnknownChain)
+}
+
+func TestHealthCheck(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainsDB.AttachEmitter(event.NoopEmitter{})
+	require.NoError(t, chainsDB.HealthCheck(), "no chains is healthy")
+
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	chainD := eth.ChainIDFromUInt64(903)
+	for _, chain := range []eth.ChainID{chainA, chainB, chainC, chainD} {
+		chainsDB.AddLogDB(chain, newTestLogDB(t))
+		chainsDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainsDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainsDB.AddCrossUnsafeTracker(chain)
+	}
+	require.NoError(t, chainsDB.HealthCheck(), "empty chains are healthy")
+
+	// chain A is healthy, with local-safe ahead of cross-safe, and the log DB ahead of local-safe
+	for i := uint64(0); i <= 2; i++ {
+		require.NoError(t, chainsDB.SealBlock(chainA, testRef("L2", i)))
+	}
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 0), testRef("L2", 0))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 1), testRef("L2", 1))
+	require.NoError(t, chainsDB.UpdateCrossSafe(chainA, testRef("L1", 0), testRef("L2", 0)))
+	// chain B lags in the log DB, which is not a problem
+	chainsDB.UpdateLocalSafe(chainB, testRef("L1", 0), testRef("L2", 0))
+	require.NoError(t, chainsDB.HealthCheck())
+
+	// chain B: the log DB has sealed a different block than local-safe
+	require.NoError(t, chainsDB.SealBlock(chainB, testRef("L2", 0)))
+	chainsDB.UpdateLocalSafe(chainB, testRef("L1", 1), testRef("L2", 1))
+	require.NoError(t, chainsDB.SealBlock(chainB, eth.BlockRef{
+		Hash:       testRef("L2-alt", 1).Hash,
+		Number:     1,
+		ParentHash: testRef("L2", 0).Hash,
+		Time:       testRef("L2", 1).Time,
+	}))
+	// chain C: cross-safe is ahead of local-safe
+	crossC, _ := chainsDB.crossDBs.Get(chainC)
+	for i := uint64(0); i <= 1; i++ {
+		require.NoError(t, crossC.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	chainsDB.UpdateLocalSafe(chainC, testRef("L1", 0), testRef("L2", 0))
+	// chain D: the cross-safe tail is invalidated
+	crossD, _ := chainsDB.crossDBs.Get(chainD)
+	for i := uint64(0); i <= 1; i++ {
+		require.NoError(t, crossD.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	require.NoError(t, crossD.RewindAndInvalidate(types.DerivedBlockRefPair{
+		DerivedFrom: testRef("L1", 1),
+		Derived:     testRef("L2", 1),
+	}))
+
+	err := chainsDB.HealthCheck()
+	require.Error(t, err)
+	require.ErrorIs(t, err, types.ErrConflict)
+	require.ErrorIs(t, err, types.ErrDataCorruption)
+	require.NotContains(t, err.Error(), "chain "+chainA.String())
+	require.ErrorContains(t, err, "chain "+chainB.String()+": local-safe")
+	require.ErrorContains(t, err, "chain "+chainC.String()+": cross-safe")
+	require.ErrorContains(t, err, "exceeds local-safe")
+	require.ErrorContains(t, err, "chain "+chainD.String()+": cross-safe tail is invalidated")
+
+	// an invalidated local-safe tail is expected, while awaiting a replacement
+	localA, _ := chainsDB.localDBs.Get(chainA)
+	require.NoError(t, localA.RewindAndInvalidate(types.DerivedBlockRefPair{
+		DerivedFrom: testRef("L1", 1),
+		Derived:     testRef("L2", 1),
+	}))
+	require.NotContains(t, chainsDB.HealthCheck().Error(), "chain "+chainA.String())
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	chainsDB := NewChainsDB(testlog.Logger(t, log.LevelDebug), sampleDepSet(t))
+	chainsDB.AttachEmitter(event.NoopEmitter{})
+	require.Empty(t, chainsDB.MetricsSnapshot())
+
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+	chainsDB.AddLogDB(chainA, newTestLogDB(t))
+	chainsDB.AddLocalDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	chainsDB.AddCrossDerivedFromDB(chainA, newTestDerivedFromDB(t))
+	// chain B only has a log DB
+	chainsDB.AddLogDB(chainB, newTestLogDB(t))
+	// chain C only has a local DB
+	localC := newTestDerivedFromDB(t)
+	chainsDB.AddLocalDerivedFromDB(chainC, localC)
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, chainsDB.SealBlock(chainA, testRef("L2", i)))
+	}
+	require.NoError(t, chainsDB.SealBlock(chainB, testRef("L2", 0)))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 0), testRef("L2", 0))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 1), testRef("L2", 1))
+	chainsDB.UpdateLocalSafe(chainA, testRef("L1", 1), testRef("L2", 2))
+	require.NoError(t, chainsDB.UpdateCrossSafe(chainA, testRef("L1", 0), testRef("L2", 0)))
+	require.NoError(t, localC.AddDerived(testRef("L1", 0), testRef("L2", 0)))
+	require.NoError(t, localC.AddDerived(testRef("L1", 1), testRef("L2", 1)))
+	require.NoError(t, localC.RewindAndInvalidate(types.DerivedBlockRefPair{
+		DerivedFrom: testRef("L1", 1),
+		Derived:     testRef("L2", 1),
+	}))
+
+	require.Equal(t, map[eth.ChainID]ChainMetrics{
+		chainA: {
+			LocalDerivedEntries: 3,
+			CrossDerivedEntries: 1,
+			LatestSealed:        3,
+			LocalSafe:           types.BlockSealFromRef(testRef("L2", 2)),
+			CrossSafe:           types.BlockSealFromRef(testRef("L2", 0)),
+		},
+		chainB: {},
+		chainC: {
+			LocalDerivedEntries: 2,
+			InvalidationPending: true,
+		},
+	}, chainsDB.MetricsSnapshot())
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -20,7 +21,14 @@ func (db *ChainsDB) AddLog(
 	if !ok {
 		return fmt.Errorf("cannot AddLog: %w: %v", types.ErrUnknownChain, chain)
 	}
-	return logDB.AddLog(logHash, parentBlock, logIdx, execMsg)
+	if err := logDB.AddLog(logHash, parentBlock, logIdx, execMsg); err != nil {
+		return err
+	}
+	c := db.counters(chain)
+	c.logs.Add(1)
+	c.touch(db.clock.Now())
+	db.markModified(chain)
+	return nil
 }
 
 func (db *ChainsDB) SealBlock(chain eth.ChainID, block eth.BlockRef) error {
@@ -32,6 +40,7 @@ func (db *ChainsDB) SealBlock(chain eth.ChainID, block eth.BlockRef) error {
 	if err != nil {
 		return fmt.Errorf("failed to seal block %v: %w", block, err)
 	}
+	db.markModified(chain)
 	db.logger.Info("Updated local unsafe", "chain", chain, "block", block)
 	db.emitter.Emit(superevents.LocalUnsafeUpdateEvent{
 		ChainID:        chain,
@@ -49,6 +58,7 @@ func (db *ChainsDB) Rewind(chain eth.ChainID, headBlock eth.BlockID) error {
 	if err := logDB.Rewind(headBlock); err != nil {
 		return fmt.Errorf("failed to rewind to block %v: %w", headBlock, err)
 	}
+	db.markModified(chain)
 
 	// Rewind the localDB
 	localDB, ok := db.localDBs.Get(chain)
@@ -70,12 +80,87 @@ func (db *ChainsDB) Rewind(chain eth.ChainID, headBlock eth.BlockID) error {
 	return nil
 }
 
+// RewindAllToL1 rewinds every chain of the dependency set to the last L2 block
+// that was derived from the L1 block with the given number.
+// The local-safe DB, the cross-safe DB and the log DB of each chain are rewound,
+// and cross-unsafe is reset if it is newer than the new local-safe head.
+//
+// This is best-effort, not atomic: a failure to rewind one chain does not stop the
+// other chains from being rewound, and a chain that failed may be partially rewound.
+// The errors of the chains that failed are returned per chain,
+// and the returned error is non-nil if any chain failed.
+func (db *ChainsDB) RewindAllToL1(l1Number uint64) (map[eth.ChainID]error, error) {
+	chains := db.depSet.Chains()
+	errs := make(map[eth.ChainID]error)
+	for _, chain := range chains {
+		if err := db.rewindToL1(chain, l1Number); err != nil {
+			db.logger.Error("Failed to rewind chain to L1 block", "chain", chain, "l1", l1Number, "err", err)
+			errs[chain] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs, fmt.Errorf("failed to rewind %d of %d chains to L1 block %d", len(errs), len(chains), l1Number)
+	}
+	return errs, nil
+}
+
+func (db *ChainsDB) rewindToL1(chain eth.ChainID, l1Number uint64) error {
+	localDB, ok := db.localDBs.Get(chain)
+	if !ok {
+		return fmt.Errorf("cannot rewind (localDB not found): %w: %s", types.ErrUnknownChain, chain)
+	}
+	crossDB, ok := db.crossDBs.Get(chain)
+	if !ok {
+		return fmt.Errorf("cannot rewind (crossDB not found): %w: %s", types.ErrUnknownChain, chain)
+	}
+	logDB, ok := db.logDBs.Get(chain)
+	if !ok {
+		return fmt.Errorf("cannot rewind (logDB not found): %w: %s", types.ErrUnknownChain, chain)
+	}
+
+	// Find the new local-safe head before changing any DB, so a chain that cannot be rewound is left untouched.
+	localSafe, err := localDB.NearestDerivedFrom(l1Number)
+	if err != nil {
+		return fmt.Errorf("failed to find local-safe block derived from L1 block %d: %w", l1Number, err)
+	}
+	latest, err := localDB.Latest()
+	if err != nil {
+		return fmt.Errorf("failed to get latest local-safe block: %w", err)
+	}
+	// A chain that has not derived anything past the L1 block has nothing to rewind.
+	if latest.DerivedFrom.Number <= l1Number {
+		return nil
+	}
+	// The log DB is rewound first: it is the only DB that may refuse the local-safe head, on a conflicting block.
+	// The log DB may lag behind local-safe, in which case there is nothing to rewind.
+	if err := logDB.Rewind(localSafe.Derived.ID()); err != nil && !errors.Is(err, types.ErrFuture) {
+		return fmt.Errorf("failed to rewind logDB to block %s: %w", localSafe.Derived, err)
+	}
+	db.markModified(chain)
+	if err := localDB.RewindToL1(l1Number); err != nil {
+		return fmt.Errorf("failed to rewind localDB to L1 block %d: %w", l1Number, err)
+	}
+	// Cross-safe may lag behind local-safe, in which case there is nothing to rewind.
+	if err := crossDB.RewindToL1(l1Number); err != nil && !errors.Is(err, types.ErrFuture) {
+		return fmt.Errorf("failed to rewind crossDB to L1 block %d: %w", l1Number, err)
+	}
+	if err := db.ResetCrossUnsafeIfNewerThan(chain, localSafe.Derived.Number+1); err != nil {
+		return fmt.Errorf("failed to reset cross-unsafe: %w", err)
+	}
+	return nil
+}
+
 func (db *ChainsDB) UpdateLocalSafe(chain eth.ChainID, derivedFrom eth.BlockRef, lastDerived eth.BlockRef) {
+	_ = db.updateLocalSafe(chain, derivedFrom, lastDerived)
+}
+
+// updateLocalSafe is UpdateLocalSafe, but returns the error that was logged, if any.
+func (db *ChainsDB) updateLocalSafe(chain eth.ChainID, derivedFrom eth.BlockRef, lastDerived eth.BlockRef) error {
 	logger := db.logger.New("chain", chain, "derivedFrom", derivedFrom, "lastDerived", lastDerived)
 	localDB, ok := db.localDBs.Get(chain)
 	if !ok {
 		logger.Error("Cannot update local-safe DB, unknown chain")
-		return
+		return fmt.Errorf("cannot update local-safe DB: %w: %s", types.ErrUnknownChain, chain)
 	}
 	logger.Debug("Updating local safe DB")
 	if err := localDB.AddDerived(derivedFrom, lastDerived); err != nil {
@@ -85,8 +170,12 @@ func (db *ChainsDB) UpdateLocalSafe(chain eth.ChainID, derivedFrom eth.BlockRef,
 			L1Ref:   derivedFrom,
 			Err:     err,
 		})
-		return
+		return fmt.Errorf("failed to update local safe: %w", err)
 	}
+	c := db.counters(chain)
+	c.localSafeUpdates.Add(1)
+	c.touch(db.clock.Now())
+	db.markModified(chain)
 	db.logger.Info("Updated local safe DB")
 	db.emitter.Emit(superevents.LocalSafeUpdateEvent{
 		ChainID: chain,
@@ -95,6 +184,7 @@ func (db *ChainsDB) UpdateLocalSafe(chain eth.ChainID, derivedFrom eth.BlockRef,
 			Derived:     types.BlockSealFromRef(lastDerived),
 		},
 	})
+	return nil
 }
 
 func (db *ChainsDB) UpdateCrossUnsafe(chain eth.ChainID, crossUnsafe types.BlockSeal) error {
@@ -103,6 +193,7 @@ func (db *ChainsDB) UpdateCrossUnsafe(chain eth.ChainID, crossUnsafe types.Block
 		return fmt.Errorf("cannot UpdateCrossUnsafe: %w: %s", types.ErrUnknownChain, chain)
 	}
 	v.Set(crossUnsafe)
+	db.markModified(chain)
 	db.logger.Info("Updated cross-unsafe", "chain", chain, "crossUnsafe", crossUnsafe)
 	db.emitter.Emit(superevents.CrossUnsafeUpdateEvent{
 		ChainID:        chain,
@@ -116,9 +207,18 @@ func (db *ChainsDB) UpdateCrossSafe(chain eth.ChainID, l1View eth.BlockRef, last
 	if !ok {
 		return fmt.Errorf("cannot UpdateCrossSafe: %w: %s", types.ErrUnknownChain, chain)
 	}
+	// Cross-safe may never advance past local-safe, nor include a block that local-safe does not have:
+	// once the cross-safe DB is rewound for an invalidated block (see CrossRewind),
+	// the invalidated block must not become cross-safe again, only its replacement may.
+	if localDB, ok := db.localDBs.Get(chain); ok {
+		if err := localDB.IsDerived(lastCrossDerived.ID()); err != nil {
+			return fmt.Errorf("cannot UpdateCrossSafe to %s, not local-safe: %w", lastCrossDerived, err)
+		}
+	}
 	if err := crossDB.AddDerived(l1View, lastCrossDerived); err != nil {
 		return err
 	}
+	db.markModified(chain)
 	db.logger.Info("Updated cross-safe", "chain", chain, "l1View", l1View, "lastCrossDerived", lastCrossDerived)
 	db.emitter.Emit(superevents.CrossSafeUpdateEvent{
 		ChainID: chain,
@@ -130,7 +230,14 @@ func (db *ChainsDB) UpdateCrossSafe(chain eth.ChainID, l1View eth.BlockRef, last
 	return nil
 }
 
-func (db *ChainsDB) onFinalizedL1(finalized eth.BlockRef) {
+func (db *ChainsDB) onFinalizedL1(finalized eth.BlockRef) error {
+	return db.SetFinalizedL1(finalized)
+}
+
+// SetFinalizedL1 updates the finalized L1 block, and notifies subscribers of the L2 finality this implies.
+// Finality cannot move backward: an older finalized L1 block is rejected with ErrOutOfOrder.
+// This is the same as handling a FinalizedL1RequestEvent.
+func (db *ChainsDB) SetFinalizedL1(finalized eth.L1BlockRef) error {
 	// Lock, so we avoid race-conditions in-between getting (for comparison) and setting.
 	// Unlock is managed explicitly, in this function so we can call NotifyL2Finalized after releasing the lock.
 	db.finalizedL1.Lock()
@@ -138,7 +245,7 @@ func (db *ChainsDB) onFinalizedL1(finalized eth.BlockRef) {
 	if v := db.finalizedL1.Value; v != (eth.BlockRef{}) && v.Number > finalized.Number {
 		db.finalizedL1.Unlock()
 		db.logger.Warn("Cannot rewind finalized L1 block", "current", v, "signal", finalized)
-		return
+		return fmt.Errorf("cannot rewind finalized L1 block %s to %s: %w", v, finalized, types.ErrOutOfOrder)
 	}
 	db.finalizedL1.Value = finalized
 	db.logger.Info("Updated finalized L1", "finalizedL1", finalized)
@@ -156,6 +263,7 @@ func (db *ChainsDB) onFinalizedL1(finalized eth.BlockRef) {
 		}
 		db.emitter.Emit(superevents.FinalizedL2UpdateEvent{ChainID: chain, FinalizedL2: fin})
 	}
+	return nil
 }
 
 func (db *ChainsDB) InvalidateLocalSafe(chainID eth.ChainID, candidate types.DerivedBlockRefPair) error {
@@ -175,6 +283,7 @@ func (db *ChainsDB) InvalidateLocalSafe(chainID eth.ChainID, candidate types.Der
 	if err := localSafeDB.RewindAndInvalidate(candidate); err != nil {
 		return fmt.Errorf("failed to invalidate entry in local-safe DB: %w", err)
 	}
+	db.markModified(chainID)
 
 	// Change cross-unsafe, if it's equal or past the invalidated block.
 	if err := db.ResetCrossUnsafeIfNewerThan(chainID, candidate.Derived.Number); err != nil {
@@ -219,23 +328,92 @@ func (db *ChainsDB) ResetCrossUnsafeIfNewerThan(chainID eth.ChainID, number uint
 		db.logger.Warn("Resetting cross-unsafe to cross-safe, since prior block was invalidated",
 			"crossUnsafe", x, "crossSafe", crossSafe, "number", number)
 		crossUnsafe.Value = crossSafe.Derived
+		db.markModified(chainID)
 	}
 	return nil
 }
 
-func (db *ChainsDB) onReplaceBlock(chainID eth.ChainID, replacement eth.BlockRef, invalidated common.Hash) {
+// CrossRewind rolls back the cross-safe DB of the given chain upon invalidation of a block,
+// to the last cross-safe block before the invalidated block. The local-safe data is not affected.
+// After rewinding, the cross-safe head is checked to still be consistent with the local-safe DB,
+// and cross-unsafe is reset if it is at or past the invalidated block.
+// Nothing changes if the invalidated block was never cross-safe.
+func (db *ChainsDB) CrossRewind(chainID eth.ChainID, invalidated types.DerivedBlockRefPair) error {
+	crossDB, ok := db.crossDBs.Get(chainID)
+	if !ok {
+		return fmt.Errorf("cannot find cross-safe DB of chain %s for rewind: %w", chainID, types.ErrUnknownChain)
+	}
+	localDB, ok := db.localDBs.Get(chainID)
+	if !ok {
+		return fmt.Errorf("cannot find local-safe DB of chain %s for rewind: %w", chainID, types.ErrUnknownChain)
+	}
+	rewound, err := crossDB.CrossRewind(invalidated)
+	if err != nil {
+		return fmt.Errorf("failed to rewind cross-safe DB: %w", err)
+	}
+	if !rewound {
+		// The invalidated block was never cross-safe, so cross-safe and cross-unsafe are not affected.
+		return nil
+	}
+	db.markModified(chainID)
+
+	crossSafe, err := crossDB.Latest()
+	if errors.Is(err, types.ErrFuture) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("cannot get cross-safe of chain %s: %w", chainID, err)
+	}
+	if err := localDB.IsDerived(crossSafe.Derived.ID()); err != nil {
+		return fmt.Errorf("cross-safe %s of chain %s is inconsistent with local-safe: %w", crossSafe.Derived, chainID, err)
+	}
+	db.logger.Warn("Rewound cross-safe, since block was invalidated",
+		"chain", chainID, "invalidated", invalidated.Derived, "crossSafe", crossSafe)
+
+	// Change cross-unsafe, if it's equal or past the invalidated block.
+	if err := db.ResetCrossUnsafeIfNewerThan(chainID, invalidated.Derived.Number); err != nil {
+		return fmt.Errorf("failed to reset cross-unsafe: %w", err)
+	}
+	return nil
+}
+
+func (db *ChainsDB) onReplaceBlock(chainID eth.ChainID, replacement eth.BlockRef, invalidated common.Hash) error {
 	localSafeDB, ok := db.localDBs.Get(chainID)
 	if !ok {
 		db.logger.Error("Cannot find DB for replacement block", "chain", chainID)
-		return
+		return fmt.Errorf("cannot find DB for replacement block: %w: %s", types.ErrUnknownChain, chainID)
+	}
+
+	// The invalidated block may still be cross-safe, if it was invalidated through the local-safe DB only.
+	// Drop it from the cross-safe DB, before the replacement can be cross-validated.
+	if _, ok := db.crossDBs.Get(chainID); ok {
+		invalidatedPair, err := localSafeDB.Invalidated()
+		if err != nil {
+			return fmt.Errorf("cannot find invalidated block %s: %w", invalidated, err)
+		}
+		if invalidatedPair.Derived.Hash != invalidated {
+			return fmt.Errorf("expected to replace %s, but %s is invalidated: %w",
+				invalidated, invalidatedPair.Derived, types.ErrConflict)
+		}
+		if err := db.CrossRewind(chainID, types.DerivedBlockRefPair{
+			DerivedFrom: invalidatedPair.DerivedFrom.ForceWithParent(eth.BlockID{}),
+			Derived:     invalidatedPair.Derived.ForceWithParent(replacement.ParentID()),
+		}); errors.Is(err, types.ErrPreviousToFirst) {
+			// The invalidated block anchors the cross-safe DB, and cannot be rewound.
+			// The local-safe replacement does not depend on the cross-safe DB, and must not be held back by it.
+			db.logger.Warn("Cannot rewind cross-safe DB past its first entry for replacement block",
+				"invalidated", invalidated, "replacement", replacement, "err", err)
+		} else if err != nil {
+			return fmt.Errorf("cannot rewind cross-safe DB for replacement of %s: %w", invalidated, err)
+		}
 	}
 
 	result, err := localSafeDB.ReplaceInvalidatedBlock(replacement, invalidated)
 	if err != nil {
 		db.logger.Error("Cannot replace invalidated block in local-safe DB",
 			"invalidated", invalidated, "replacement", replacement, "err", err)
-		return
+		return fmt.Errorf("cannot replace invalidated block %s with %s: %w", invalidated, replacement, err)
 	}
+	db.markModified(chainID)
 	// Consider the replacement as a new local-unsafe block, so we can try to index the new event-data.
 	db.emitter.Emit(superevents.LocalUnsafeReceivedEvent{
 		ChainID:        chainID,
@@ -248,4 +426,5 @@ func (db *ChainsDB) onReplaceBlock(chainID eth.ChainID, replacement eth.BlockRef
 	})
 
 	// TODO Make sure the events-DB has a matching block-hash with the replacement, roll it back otherwise.
+	return nil
 }
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/op-node/rollup/event"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/superevents"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/types"
+)
+
+func TestRewindAllToL1(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+
+	// addChain attaches the DBs of a chain, with L2 blocks 0 up to lastSealed sealed,
+	// and one L2 block derived from each L1 block in the inclusive local-safe range.
+	// Cross-safe trails local-safe, and ends at L1 block 3.
+	addChain := func(chain eth.ChainID, firstLocal uint64, lastSealed uint64) {
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+		chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		crossDB := newTestDerivedFromDB(t)
+		chainDB.AddCrossDerivedFromDB(chain, crossDB)
+		chainDB.AddCrossUnsafeTracker(chain)
+		for i := uint64(0); i <= lastSealed; i++ {
+			require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		}
+		for i := firstLocal; i <= 5; i++ {
+			chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+		}
+		for i := firstLocal; i <= 3; i++ {
+			require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+		}
+		require.NoError(t, chainDB.UpdateCrossUnsafe(chain, types.BlockSealFromRef(testRef("L2", 4))))
+	}
+	addChain(chainA, 0, 5)
+	// the log DB of chain B lags behind the L2 block it is rewound to, and has nothing to rewind
+	addChain(chainB, 0, 1)
+	// chain C has no local-safe data derived from L1 block 2, and fails to rewind
+	addChain(chainC, 3, 5)
+
+	errs, err := chainDB.RewindAllToL1(2)
+	require.Error(t, err)
+	require.Len(t, errs, 1)
+	require.Error(t, errs[chainC])
+
+	for _, chain := range []eth.ChainID{chainA, chainB} {
+		localSafe, err := chainDB.LocalSafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L1", 2).ID(), localSafe.DerivedFrom.ID())
+		require.Equal(t, testRef("L2", 2).ID(), localSafe.Derived.ID())
+		crossSafe, err := chainDB.CrossSafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L2", 2).ID(), crossSafe.Derived.ID())
+		crossUnsafe, err := chainDB.CrossUnsafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L2", 2).ID(), crossUnsafe.ID())
+	}
+	unsafe, err := chainDB.LocalUnsafe(chainA)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 2).ID(), unsafe.ID())
+	unsafe, err = chainDB.LocalUnsafe(chainB)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 1).ID(), unsafe.ID())
+
+	// the failed chain is left untouched
+	localSafe, err := chainDB.LocalSafe(chainC)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 5).ID(), localSafe.Derived.ID())
+	unsafe, err = chainDB.LocalUnsafe(chainC)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 5).ID(), unsafe.ID())
+}
+
+func TestRewindAllToL1LaggingChain(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainA := eth.ChainIDFromUInt64(900)
+	chainB := eth.ChainIDFromUInt64(901)
+	chainC := eth.ChainIDFromUInt64(902)
+
+	// addChain attaches the DBs of a chain, with one L2 block sealed,
+	// local-safe and cross-safe for each L1 block up to lastL1.
+	addChain := func(chain eth.ChainID, lastL1 uint64) {
+		chainDB.AddLogDB(chain, newTestLogDB(t))
+		chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+		chainDB.AddCrossUnsafeTracker(chain)
+		for i := uint64(0); i <= lastL1; i++ {
+			require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+			chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+			require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", i), testRef("L2", i)))
+		}
+	}
+	addChain(chainA, 5)
+	// chain B has not derived anything past L1 block 1, and is not affected by a rewind to L1 block 3
+	addChain(chainB, 1)
+	addChain(chainC, 5)
+
+	errs, err := chainDB.RewindAllToL1(3)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+
+	for chain, expected := range map[eth.ChainID]uint64{chainA: 3, chainB: 1, chainC: 3} {
+		localSafe, err := chainDB.LocalSafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L2", expected).ID(), localSafe.Derived.ID())
+		crossSafe, err := chainDB.CrossSafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L2", expected).ID(), crossSafe.Derived.ID())
+		unsafe, err := chainDB.LocalUnsafe(chain)
+		require.NoError(t, err)
+		require.Equal(t, testRef("L2", expected).ID(), unsafe.ID())
+	}
+}
+
+func TestUpdateCrossSafeNotLocalSafe(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossUnsafeTracker(chain)
+
+	for i := uint64(0); i <= 2; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+	}
+	require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 0), testRef("L2", 0)))
+
+	// cross-safe cannot advance past local-safe
+	require.ErrorIs(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 3), testRef("L2", 3)), types.ErrFuture)
+	// cross-safe cannot include a block that conflicts with local-safe
+	conflicting := testRef("L2 conflicting", 1)
+	conflicting.ParentHash = testRef("L2", 0).Hash
+	require.ErrorIs(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 1), conflicting), types.ErrConflict)
+
+	crossSafe, err := chainDB.CrossSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 0).ID(), crossSafe.Derived.ID())
+	require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 1), testRef("L2", 1)))
+}
+
+func TestReplaceBlockRewindsCrossSafe(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		t.Errorf("failed to handle %s: %v", ev, err)
+	})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossUnsafeTracker(chain)
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+		require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", i), testRef("L2", i)))
+	}
+	require.NoError(t, chainDB.UpdateCrossUnsafe(chain, types.BlockSealFromRef(testRef("L2", 3))))
+	// An L1 reorg invalidates L2 block 3, which was already cross-safe.
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 3), Derived: testRef("L2", 3)}
+	require.NoError(t, chainDB.InvalidateLocalSafe(chain, invalidated))
+	crossSafe, err := chainDB.CrossSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 3).ID(), crossSafe.Derived.ID(), "not rewound until replaced")
+
+	replacement := testRef("L2 replacement", 3)
+	replacement.ParentHash = testRef("L2", 2).Hash
+	require.True(t, chainDB.OnEvent(superevents.ReplaceBlockEvent{
+		ChainID:     chain,
+		Replacement: types.BlockReplacement{Replacement: replacement, Invalidated: invalidated.Derived.Hash},
+	}))
+
+	crossSafe, err = chainDB.CrossSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L1", 2).ID(), crossSafe.DerivedFrom.ID())
+	require.Equal(t, testRef("L2", 2).ID(), crossSafe.Derived.ID())
+	crossUnsafe, err := chainDB.CrossUnsafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 2).ID(), crossUnsafe.ID())
+	// the local-safe data is preserved, and now includes the replacement
+	localSafe, err := chainDB.LocalSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L1", 3).ID(), localSafe.DerivedFrom.ID())
+	require.Equal(t, replacement.ID(), localSafe.Derived.ID())
+
+	// the replacement can become cross-safe, the invalidated block cannot
+	require.Error(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 3), testRef("L2", 3)))
+	require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", 3), replacement))
+}
+
+func TestReplaceBlockAtCrossSafeAnchor(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chainDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		t.Errorf("failed to handle %s: %v", ev, err)
+	})
+	chain := eth.ChainIDFromUInt64(900)
+	crossDB := newTestDerivedFromDB(t)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, crossDB)
+	chainDB.AddCrossUnsafeTracker(chain)
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+	}
+	// the cross-safe DB starts at the block that is invalidated, and cannot be rewound past it
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 3), testRef("L2", 3)))
+
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 3), Derived: testRef("L2", 3)}
+	require.NoError(t, chainDB.InvalidateLocalSafe(chain, invalidated))
+	replacement := testRef("L2 replacement", 3)
+	replacement.ParentHash = testRef("L2", 2).Hash
+	require.True(t, chainDB.OnEvent(superevents.ReplaceBlockEvent{
+		ChainID:     chain,
+		Replacement: types.BlockReplacement{Replacement: replacement, Invalidated: invalidated.Derived.Hash},
+	}))
+
+	// the local-safe replacement is not held back by the cross-safe DB
+	localSafe, err := chainDB.LocalSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, replacement.ID(), localSafe.Derived.ID())
+}
+
+func TestReplaceBlockCrossRewindConflict(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	var handleErr error
+	chainDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		handleErr = err
+	})
+	chain := eth.ChainIDFromUInt64(900)
+	crossDB := newTestDerivedFromDB(t)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, crossDB)
+	chainDB.AddCrossUnsafeTracker(chain)
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+	}
+	// the cross-safe DB disagrees with local-safe about L2 block 3
+	for i := uint64(0); i <= 2; i++ {
+		require.NoError(t, crossDB.AddDerived(testRef("L1", i), testRef("L2", i)))
+	}
+	conflicting := testRef("L2 conflicting", 3)
+	conflicting.ParentHash = testRef("L2", 2).Hash
+	require.NoError(t, crossDB.AddDerived(testRef("L1", 3), conflicting))
+
+	invalidated := types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 3), Derived: testRef("L2", 3)}
+	require.NoError(t, chainDB.InvalidateLocalSafe(chain, invalidated))
+	replacement := testRef("L2 replacement", 3)
+	replacement.ParentHash = testRef("L2", 2).Hash
+	require.True(t, chainDB.OnEvent(superevents.ReplaceBlockEvent{
+		ChainID:     chain,
+		Replacement: types.BlockReplacement{Replacement: replacement, Invalidated: invalidated.Derived.Hash},
+	}))
+
+	// the cross-safe rewind failure is surfaced, and the replacement is not applied
+	require.ErrorIs(t, handleErr, types.ErrConflict)
+	_, err := chainDB.LocalSafe(chain)
+	require.ErrorIs(t, err, types.ErrAwaitReplacementBlock)
+}
+
+func TestCrossRewindNotCrossSafe(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+	chain := eth.ChainIDFromUInt64(900)
+	chainDB.AddLogDB(chain, newTestLogDB(t))
+	chainDB.AddLocalDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossDerivedFromDB(chain, newTestDerivedFromDB(t))
+	chainDB.AddCrossUnsafeTracker(chain)
+
+	for i := uint64(0); i <= 3; i++ {
+		require.NoError(t, chainDB.SealBlock(chain, testRef("L2", i)))
+		chainDB.UpdateLocalSafe(chain, testRef("L1", i), testRef("L2", i))
+	}
+	for i := uint64(0); i <= 1; i++ {
+		require.NoError(t, chainDB.UpdateCrossSafe(chain, testRef("L1", i), testRef("L2", i)))
+	}
+	require.NoError(t, chainDB.UpdateCrossUnsafe(chain, types.BlockSealFromRef(testRef("L2", 3))))
+
+	// block 2 was never cross-safe: nothing is rewound, and cross-unsafe is kept
+	require.NoError(t, chainDB.CrossRewind(chain, types.DerivedBlockRefPair{DerivedFrom: testRef("L1", 2), Derived: testRef("L2", 2)}))
+	crossSafe, err := chainDB.CrossSafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 1).ID(), crossSafe.Derived.ID())
+	crossUnsafe, err := chainDB.CrossUnsafe(chain)
+	require.NoError(t, err)
+	require.Equal(t, testRef("L2", 3).ID(), crossUnsafe.ID())
+}
+
+func TestSetFinalizedL1(t *testing.T) {
+	logger := testlog.Logger(t, log.LevelDebug)
+	chainDB := NewChainsDB(logger, sampleDepSet(t))
+	chainDB.AttachEmitter(event.NoopEmitter{})
+
+	_, ok := chainDB.FinalizedL1()
+	require.False(t, ok, "no finality signal yet")
+
+	require.NoError(t, chainDB.SetFinalizedL1(testRef("L1", 3)))
+	ref, ok := chainDB.FinalizedL1()
+	require.True(t, ok)
+	require.Equal(t, testRef("L1", 3), ref)
+
+	// finality cannot move backward
+	require.ErrorIs(t, chainDB.SetFinalizedL1(testRef("L1", 2)), types.ErrOutOfOrder)
+	ref, _ = chainDB.FinalizedL1()
+	require.Equal(t, testRef("L1", 3), ref)
+
+	// repeating the same signal, or moving forward, is fine
+	require.NoError(t, chainDB.SetFinalizedL1(testRef("L1", 3)))
+	require.NoError(t, chainDB.SetFinalizedL1(testRef("L1", 5)))
+
+	// the event path shares the same validation
+	var handlerErr error
+	chainDB.AttachEventErrorHandler(func(ev event.Event, err error) {
+		handlerErr = err
+	})
+	require.True(t, chainDB.OnEvent(superevents.FinalizedL1RequestEvent{FinalizedL1: testRef("L1", 4)}))
+	require.ErrorIs(t, handlerErr, types.ErrOutOfOrder)
+	ref, _ = chainDB.FinalizedL1()
+	require.Equal(t, testRef("L1", 5), ref)
+}
@@ -13,6 +13,8 @@ var (
 	ErrSkipped = errors.New("skipped data")
 	// ErrFuture happens when data is just not yet available
 	ErrFuture = errors.New("future data")
+	// ErrNotFound happens when data is looked up that is not present, and is not known to become available.
+	ErrNotFound = errors.New("not found")
 	// ErrConflict happens when we know for sure that there is different canonical data
 	ErrConflict = errors.New("conflicting data")
 	// ErrAwaitReplacementBlock happens when we know for sure that a replacement block is needed before progress can be made.
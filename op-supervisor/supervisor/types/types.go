@@ -300,6 +300,26 @@ type DerivedIDPair struct {
 	Derived     eth.BlockID `json:"derived"`
 }
 
+// ChainBlock identifies a block of a specific chain.
+type ChainBlock struct {
+	ChainID eth.ChainID `json:"chainID"`
+	Block   eth.BlockID `json:"block"`
+}
+
+func (c ChainBlock) String() string {
+	return fmt.Sprintf("%s on chain %s", c.Block, c.ChainID)
+}
+
+// LogPosition identifies a log by the block that includes it, and its index within that block.
+type LogPosition struct {
+	Block  BlockSeal
+	LogIdx uint32
+}
+
+func (p LogPosition) String() string {
+	return fmt.Sprintf("log %d of %s", p.LogIdx, p.Block)
+}
+
 type BlockReplacement struct {
 	Replacement eth.BlockRef `json:"replacement"`
 	Invalidated common.Hash  `json:"invalidated"`